@@ -0,0 +1,102 @@
+package ddtracer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// abandonedSpans is a SpanObserver tracking the open spans, and logging the
+// ones still open after timeout, which are likely never finished by their
+// instrumentation. See WithAbandonedSpanDetection.
+type abandonedSpans struct {
+	t       *Tracer
+	timeout time.Duration
+
+	mu sync.Mutex
+	// open are the spans started but not finished yet, true once reported.
+	open map[*Span]bool
+
+	exit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// startAbandonedSpans starts checking the open spans every half timeout.
+func (t *Tracer) startAbandonedSpans(timeout time.Duration) *abandonedSpans {
+	a := &abandonedSpans{
+		t:       t,
+		timeout: timeout,
+		open:    make(map[*Span]bool),
+		exit:    make(chan struct{}),
+	}
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+
+		ticker := time.NewTicker(timeout / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				a.check()
+			case <-a.exit:
+				return
+			}
+		}
+	}()
+	return a
+}
+
+func (a *abandonedSpans) stop() {
+	close(a.exit)
+	a.wg.Wait()
+}
+
+func (a *abandonedSpans) OnStart(span *Span) {
+	a.mu.Lock()
+	a.open[span] = false
+	a.mu.Unlock()
+}
+
+func (a *abandonedSpans) OnFinish(span *Span) {
+	a.mu.Lock()
+	reported := a.open[span]
+	delete(a.open, span)
+	a.mu.Unlock()
+
+	if reported {
+		a.t.logger.Printf("abandoned span %s: finished after %s", span.describe(), time.Duration(span.Duration))
+	}
+}
+
+// check logs the spans open for longer than the timeout, once, with their
+// creation stack trace when captured (see WithCreationStackTraces).
+func (a *abandonedSpans) check() {
+	now := a.t.clock.Now()
+
+	var abandoned []*Span
+	a.mu.Lock()
+	for span, reported := range a.open {
+		if !reported && now.Sub(span.start) >= a.timeout {
+			a.open[span] = true
+			abandoned = append(abandoned, span)
+		}
+	}
+	a.mu.Unlock()
+
+	for _, span := range abandoned {
+		if len(span.callers) > 0 {
+			a.t.logger.Printf("abandoned span %s: unfinished after %s, created at:\n%s", span.describe(), now.Sub(span.start), formatStack(span.callers))
+		} else {
+			a.t.logger.Printf("abandoned span %s: unfinished after %s", span.describe(), now.Sub(span.start))
+		}
+	}
+}
+
+// describe identifies the span in the logs.
+func (s *Span) describe() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fmt.Sprintf("%q (trace_id=%d span_id=%d)", s.Name, s.TraceID, s.SpanID)
+}
@@ -0,0 +1,62 @@
+package ddtracer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAbandonedSpanDetection(t *testing.T) {
+	l := &recordingLogger{}
+	clock := &fakeClock{t: time.Now()}
+	// The timeout is long enough for the background checks not to run.
+	tr := NewTracerWithOptions(
+		WithTransport(discardTransport{}),
+		WithLogger(l),
+		WithClock(clock),
+		WithAbandonedSpanDetection(time.Hour),
+	).(*Tracer)
+	defer tr.Close()
+
+	leaked := tr.StartSpan("leaked", WithStackTrace()).(*Span)
+	tr.StartSpan("finished").Finish()
+	clock.add(30 * time.Minute)
+	recent := tr.StartSpan("recent").(*Span)
+	clock.add(30 * time.Minute)
+
+	tr.abandoned.check()
+	require.Len(t, l.lines, 1)
+	assert.Contains(t, l.lines[0], `abandoned span "leaked"`)
+	assert.Contains(t, l.lines[0], "unfinished after 1h0m0s")
+	assert.Contains(t, l.lines[0], "TestAbandonedSpanDetection")
+
+	// The spans are reported once.
+	tr.abandoned.check()
+	assert.Len(t, l.lines, 1)
+
+	recent.Finish()
+	clock.add(time.Minute)
+	leaked.Finish()
+	require.Len(t, l.lines, 2)
+	assert.Contains(t, l.lines[1], `abandoned span "leaked"`)
+	assert.Contains(t, l.lines[1], "finished after 1h1m0s")
+	assert.Empty(t, tr.abandoned.open)
+}
+
+func TestAbandonedSpanDetectionTicker(t *testing.T) {
+	l := &recordingLogger{}
+	tr := NewTracerWithOptions(
+		WithTransport(discardTransport{}),
+		WithLogger(l),
+		WithAbandonedSpanDetection(20*time.Millisecond),
+	).(*Tracer)
+
+	tr.StartSpan("leaked")
+	time.Sleep(100 * time.Millisecond)
+	tr.Close()
+
+	require.NotEmpty(t, l.lines)
+	assert.Contains(t, l.lines[0], `abandoned span "leaked"`)
+}
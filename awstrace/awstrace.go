@@ -0,0 +1,116 @@
+// Package awstrace propagates the spans across SQS and SNS through the
+// message attributes.
+//
+// The context is packed as JSON into a single attribute, as the messages are
+// limited to 10 attributes.
+package awstrace
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+const (
+	// AttributeName is the message attribute holding the context.
+	AttributeName = "_datadog"
+
+	// maxAttributes is the number of attributes allowed by SQS and SNS.
+	maxAttributes = 10
+
+	stringDataType = "String"
+)
+
+// ErrTooManyAttributes is returned by Inject when the message already has
+// the maximum number of attributes, it's sent without the context.
+var ErrTooManyAttributes = errors.New("awstrace: too many message attributes")
+
+// InjectSQS injects sc into the attributes of an SQS message, attrs is
+// allocated when nil and returned, i.e.
+//
+//	input.MessageAttributes, err = awstrace.InjectSQS(tracer, span.Context(), input.MessageAttributes)
+func InjectSQS(tr opentracing.Tracer, sc opentracing.SpanContext, attrs map[string]*sqs.MessageAttributeValue) (map[string]*sqs.MessageAttributeValue, error) {
+	if _, ok := attrs[AttributeName]; !ok && len(attrs) >= maxAttributes {
+		return attrs, ErrTooManyAttributes
+	}
+
+	value, err := inject(tr, sc)
+	if err != nil {
+		return attrs, err
+	}
+
+	if attrs == nil {
+		attrs = make(map[string]*sqs.MessageAttributeValue, 1)
+	}
+	attrs[AttributeName] = &sqs.MessageAttributeValue{
+		DataType:    aws.String(stringDataType),
+		StringValue: aws.String(value),
+	}
+	return attrs, nil
+}
+
+// ExtractSQS extracts the context from the attributes of an SQS message, it returns
+// opentracing.ErrSpanContextNotFound when there's none.
+func ExtractSQS(tr opentracing.Tracer, attrs map[string]*sqs.MessageAttributeValue) (opentracing.SpanContext, error) {
+	attr, ok := attrs[AttributeName]
+	if !ok || attr == nil {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+	return extract(tr, aws.StringValue(attr.StringValue))
+}
+
+// InjectSNS injects sc into the attributes of an SNS message, see InjectSQS.
+func InjectSNS(tr opentracing.Tracer, sc opentracing.SpanContext, attrs map[string]*sns.MessageAttributeValue) (map[string]*sns.MessageAttributeValue, error) {
+	if _, ok := attrs[AttributeName]; !ok && len(attrs) >= maxAttributes {
+		return attrs, ErrTooManyAttributes
+	}
+
+	value, err := inject(tr, sc)
+	if err != nil {
+		return attrs, err
+	}
+
+	if attrs == nil {
+		attrs = make(map[string]*sns.MessageAttributeValue, 1)
+	}
+	attrs[AttributeName] = &sns.MessageAttributeValue{
+		DataType:    aws.String(stringDataType),
+		StringValue: aws.String(value),
+	}
+	return attrs, nil
+}
+
+// ExtractSNS extracts the context from the attributes of an SNS message, see ExtractSQS.
+func ExtractSNS(tr opentracing.Tracer, attrs map[string]*sns.MessageAttributeValue) (opentracing.SpanContext, error) {
+	attr, ok := attrs[AttributeName]
+	if !ok || attr == nil {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+	return extract(tr, aws.StringValue(attr.StringValue))
+}
+
+// inject returns sc injected in a TextMap, encoded as JSON.
+func inject(tr opentracing.Tracer, sc opentracing.SpanContext) (string, error) {
+	carrier := opentracing.TextMapCarrier{}
+	if err := tr.Inject(sc, opentracing.TextMap, carrier); err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(carrier)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func extract(tr opentracing.Tracer, value string) (opentracing.SpanContext, error) {
+	carrier := opentracing.TextMapCarrier{}
+	if err := json.Unmarshal([]byte(value), &carrier); err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	return tr.Extract(opentracing.TextMap, carrier)
+}
@@ -0,0 +1,76 @@
+package awstrace
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQS(t *testing.T) {
+	tr := ddtracer.NewTracer()
+	span := tr.StartSpan("send").(*ddtracer.Span)
+	span.SetBaggageItem("user", "42")
+
+	attrs, err := InjectSQS(tr, span.Context(), nil)
+	require.NoError(t, err)
+	require.Len(t, attrs, 1)
+	assert.Equal(t, "String", aws.StringValue(attrs[AttributeName].DataType))
+
+	sc, err := ExtractSQS(tr, attrs)
+	require.NoError(t, err)
+
+	child := tr.StartSpan("receive", opentracing.FollowsFrom(sc)).(*ddtracer.Span)
+	assert.Equal(t, span.TraceID, child.TraceID)
+	assert.Equal(t, span.SpanID, child.ParentID)
+	assert.Equal(t, "42", child.BaggageItem("user"))
+}
+
+func TestSQSTooManyAttributes(t *testing.T) {
+	tr := ddtracer.NewTracer()
+	span := tr.StartSpan("send")
+
+	attrs := map[string]*sqs.MessageAttributeValue{}
+	for i := 0; i < maxAttributes; i++ {
+		attrs[strconv.Itoa(i)] = &sqs.MessageAttributeValue{}
+	}
+
+	_, err := InjectSQS(tr, span.Context(), attrs)
+	assert.Equal(t, ErrTooManyAttributes, err)
+	assert.Len(t, attrs, maxAttributes)
+}
+
+func TestSNS(t *testing.T) {
+	tr := ddtracer.NewTracer()
+	span := tr.StartSpan("publish").(*ddtracer.Span)
+
+	attrs := map[string]*sns.MessageAttributeValue{
+		"app": {DataType: aws.String("String"), StringValue: aws.String("x")},
+	}
+	attrs, err := InjectSNS(tr, span.Context(), attrs)
+	require.NoError(t, err)
+	assert.Len(t, attrs, 2)
+
+	sc, err := ExtractSNS(tr, attrs)
+	require.NoError(t, err)
+	child := tr.StartSpan("consume", opentracing.FollowsFrom(sc)).(*ddtracer.Span)
+	assert.Equal(t, span.TraceID, child.TraceID)
+}
+
+func TestExtractErrors(t *testing.T) {
+	tr := ddtracer.NewTracer()
+
+	_, err := ExtractSQS(tr, nil)
+	assert.Equal(t, opentracing.ErrSpanContextNotFound, err)
+
+	_, err = ExtractSNS(tr, map[string]*sns.MessageAttributeValue{
+		AttributeName: {StringValue: aws.String("{")},
+	})
+	assert.Equal(t, opentracing.ErrSpanContextCorrupted, err)
+}
@@ -0,0 +1,102 @@
+package ddtracer
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+const (
+	b3TraceID      = "x-b3-traceid"
+	b3SpanID       = "x-b3-spanid"
+	b3ParentSpanID = "x-b3-parentspanid"
+	b3Sampled      = "x-b3-sampled"
+)
+
+// b3Propagator implements Zipkin's B3 multi-header propagation.
+// The upper 64 bits of 128-bit trace IDs are kept apart.
+type b3Propagator struct{}
+
+func (p *b3Propagator) Inject(sc *SpanContext, carrier interface{}) error {
+	tm, ok := carrier.(opentracing.TextMapWriter)
+	if !ok {
+		return opentracing.ErrInvalidCarrier
+	}
+
+	if sc.traceIDHigh != 0 {
+		tm.Set(b3TraceID, formatB3ID(sc.traceIDHigh)+formatB3ID(sc.traceID))
+	} else {
+		tm.Set(b3TraceID, formatB3ID(sc.traceID))
+	}
+	tm.Set(b3SpanID, formatB3ID(sc.spanID))
+	if sc.parentID > 0 {
+		tm.Set(b3ParentSpanID, formatB3ID(sc.parentID))
+	}
+	if sc.sampled {
+		tm.Set(b3Sampled, "1")
+	} else {
+		tm.Set(b3Sampled, "0")
+	}
+
+	return nil
+}
+
+func (p *b3Propagator) Extract(carrier interface{}) (opentracing.SpanContext, error) {
+	tm, ok := carrier.(opentracing.TextMapReader)
+	if !ok {
+		return nil, opentracing.ErrInvalidCarrier
+	}
+
+	var err error
+	var spanID, traceID, traceIDHigh, parentID uint64
+	sampled := true
+	err = tm.ForeachKey(func(k, v string) error {
+		switch lowerKey(k) {
+		case b3TraceID:
+			if len(v) > 16 {
+				if traceIDHigh, err = strconv.ParseUint(v[:len(v)-16], 16, 64); err != nil {
+					break
+				}
+				v = v[len(v)-16:]
+			}
+			traceID, err = strconv.ParseUint(v, 16, 64)
+		case b3SpanID:
+			spanID, err = strconv.ParseUint(v, 16, 64)
+		case b3ParentSpanID:
+			parentID, err = strconv.ParseUint(v, 16, 64)
+		case b3Sampled:
+			sampled = v == "1" || v == "true"
+		}
+
+		if err != nil {
+			return opentracing.ErrSpanContextCorrupted
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if traceID == 0 || spanID == 0 {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+
+	span := &Span{
+		Span: &tracer.Span{
+			SpanID:   spanID,
+			ParentID: parentID,
+			TraceID:  traceID,
+			Sampled:  sampled,
+		},
+		traceIDHigh: traceIDHigh,
+	}
+
+	return span.Context(), nil
+}
+
+func formatB3ID(id uint64) string {
+	s := strconv.FormatUint(id, 16)
+	return strings.Repeat("0", 16-len(s)) + s
+}
@@ -0,0 +1,75 @@
+package ddtracer
+
+import (
+	"net/http"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestB3Propagation(t *testing.T) {
+	tr := NewTracer().(*Tracer)
+	tr.EnableB3(true, true)
+
+	span := tr.StartSpan("span").(*Span)
+	span.SpanID = 0xaa
+	span.TraceID = 0xbb
+	span.ParentID = 0xcc
+
+	h := http.Header{}
+	require.NoError(t, tr.InjectHTTPHeader(span.Context(), h))
+	assert.Equal(t, "00000000000000bb", h.Get("X-B3-Traceid"))
+	assert.Equal(t, "00000000000000aa", h.Get("X-B3-Spanid"))
+	assert.Equal(t, "00000000000000cc", h.Get("X-B3-Parentspanid"))
+	assert.Equal(t, "1", h.Get("X-B3-Sampled"))
+	assert.Equal(t, "187", h.Get("X-Datadog-Trace-Id"))
+
+	t.Run("Extract", func(t *testing.T) {
+		sc, err := tr.ExtractHTTPHeader(http.Header{
+			"X-B3-Traceid":      []string{"463ac35c9f6413ad48485a3953bb6124"},
+			"X-B3-Spanid":       []string{"a2fb4a1d1a96d312"},
+			"X-B3-Parentspanid": []string{"0020000000000001"},
+			"X-B3-Sampled":      []string{"0"},
+		})
+		require.NoError(t, err)
+
+		ctx := sc.(*SpanContext)
+		assert.Equal(t, uint64(0x48485a3953bb6124), ctx.traceID)
+		assert.Equal(t, uint64(0xa2fb4a1d1a96d312), ctx.spanID)
+		assert.Equal(t, uint64(0x0020000000000001), ctx.parentID)
+	})
+
+	t.Run("Datadog headers take precedence", func(t *testing.T) {
+		sc, err := tr.ExtractHTTPHeader(http.Header{
+			"X-B3-Traceid":        []string{"ff"},
+			"X-B3-Spanid":         []string{"ff"},
+			"X-Datadog-Trace-Id":  []string{"187"},
+			"X-Datadog-Parent-Id": []string{"170"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, uint64(0xbb), sc.(*SpanContext).traceID)
+	})
+
+	t.Run("Corrupted", func(t *testing.T) {
+		_, err := tr.ExtractHTTPHeader(http.Header{"X-B3-Traceid": []string{"NaN"}})
+		assert.Equal(t, opentracing.ErrSpanContextCorrupted, err)
+	})
+
+	t.Run("Extract only", func(t *testing.T) {
+		tr := NewTracer().(*Tracer)
+		tr.EnableB3(false, true)
+
+		h := http.Header{}
+		require.NoError(t, tr.InjectHTTPHeader(span.Context(), h))
+		assert.Empty(t, h.Get("X-B3-Traceid"))
+
+		sc, err := tr.ExtractHTTPHeader(http.Header{
+			"X-B3-Traceid": []string{"bb"},
+			"X-B3-Spanid":  []string{"aa"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, uint64(0xbb), sc.(*SpanContext).traceID)
+	})
+}
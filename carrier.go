@@ -0,0 +1,81 @@
+package ddtracer
+
+import (
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// knownFields maps the propagated fields, in their lower case and canonical
+// MIME forms, to their lower case form. It lets lowerKey match the keys of
+// http.Header, which are canonicalized, without allocating.
+var knownFields = func() map[string]string {
+	m := make(map[string]string)
+	for _, f := range []string{
+		fieldDatadogTraceID, fieldDatadogParentID, fieldDatadogSamplingPriority, fieldDatadogTags,
+		fieldSpanID, fieldTraceID, fieldParentID,
+		b3TraceID, b3SpanID, b3ParentSpanID, b3Sampled,
+		traceParentKey, traceStateKey,
+		xrayTraceIDKey, jaegerTraceIDKey, jaegerBaggageKey,
+	} {
+		m[f] = f
+		m[textproto.CanonicalMIMEHeaderKey(f)] = f
+	}
+	return m
+}()
+
+// lowerKey returns k in lower case, only allocating for the unknown keys
+// with upper case letters.
+func lowerKey(k string) string {
+	if f, ok := knownFields[k]; ok {
+		return f
+	}
+	for i := 0; i < len(k); i++ {
+		if 'A' <= k[i] && k[i] <= 'Z' {
+			return strings.ToLower(k)
+		}
+	}
+	return k
+}
+
+// HeaderCarrier adapts an http.Header as a carrier of the HTTPHeaders and
+// TextMap formats. Unlike opentracing.HTTPHeadersCarrier, it only reads the
+// first value of every header.
+type HeaderCarrier http.Header
+
+// Set implements opentracing.TextMapWriter, canonicalizing key.
+func (c HeaderCarrier) Set(key, val string) {
+	http.Header(c).Set(key, val)
+}
+
+// ForeachKey implements opentracing.TextMapReader.
+func (c HeaderCarrier) ForeachKey(handler func(key, val string) error) error {
+	for k, vals := range c {
+		if len(vals) == 0 {
+			continue
+		}
+		if err := handler(k, vals[0]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MapCarrier adapts a map[string]string as a carrier of the HTTPHeaders and
+// TextMap formats, with its keys in lower case, i.e. for message attributes.
+type MapCarrier map[string]string
+
+// Set implements opentracing.TextMapWriter, lowering key.
+func (c MapCarrier) Set(key, val string) {
+	c[lowerKey(key)] = val
+}
+
+// ForeachKey implements opentracing.TextMapReader.
+func (c MapCarrier) ForeachKey(handler func(key, val string) error) error {
+	for k, v := range c {
+		if err := handler(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package ddtracer
+
+import (
+	"net/http"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLowerKey(t *testing.T) {
+	assert.Equal(t, "x-datadog-trace-id", lowerKey("X-Datadog-Trace-Id"))
+	assert.Equal(t, "x-datadog-trace-id", lowerKey("x-datadog-trace-id"))
+	assert.Equal(t, "x-datadog-trace-id", lowerKey("X-DATADOG-TRACE-ID"))
+	assert.Equal(t, "ot-baggage-user", lowerKey("Ot-Baggage-User"))
+	assert.Equal(t, "traceparent", lowerKey("Traceparent"))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		lowerKey("X-Datadog-Parent-Id")
+		lowerKey("x-b3-traceid")
+	})
+	assert.Zero(t, allocs)
+}
+
+func TestCarriers(t *testing.T) {
+	tr := NewTracer()
+	span := tr.StartSpan("test")
+	span.SetBaggageItem("user", "jane")
+
+	for name, carrier := range map[string]interface {
+		opentracing.TextMapWriter
+		opentracing.TextMapReader
+	}{
+		"Header": HeaderCarrier(http.Header{}),
+		"Map":    MapCarrier{},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, tr.Inject(span.Context(), opentracing.HTTPHeaders, carrier))
+			sc, err := tr.Extract(opentracing.HTTPHeaders, carrier)
+			require.NoError(t, err)
+
+			ctx := sc.(*SpanContext)
+			assert.Equal(t, span.(*Span).TraceID, ctx.traceID)
+			assert.Equal(t, span.(*Span).SpanID, ctx.spanID)
+			assert.Equal(t, "jane", ctx.baggage["user"])
+		})
+	}
+
+	t.Run("Canonical keys", func(t *testing.T) {
+		h := http.Header{}
+		HeaderCarrier(h).Set("x-datadog-trace-id", "1")
+		assert.Equal(t, []string{"1"}, h["X-Datadog-Trace-Id"])
+
+		m := MapCarrier{}
+		m.Set("X-Datadog-Trace-Id", "1")
+		assert.Equal(t, "1", m["x-datadog-trace-id"])
+	})
+}
+
+func BenchmarkExtractHTTPHeaders(b *testing.B) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{})).(*Tracer)
+	h := http.Header{}
+	require.NoError(b, tr.InjectHTTPHeader(tr.StartSpan("test").Context(), h))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tr.ExtractHTTPHeader(h)
+	}
+}
@@ -0,0 +1,72 @@
+// Package chitrace traces the requests served by a chi router with a
+// ddtracer.Tracer, using the matched route pattern as the resource of the spans.
+package chitrace
+
+import (
+	"net/http"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	"github.com/gchaincl/dd-go-opentracing/nethttp"
+	"github.com/go-chi/chi/v5"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+const defaultOperation = "http.request"
+
+// Option configures the Middleware.
+type Option func(*options)
+
+type options struct {
+	operation string
+}
+
+// OperationName sets the operation name of the server spans, "http.request" by default.
+func OperationName(name string) Option {
+	return func(o *options) {
+		o.operation = name
+	}
+}
+
+// Middleware traces every request with a server span child of the context
+// propagated by the client, if any. Its resource is the request method
+// followed by the route pattern, i.e. "GET /users/{id}", or by the
+// normalized path when no route matched. The span is reachable from the
+// request context through ddtracer.SpanFromContext.
+//
+// chi matches the routes once the middlewares are called, so the resource is
+// set when the request has been served.
+func Middleware(tr opentracing.Tracer, opts ...Option) func(http.Handler) http.Handler {
+	o := &options{operation: defaultOperation}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sso := []opentracing.StartSpanOption{
+				ext.SpanKindRPCServer,
+				ddtracer.SpanType(ddtracer.SpanTypeWeb),
+			}
+			if sc, err := tr.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header)); err == nil {
+				sso = append(sso, opentracing.ChildOf(sc))
+			}
+
+			span := tr.StartSpan(o.operation, sso...)
+			defer span.Finish()
+
+			ext.HTTPMethod.Set(span, r.Method)
+			ext.HTTPUrl.Set(span, r.URL.Path)
+
+			sw := nethttp.NewStatusWriter(w)
+			next.ServeHTTP(sw, r.WithContext(ddtracer.ContextWithSpan(r.Context(), span)))
+
+			route := nethttp.NormalizePath(r.URL.Path)
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+			ext.Component.Set(span, r.Method+" "+route)
+			ext.HTTPStatusCode.Set(span, uint16(sw.Status))
+		})
+	}
+}
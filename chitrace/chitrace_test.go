@@ -0,0 +1,58 @@
+package chitrace
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	"github.com/gchaincl/dd-go-opentracing/mocktracer"
+	"github.com/go-chi/chi/v5"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	tr := mocktracer.New()
+
+	router := chi.NewRouter()
+	router.Use(Middleware(tr))
+	var active opentracing.Span
+	router.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		active = ddtracer.SpanFromContext(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	parent := tr.StartSpan("parent").(*ddtracer.Span)
+	req := httptest.NewRequest("GET", "/users/42", nil)
+	require.NoError(t, tr.Inject(parent.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header)))
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := tr.FinishedSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	require.NotNil(t, active)
+	assert.Equal(t, active.(*ddtracer.Span).SpanID, span.SpanID)
+	assert.Equal(t, "http.request", span.Name)
+	assert.Equal(t, parent.TraceID, span.TraceID)
+	assert.Equal(t, parent.SpanID, span.ParentID)
+	assert.Equal(t, "GET /users/{id}", span.Resource)
+	assert.Equal(t, "web", span.Type)
+	assert.Equal(t, "/users/42", span.Tags["http.url"])
+	assert.Equal(t, "418", span.Tags["http.status_code"])
+}
+
+func TestMiddlewareNotFound(t *testing.T) {
+	tr := mocktracer.New()
+
+	router := chi.NewRouter()
+	router.Use(Middleware(tr, OperationName("chi.request")))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+
+	spans := tr.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "chi.request", spans[0].Name)
+	assert.Equal(t, "GET /users/?", spans[0].Resource)
+	assert.Equal(t, "404", spans[0].Tags["http.status_code"])
+}
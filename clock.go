@@ -0,0 +1,43 @@
+package ddtracer
+
+import "time"
+
+// Clock tells the time of the spans started by a Tracer, see WithClock.
+// It must be safe for concurrent use.
+type Clock interface {
+	// Now returns the current time, the start of the spans.
+	Now() time.Time
+	// Since returns the time elapsed since t, a time returned by Now. It
+	// gives the duration of the spans.
+	Since(t time.Time) time.Duration
+}
+
+// systemClock is the default Clock. The times returned by time.Now carry a
+// monotonic clock reading, so the durations survive the wall clock steps
+// (i.e. NTP adjustments).
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+// now returns the current time according to the Clock of the span's Tracer.
+func (s *Span) now() time.Time {
+	if s.tr == nil {
+		return time.Now()
+	}
+	return s.tr.clock.Now()
+}
+
+// since returns the time elapsed since the start of the span, according to
+// the Clock of its Tracer.
+func (s *Span) since() time.Duration {
+	if s.tr == nil || s.start.IsZero() {
+		return time.Duration(time.Now().UnixNano() - s.Start)
+	}
+	return s.tr.clock.Since(s.start)
+}
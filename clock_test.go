@@ -0,0 +1,43 @@
+package ddtracer
+
+import (
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+func (c *fakeClock) Since(t time.Time) time.Duration { return c.t.Sub(t) }
+
+func TestClock(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)}
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}), WithClock(clock))
+
+	t.Run("duration", func(t *testing.T) {
+		start := clock.t
+		span := tr.StartSpan("op").(*Span)
+		clock.add(1500 * time.Millisecond)
+		span.Finish()
+
+		assert.Equal(t, start.UnixNano(), span.Start)
+		assert.Equal(t, int64(1500*time.Millisecond), span.Duration)
+	})
+
+	t.Run("explicit start time", func(t *testing.T) {
+		span := tr.StartSpan("op", opentracing.StartTime(clock.t.Add(-time.Second))).(*Span)
+		clock.add(time.Second)
+		span.Finish()
+
+		assert.Equal(t, int64(2*time.Second), span.Duration)
+	})
+
+	t.Run("explicit finish time", func(t *testing.T) {
+		span := tr.StartSpan("op").(*Span)
+		span.FinishWithOptions(opentracing.FinishOptions{FinishTime: clock.t.Add(time.Minute)})
+
+		assert.Equal(t, int64(time.Minute), span.Duration)
+	})
+}
@@ -0,0 +1,47 @@
+package ddtracer
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+const (
+	logTraceIDKey = "dd.trace_id"
+	logSpanIDKey  = "dd.span_id"
+)
+
+// LogCorrelationFields returns the dd.trace_id and dd.span_id fields of the
+// active span in ctx, ready to be attached to a structured logger.
+// An empty slice is returned when there's no active span.
+func LogCorrelationFields(ctx context.Context) []log.Field {
+	span := activeSpan(ctx)
+	if span == nil {
+		return []log.Field{}
+	}
+
+	return []log.Field{
+		log.String(logTraceIDKey, strconv.FormatUint(span.TraceID, 10)),
+		log.String(logSpanIDKey, strconv.FormatUint(span.SpanID, 10)),
+	}
+}
+
+// LogCorrelationMap is like LogCorrelationFields, but returns the fields as a map.
+func LogCorrelationMap(ctx context.Context) map[string]interface{} {
+	fields := LogCorrelationFields(ctx)
+	m := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		m[f.Key()] = f.Value()
+	}
+	return m
+}
+
+// activeSpan returns the DataDog span of the span in ctx, see SpanFromContext.
+func activeSpan(ctx context.Context) *tracer.Span {
+	if s, ok := SpanFromContext(ctx).(*Span); ok {
+		return s.Span
+	}
+	return nil
+}
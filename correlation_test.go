@@ -0,0 +1,32 @@
+package ddtracer
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogCorrelationFields(t *testing.T) {
+	span := NewTracer().StartSpan("test").(*Span)
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+
+	fields := LogCorrelationFields(ctx)
+	require.Len(t, fields, 2)
+	assert.Equal(t, "dd.trace_id", fields[0].Key())
+	assert.Equal(t, strconv.FormatUint(span.TraceID, 10), fields[0].Value())
+	assert.Equal(t, "dd.span_id", fields[1].Key())
+	assert.Equal(t, strconv.FormatUint(span.SpanID, 10), fields[1].Value())
+
+	m := LogCorrelationMap(ctx)
+	assert.Equal(t, strconv.FormatUint(span.TraceID, 10), m["dd.trace_id"])
+	assert.Equal(t, strconv.FormatUint(span.SpanID, 10), m["dd.span_id"])
+
+	t.Run("No active span", func(t *testing.T) {
+		assert.Empty(t, LogCorrelationFields(context.Background()))
+		assert.Empty(t, LogCorrelationMap(context.Background()))
+	})
+}
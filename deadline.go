@@ -0,0 +1,50 @@
+package ddtracer
+
+import (
+	"context"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+const (
+	// deadlineRemainingKey is the time budget left by the deadline of the
+	// context when the span started.
+	deadlineRemainingKey = "deadline.remaining_ms"
+
+	// deadlineExceededKey marks the spans finished after the deadline.
+	deadlineExceededKey = "deadline_exceeded"
+)
+
+// StartSpanFromContextWithDeadline is StartSpanFromContext tagging the span
+// with the milliseconds left before the deadline of ctx, if any, as
+// deadline.remaining_ms, and with deadline_exceeded when finished after it.
+// It helps diagnosing the timeouts cascading through the services.
+func StartSpanFromContextWithDeadline(ctx context.Context, op string, opts ...opentracing.StartSpanOption) (opentracing.Span, context.Context) {
+	span, ctx := StartSpanFromContext(ctx, op, opts...)
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return span, ctx
+	}
+	// The time left is measured from the start of the span, as read from the
+	// clock of its Tracer, see WithClock.
+	remaining := time.Until(deadline)
+	if s, ok := span.(*Span); ok && s.lock() {
+		s.deadline = deadline
+		if !s.start.IsZero() {
+			remaining = deadline.Sub(s.start)
+		}
+		s.mu.Unlock()
+	}
+	span.SetTag(deadlineRemainingKey, remaining.Seconds()*1000)
+	return span, ctx
+}
+
+// checkDeadline tags the span as deadline_exceeded if it finished after its
+// deadline. The span must be locked.
+func (s *Span) checkDeadline() {
+	if !s.deadline.IsZero() && s.Start+s.Duration > s.deadline.UnixNano() {
+		s.setTag(deadlineExceededKey, true)
+	}
+}
@@ -0,0 +1,57 @@
+package ddtracer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartSpanFromContextWithDeadline(t *testing.T) {
+	prev := opentracing.GlobalTracer()
+	defer opentracing.SetGlobalTracer(prev)
+	opentracing.SetGlobalTracer(NewTracer())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	span, sctx := StartSpanFromContextWithDeadline(ctx, "in time")
+	assert.Equal(t, span, SpanFromContext(sctx))
+	remaining := span.(*Span).Metrics[deadlineRemainingKey]
+	assert.True(t, remaining > 59000 && remaining <= 60000, "%v", remaining)
+	span.Finish()
+	assert.Empty(t, span.(*Span).GetMeta(deadlineExceededKey))
+
+	t.Run("Exceeded", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+
+		span, _ := StartSpanFromContextWithDeadline(ctx, "late")
+		<-ctx.Done()
+		span.Finish()
+		assert.Equal(t, "true", span.(*Span).GetMeta(deadlineExceededKey))
+	})
+
+	t.Run("Clock", func(t *testing.T) {
+		clock := &fakeClock{t: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)}
+		opentracing.SetGlobalTracer(NewTracerWithOptions(WithTransport(discardTransport{}), WithClock(clock)))
+
+		ctx, cancel := context.WithDeadline(context.Background(), clock.t.Add(2*time.Second))
+		defer cancel()
+
+		span, _ := StartSpanFromContextWithDeadline(ctx, "op")
+		assert.Equal(t, 2000.0, span.(*Span).Metrics[deadlineRemainingKey])
+		clock.add(3 * time.Second)
+		span.Finish()
+		assert.Equal(t, "true", span.(*Span).GetMeta(deadlineExceededKey))
+	})
+
+	t.Run("No deadline", func(t *testing.T) {
+		span, _ := StartSpanFromContextWithDeadline(context.Background(), "op")
+		span.Finish()
+		assert.NotContains(t, span.(*Span).Metrics, deadlineRemainingKey)
+		assert.Empty(t, span.(*Span).GetMeta(deadlineExceededKey))
+	})
+}
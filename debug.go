@@ -0,0 +1,267 @@
+package ddtracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// maxDebugTraces is the number of traces kept for DumpTrace, the oldest
+// ones being evicted first.
+const maxDebugTraces = 1000
+
+// debugObserver logs every finished span on a single line, and keeps the
+// lines of the latest traces for DumpTrace. See WithDebug.
+type debugObserver struct {
+	logger Logger
+
+	mu     sync.Mutex
+	traces map[uint64][]string
+	order  []uint64
+}
+
+func newDebugObserver(logger Logger) *debugObserver {
+	return &debugObserver{logger: logger, traces: make(map[uint64][]string)}
+}
+
+func (o *debugObserver) OnStart(span *Span) {}
+
+func (o *debugObserver) OnFinish(span *Span) {
+	line := formatSpan(span)
+	o.logger.Printf("span %s", line)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	lines, ok := o.traces[span.TraceID]
+	if !ok {
+		if len(o.order) == maxDebugTraces {
+			delete(o.traces, o.order[0])
+			o.order = o.order[1:]
+		}
+		o.order = append(o.order, span.TraceID)
+	}
+	o.traces[span.TraceID] = append(lines, line)
+}
+
+func (o *debugObserver) dump(traceID uint64) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return strings.Join(o.traces[traceID], "\n")
+}
+
+// formatSpan formats span on a single line, its tags sorted by key, i.e.
+// trace_id=1 span_id=2 parent_id=1 name="op" service="svc" resource="res"
+// duration=1.5ms error=0 tags={"k":"v"}.
+func formatSpan(span *Span) string {
+	tags := make([]string, 0, len(span.Meta)+len(span.Metrics))
+	for k, v := range span.Meta {
+		tags = append(tags, strconv.Quote(k)+":"+strconv.Quote(v))
+	}
+	for k, v := range span.Metrics {
+		tags = append(tags, strconv.Quote(k)+":"+strconv.FormatFloat(v, 'g', -1, 64))
+	}
+	sort.Strings(tags)
+
+	return fmt.Sprintf("trace_id=%d span_id=%d parent_id=%d name=%q service=%q resource=%q duration=%s error=%d tags={%s}",
+		span.TraceID, span.SpanID, span.ParentID, span.Name, span.Service, span.Resource,
+		time.Duration(span.Duration), span.Error, strings.Join(tags, ","))
+}
+
+// DumpTrace returns the spans of the trace traceID finished by the Tracer,
+// one per line as they are logged by WithDebug. It's empty unless debug is
+// enabled, or once the trace has been evicted by the newer ones.
+func (t *Tracer) DumpTrace(traceID uint64) string {
+	if t.debug == nil {
+		return ""
+	}
+	return t.debug.dump(traceID)
+}
+
+// DebugHandler returns an http.Handler reporting the state of the Tracer as
+// JSON, i.e. on a debug port: its configuration, the queued spans, its
+// Stats, the status of the last flush, its samplers and the span durations
+// percentiles when enabled by WithDurationHistograms. With a trace_id query
+// parameter, in decimal, it writes the DumpTrace of the trace instead, to
+// check the instrumentation locally.
+func (t *Tracer) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if _, ok := query["trace_id"]; !ok {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(t.debugState())
+			return
+		}
+
+		traceID, err := strconv.ParseUint(query.Get("trace_id"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid trace_id", http.StatusBadRequest)
+			return
+		}
+		dump := t.DumpTrace(traceID)
+		if dump == "" {
+			http.Error(w, "trace not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, dump)
+	})
+}
+
+// DebugHandler returns the Tracer.DebugHandler of the opentracing global
+// tracer, responding 404 while it's not a Tracer.
+func DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t, ok := opentracing.GlobalTracer().(*Tracer)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		t.DebugHandler().ServeHTTP(w, r)
+	})
+}
+
+// debugState is the state of a Tracer reported by DebugHandler.
+type debugState struct {
+	Service           string  `json:"service"`
+	Enabled           bool    `json:"enabled"`
+	Debug             bool    `json:"debug"`
+	SampleRate        float64 `json:"sample_rate"`
+	AnalyticsRate     float64 `json:"analytics_rate"`
+	TraceID128Bit     bool    `json:"trace_id_128bit"`
+	PartialFlushSpans uint64  `json:"partial_flush_spans"`
+
+	QueuedSpans int `json:"queued_spans"`
+	RetrySpans  int `json:"retry_spans"`
+
+	SpansStarted  uint64 `json:"spans_started"`
+	SpansFinished uint64 `json:"spans_finished"`
+	SpansDropped  uint64 `json:"spans_dropped"`
+	SpansLimited  uint64 `json:"spans_limited"`
+	Truncations   uint64 `json:"truncations"`
+	Flushes       uint64 `json:"flushes"`
+	FlushErrors   uint64 `json:"flush_errors"`
+
+	LastFlush *lastFlushState `json:"last_flush,omitempty"`
+
+	Sampler map[string]interface{} `json:"sampler,omitempty"`
+
+	Durations map[string]durationsState `json:"durations,omitempty"`
+}
+
+// durationsState summarizes a DurationHistogram.
+type durationsState struct {
+	Count       uint64        `json:"count"`
+	MeanSeconds float64       `json:"mean_seconds"`
+	MinSeconds  float64       `json:"min_seconds"`
+	P50Seconds  float64       `json:"p50_seconds"`
+	P90Seconds  float64       `json:"p90_seconds"`
+	P99Seconds  float64       `json:"p99_seconds"`
+	MaxSeconds  float64       `json:"max_seconds"`
+	Buckets     []bucketState `json:"buckets"`
+}
+
+type bucketState struct {
+	// LESeconds is the upper bound of the bucket, absent for the last one.
+	LESeconds float64 `json:"le_seconds,omitempty"`
+	Count     uint64  `json:"count"`
+}
+
+func newDurationsState(h DurationHistogram) durationsState {
+	state := durationsState{
+		Count:       h.Count,
+		MeanSeconds: h.Mean().Seconds(),
+		MinSeconds:  h.Min.Seconds(),
+		P50Seconds:  h.Quantile(0.5).Seconds(),
+		P90Seconds:  h.Quantile(0.9).Seconds(),
+		P99Seconds:  h.Quantile(0.99).Seconds(),
+		MaxSeconds:  h.Max.Seconds(),
+	}
+	for _, b := range h.Buckets {
+		state.Buckets = append(state.Buckets, bucketState{LESeconds: b.UpperBound.Seconds(), Count: b.Count})
+	}
+	return state
+}
+
+type lastFlushState struct {
+	Time           time.Time `json:"time"`
+	LatencySeconds float64   `json:"latency_seconds"`
+	Error          string    `json:"error,omitempty"`
+}
+
+func (t *Tracer) debugState() *debugState {
+	s := t.Stats()
+	state := &debugState{
+		Service:           t.serviceName(),
+		Enabled:           t.Enabled(),
+		Debug:             t.debug != nil,
+		SampleRate:        t.SampleRate(),
+		AnalyticsRate:     t.AnalyticsRate,
+		TraceID128Bit:     t.TraceID128Bit,
+		PartialFlushSpans: t.partialFlushSpans,
+		SpansStarted:      s.SpansStarted,
+		SpansFinished:     s.SpansFinished,
+		SpansDropped:      s.SpansDropped,
+		SpansLimited:      s.SpansLimited,
+		Truncations:       s.Truncations,
+		Flushes:           s.Flushes,
+		FlushErrors:       s.FlushErrors,
+		Sampler:           describeSampler(t.currentSampler()),
+	}
+	if t.queue != nil {
+		state.QueuedSpans = t.queue.queued()
+	}
+	if len(s.Durations) > 0 {
+		state.Durations = make(map[string]durationsState, len(s.Durations))
+		for op, h := range s.Durations {
+			state.Durations[op] = newDurationsState(h)
+		}
+	}
+	for _, r := range t.retries() {
+		state.RetrySpans += r.kept()
+	}
+
+	t.stats.lastFlushMu.Lock()
+	if !t.stats.lastFlush.IsZero() {
+		state.LastFlush = &lastFlushState{Time: t.stats.lastFlush, LatencySeconds: s.FlushLatency.Seconds()}
+		if err := t.stats.lastFlushErr; err != nil {
+			state.LastFlush.Error = err.Error()
+		}
+	}
+	t.stats.lastFlushMu.Unlock()
+
+	return state
+}
+
+// describeSampler returns the type and the rates of s, nil when it's nil.
+func describeSampler(s Sampler) map[string]interface{} {
+	switch s := s.(type) {
+	case nil:
+		return nil
+	case allSampler:
+		return map[string]interface{}{"type": "all"}
+	case rateSampler:
+		return map[string]interface{}{"type": "rate", "rate": float64(s)}
+	case *serviceSampler:
+		return map[string]interface{}{
+			"type":       "service",
+			"services":   s.services,
+			"operations": s.operations,
+			"fallback":   describeSampler(s.fallback),
+		}
+	case *rateLimitedSampler:
+		return map[string]interface{}{"type": "rate_limited", "per_second": s.perSecond}
+	case *adaptiveSampler:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return map[string]interface{}{"type": "adaptive", "target": s.target, "rate": s.rate}
+	}
+	return map[string]interface{}{"type": fmt.Sprintf("%T", s)}
+}
@@ -0,0 +1,109 @@
+package ddtracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugLogsSpans(t *testing.T) {
+	l := &recordingLogger{}
+	tr := NewTracerWithOptions(
+		WithServiceName("svc"),
+		WithTransport(discardTransport{}),
+		WithLogger(l),
+		WithDebug(true),
+	).(*Tracer)
+
+	start := time.Now()
+	root := tr.StartSpan("root", opentracing.StartTime(start)).(*Span)
+	child := tr.StartSpan("child", opentracing.ChildOf(root.Context()), opentracing.Tag{Key: "k", Value: "v"}).(*Span)
+	child.Finish()
+	root.FinishWithOptions(opentracing.FinishOptions{FinishTime: start.Add(1500 * time.Microsecond)})
+
+	require.Len(t, l.lines, 2)
+	assert.True(t, strings.HasPrefix(l.lines[0], fmt.Sprintf("span trace_id=%d span_id=%d parent_id=%d name=\"child\"", root.TraceID, child.SpanID, root.SpanID)))
+	assert.Contains(t, l.lines[0], `"k":"v"`)
+	assert.Equal(t,
+		fmt.Sprintf(`span trace_id=%d span_id=%d parent_id=0 name="root" service="svc" resource="root" duration=1.5ms error=0 tags={}`, root.TraceID, root.SpanID),
+		l.lines[1])
+
+	dump := tr.DumpTrace(root.TraceID)
+	assert.Equal(t, strings.TrimPrefix(l.lines[0], "span ")+"\n"+strings.TrimPrefix(l.lines[1], "span "), dump)
+	assert.Empty(t, tr.DumpTrace(root.TraceID+1))
+}
+
+func TestDumpTraceDisabled(t *testing.T) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{})).(*Tracer)
+	span := tr.StartSpan("op").(*Span)
+	span.Finish()
+	assert.Empty(t, tr.DumpTrace(span.TraceID))
+}
+
+func TestDebugHandler(t *testing.T) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}), WithLogger(NopLogger), WithDebug(true)).(*Tracer)
+	span := tr.StartSpan("op").(*Span)
+	span.Finish()
+
+	for query, status := range map[string]int{
+		fmt.Sprint(span.TraceID):     http.StatusOK,
+		fmt.Sprint(span.TraceID + 1): http.StatusNotFound,
+		"abc":                        http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		tr.DebugHandler().ServeHTTP(w, httptest.NewRequest("GET", "/?trace_id="+query, nil))
+		assert.Equal(t, status, w.Code, query)
+	}
+}
+
+func TestDebugHandlerState(t *testing.T) {
+	tr := NewTracerWithOptions(
+		WithServiceName("svc"),
+		WithTransport(&flakyTransport{down: 1}),
+		WithLogger(NopLogger),
+		WithSampler(ServiceSampler(map[string]float64{"svc": 1}, nil, nil)),
+		WithMaxQueueSize(100),
+		WithFlushInterval(time.Hour),
+	).(*Tracer)
+	defer tr.Close()
+
+	tr.StartSpan("op").Finish()
+	tr.StartSpan("op").Finish()
+	tr.FlushTraces()
+
+	w := httptest.NewRecorder()
+	tr.DebugHandler().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var state map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &state))
+	assert.Equal(t, "svc", state["service"])
+	assert.Equal(t, float64(1), state["sample_rate"])
+	assert.Equal(t, float64(2), state["spans_started"])
+	assert.Equal(t, "service", state["sampler"].(map[string]interface{})["type"])
+	assert.Equal(t, "connection refused", state["last_flush"].(map[string]interface{})["error"])
+}
+
+func TestGlobalDebugHandler(t *testing.T) {
+	prev := opentracing.GlobalTracer()
+	defer opentracing.SetGlobalTracer(prev)
+
+	opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+	w := httptest.NewRecorder()
+	DebugHandler().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	opentracing.SetGlobalTracer(NewTracerWithOptions(WithTransport(discardTransport{})))
+	w = httptest.NewRecorder()
+	DebugHandler().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -6,4 +6,25 @@
 // to invoke Span.SetError, opentracing-go/log.Error() has been used (see examples below).
 //
 // This is an OpenTracing API wrapper, all the methods implementing the specifications are described in detail on official documentation.
+//
+// # Performance
+//
+// Starting and finishing a span without tags only allocates the DataDog span
+// and its wrapper, which aren't pooled as they might be referenced once finished.
+// The allocations of the hot paths, measured with go test -bench . -benchmem
+// before and after the span contexts stopped wrapping their span in a
+// context.Context and the Datadog headers got extracted into a single struct:
+//
+//	Benchmark                before              after
+//	StartSpan                2 allocs  373 B     2 allocs  369 B
+//	StartChildSpan           8 allocs  600 B     5 allocs  504 B
+//	StartSpanWithTags       14 allocs 1295 B    11 allocs 1192 B
+//	SetTag                   0 allocs    8 B     0 allocs    8 B
+//	SpanContext              3 allocs  176 B     1 allocs   96 B
+//	InjectTextMap            4 allocs  176 B     3 allocs  144 B
+//	InjectHTTPHeaders        7 allocs  520 B     6 allocs  471 B
+//	ExtractTextMap          14 allocs 1024 B     7 allocs  816 B
+//	ExtractHTTPHeaders      14 allocs 1024 B     7 allocs  816 B
+//
+// TestStartSpanAllocs guards the allocations of the tagless spans.
 package ddtracer
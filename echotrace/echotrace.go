@@ -0,0 +1,84 @@
+// Package echotrace traces the requests served by an echo router with a
+// ddtracer.Tracer, using the matched route as the resource of the spans.
+package echotrace
+
+import (
+	"net/http"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	"github.com/gchaincl/dd-go-opentracing/nethttp"
+	"github.com/labstack/echo/v4"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+const defaultOperation = "http.request"
+
+// Option configures the Middleware.
+type Option func(*options)
+
+type options struct {
+	operation string
+}
+
+// OperationName sets the operation name of the server spans, "http.request" by default.
+func OperationName(name string) Option {
+	return func(o *options) {
+		o.operation = name
+	}
+}
+
+// Middleware traces every request with a server span child of the context
+// propagated by the client, if any. Its resource is the request method
+// followed by the route, i.e. "GET /users/:id", or by the normalized path
+// when no route matched. The span is reachable from the request context
+// through ddtracer.SpanFromContext.
+//
+// The errors returned by the handlers are logged on the span, whose status
+// code is the one echo responds with: the code of the *echo.HTTPError, 500
+// for the other errors.
+func Middleware(tr opentracing.Tracer, opts ...Option) echo.MiddlewareFunc {
+	o := &options{operation: defaultOperation}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			r := c.Request()
+			sso := []opentracing.StartSpanOption{
+				ext.SpanKindRPCServer,
+				ddtracer.SpanType(ddtracer.SpanTypeWeb),
+			}
+			if sc, err := tr.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header)); err == nil {
+				sso = append(sso, opentracing.ChildOf(sc))
+			}
+
+			span := tr.StartSpan(o.operation, sso...)
+			defer span.Finish()
+
+			route := c.Path()
+			if route == "" {
+				route = nethttp.NormalizePath(r.URL.Path)
+			}
+			ext.Component.Set(span, r.Method+" "+route)
+			ext.HTTPMethod.Set(span, r.Method)
+			ext.HTTPUrl.Set(span, r.URL.Path)
+
+			c.SetRequest(r.WithContext(ddtracer.ContextWithSpan(r.Context(), span)))
+			err := next(c)
+
+			status := c.Response().Status
+			if err != nil {
+				span.LogFields(log.Error(err))
+				status = http.StatusInternalServerError
+				if he, ok := err.(*echo.HTTPError); ok {
+					status = he.Code
+				}
+			}
+			ext.HTTPStatusCode.Set(span, uint16(status))
+			return err
+		}
+	}
+}
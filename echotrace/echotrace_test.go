@@ -0,0 +1,67 @@
+package echotrace
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	"github.com/gchaincl/dd-go-opentracing/mocktracer"
+	"github.com/labstack/echo/v4"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	tr := mocktracer.New()
+
+	e := echo.New()
+	e.Use(Middleware(tr))
+	var active opentracing.Span
+	e.GET("/users/:id", func(c echo.Context) error {
+		active = ddtracer.SpanFromContext(c.Request().Context())
+		return c.NoContent(http.StatusTeapot)
+	})
+
+	parent := tr.StartSpan("parent").(*ddtracer.Span)
+	req := httptest.NewRequest("GET", "/users/42", nil)
+	require.NoError(t, tr.Inject(parent.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header)))
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := tr.FinishedSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	require.NotNil(t, active)
+	assert.Equal(t, active.(*ddtracer.Span).SpanID, span.SpanID)
+	assert.Equal(t, "http.request", span.Name)
+	assert.Equal(t, parent.TraceID, span.TraceID)
+	assert.Equal(t, parent.SpanID, span.ParentID)
+	assert.Equal(t, "GET /users/:id", span.Resource)
+	assert.Equal(t, "web", span.Type)
+	assert.Equal(t, "/users/42", span.Tags["http.url"])
+	assert.Equal(t, "418", span.Tags["http.status_code"])
+}
+
+func TestMiddlewareErrors(t *testing.T) {
+	tr := mocktracer.New()
+
+	e := echo.New()
+	e.Use(Middleware(tr, OperationName("echo.request")))
+	e.GET("/fail", func(c echo.Context) error {
+		return errors.New("boom")
+	})
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+
+	spans := tr.FinishedSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "echo.request", spans[0].Name)
+	assert.Equal(t, "500", spans[0].Tags["http.status_code"])
+	assert.True(t, spans[0].Error)
+	assert.Equal(t, "boom", spans[0].Tags["error.msg"])
+
+	assert.Equal(t, "GET /users/?", spans[1].Resource)
+	assert.Equal(t, "404", spans[1].Tags["http.status_code"])
+}
@@ -0,0 +1,115 @@
+// Package elastictrace traces the requests of the Elasticsearch clients with
+// db spans, child of the span in the request context. It wraps the HTTP
+// transport of the client, i.e. the Transport of the go-elasticsearch
+// Config, or the HTTP client of olivere/elastic.
+package elastictrace
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	"github.com/gchaincl/dd-go-opentracing/nethttp"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+const (
+	defaultService = "elasticsearch"
+
+	queryOperation = "elasticsearch.query"
+
+	// TagBody is the body of the request, its values obfuscated.
+	TagBody = "elasticsearch.body"
+)
+
+// Option configures the Transport.
+type Option func(*transport)
+
+// ServiceName sets the service of the spans, "elasticsearch" by default.
+func ServiceName(name string) Option {
+	return func(t *transport) {
+		t.service = name
+	}
+}
+
+type transport struct {
+	tr      opentracing.Tracer
+	rt      http.RoundTripper
+	service string
+}
+
+// NewTransport returns an http.RoundTripper tracing the requests sent through
+// rt, http.DefaultTransport when nil. The resource of the spans is the
+// request method followed by its normalized path, i.e. "GET /users/_doc/?",
+// and the body of the queries is obfuscated by ddtracer.ObfuscateJSON.
+func NewTransport(tr opentracing.Tracer, rt http.RoundTripper, opts ...Option) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	t := &transport{tr: tr, rt: rt, service: defaultService}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	span := t.tr.StartSpan(queryOperation, ddtracer.ChildOfContext(req.Context()), ext.SpanKindRPCClient)
+	defer span.Finish()
+
+	ext.DBType.Set(span, "elasticsearch")
+	ext.PeerService.Set(span, t.service)
+	ext.Component.Set(span, req.Method+" "+nethttp.NormalizePath(req.URL.Path))
+	ext.HTTPMethod.Set(span, req.Method)
+	ext.HTTPUrl.Set(span, req.URL.Path)
+
+	req, body, err := readBody(req)
+	if err != nil {
+		span.LogFields(log.Error(err))
+		return nil, err
+	}
+	if len(body) > 0 {
+		span.SetTag(TagBody, ddtracer.ObfuscateJSON(string(body)))
+	}
+
+	resp, err := t.rt.RoundTrip(req)
+	if err != nil {
+		span.LogFields(log.Error(err))
+		return resp, err
+	}
+
+	ext.HTTPStatusCode.Set(span, uint16(resp.StatusCode))
+	return resp, nil
+}
+
+// readBody returns the body of req, and a request to send in place of req
+// when reading it consumed the body of req. RoundTrippers must not modify the
+// request, the body is read from a copy when req has a GetBody.
+func readBody(req *http.Request) (*http.Request, []byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil, nil
+	}
+
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return req, nil, err
+		}
+		defer rc.Close()
+		body, err := ioutil.ReadAll(rc)
+		return req, body, err
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return req, nil, err
+	}
+	r := new(http.Request)
+	*r = *req
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return r, body, nil
+}
@@ -0,0 +1,84 @@
+package elastictrace
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	"github.com/gchaincl/dd-go-opentracing/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransport(t *testing.T) {
+	var received string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received = string(body)
+		w.Write([]byte(`{"hits":{}}`))
+	}))
+	defer ts.Close()
+
+	tr := mocktracer.New()
+	client := &http.Client{Transport: NewTransport(tr, nil, ServiceName("search"))}
+
+	parent := tr.StartSpan("parent").(*ddtracer.Span)
+	ctx := ddtracer.ContextWithSpan(context.Background(), parent)
+
+	query := `{"query":{"match":{"email":"jane@example.com"}}}`
+	// http.NewRequest sets the GetBody of the strings.Reader bodies only.
+	for _, body := range []io.Reader{
+		strings.NewReader(query),
+		ioutil.NopCloser(strings.NewReader(query)),
+	} {
+		req, _ := http.NewRequest("POST", ts.URL+"/users/_search", body)
+		resp, err := client.Do(req.WithContext(ctx))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, query, received)
+	}
+
+	spans := tr.FinishedSpans()
+	require.Len(t, spans, 2)
+	for _, span := range spans {
+		assert.Equal(t, "elasticsearch.query", span.Name)
+		assert.Equal(t, "search", span.Service)
+		assert.Equal(t, "db", span.Type)
+		assert.Equal(t, "POST /users/_search", span.Resource)
+		assert.Equal(t, `{"query":{"match":{"email":?}}}`, span.Tags[TagBody])
+		assert.Equal(t, "200", span.Tags["http.status_code"])
+		assert.Equal(t, parent.TraceID, span.TraceID)
+		assert.Equal(t, parent.SpanID, span.ParentID)
+	}
+}
+
+func TestTransportErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	tr := mocktracer.New()
+	client := &http.Client{Transport: NewTransport(tr, nil)}
+
+	resp, err := client.Get(ts.URL + "/users/_doc/42")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	ts.Close()
+	_, err = client.Get(ts.URL + "/users/_doc/42")
+	require.Error(t, err)
+
+	spans := tr.FinishedSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "elasticsearch", spans[0].Service)
+	assert.Equal(t, "GET /users/_doc/?", spans[0].Resource)
+	assert.NotContains(t, spans[0].Tags, TagBody)
+	assert.True(t, spans[0].Error)
+	assert.True(t, spans[1].Error)
+}
@@ -0,0 +1,72 @@
+package ddtracer
+
+import (
+	"net"
+	"os"
+	"strconv"
+)
+
+// Environment variables read by the Tracer, the same as the other DataDog tracing libraries.
+const (
+	agentHostEnv  = "DD_AGENT_HOST"
+	agentPortEnv  = "DD_TRACE_AGENT_PORT"
+	serviceEnv    = "DD_SERVICE"
+	envEnv        = "DD_ENV"
+	versionEnv    = "DD_VERSION"
+	sampleRateEnv = "DD_TRACE_SAMPLE_RATE"
+	apiKeyEnv     = "DD_API_KEY"
+	siteEnv       = "DD_SITE"
+	statsdPortEnv = "DD_DOGSTATSD_PORT"
+
+	spanSamplingRulesEnv     = "DD_SPAN_SAMPLING_RULES"
+	spanSamplingRulesFileEnv = "DD_SPAN_SAMPLING_RULES_FILE"
+
+	propagationStyleEnv        = "DD_TRACE_PROPAGATION_STYLE"
+	propagationStyleInjectEnv  = "DD_TRACE_PROPAGATION_STYLE_INJECT"
+	propagationStyleExtractEnv = "DD_TRACE_PROPAGATION_STYLE_EXTRACT"
+)
+
+// loadEnv configures c from the environment, the invalid values are ignored,
+// the invalid span sampling rules being logged by NewTracerWithOptions.
+func (c *config) loadEnv() {
+	c.agentHost, c.agentPort = os.Getenv(agentHostEnv), os.Getenv(agentPortEnv)
+	c.apiKey, c.site = os.Getenv(apiKeyEnv), os.Getenv(siteEnv)
+
+	if port := os.Getenv(statsdPortEnv); port != "" {
+		host := c.agentHost
+		if host == "" {
+			host = "localhost"
+		}
+		c.dogstatsdAddr = net.JoinHostPort(host, port)
+	}
+
+	if v := os.Getenv(serviceEnv); v != "" {
+		c.service = v
+	}
+	if v := os.Getenv(envEnv); v != "" {
+		c.tags[string(EnvTag)] = v
+	}
+	if v := os.Getenv(versionEnv); v != "" {
+		c.tags[string(VersionTag)] = v
+	}
+
+	if v := os.Getenv(propagationStyleEnv); v != "" {
+		c.propagationInject, c.propagationExtract = splitStyles(v), splitStyles(v)
+	}
+	if v := os.Getenv(propagationStyleInjectEnv); v != "" {
+		c.propagationInject = splitStyles(v)
+	}
+	if v := os.Getenv(propagationStyleExtractEnv); v != "" {
+		c.propagationExtract = splitStyles(v)
+	}
+
+	if rules, file := os.Getenv(spanSamplingRulesEnv), os.Getenv(spanSamplingRulesFileEnv); rules != "" || file != "" {
+		c.spanRules, c.spanRulesErr = loadSpanSamplingRules(rules, file)
+	}
+
+	if v := os.Getenv(sampleRateEnv); v != "" {
+		if rate, err := strconv.ParseFloat(v, 64); err == nil && rate >= 0 && rate <= 1 {
+			c.sampleRate = rate
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package ddtracer
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setenv sets the environment variables of vars, and returns a function restoring them.
+func setenv(vars map[string]string) func() {
+	for k, v := range vars {
+		os.Setenv(k, v)
+	}
+	return func() {
+		for k := range vars {
+			os.Unsetenv(k)
+		}
+	}
+}
+
+func TestEnvConfig(t *testing.T) {
+	env := newEnv(t)
+	defer env.close()
+	u, _ := url.Parse(env.ts.URL)
+	hostPort := strings.Split(u.Host, ":")
+
+	defer setenv(map[string]string{
+		agentHostEnv: hostPort[0],
+		agentPortEnv: hostPort[1],
+		serviceEnv:   "env-service",
+		envEnv:       "staging",
+		versionEnv:   "1.2.3",
+	})()
+
+	tr := NewTracer().(*Tracer)
+
+	parent := tr.StartSpan("parent")
+	child := tr.StartSpan("child", opentracing.ChildOf(parent.Context()))
+	child.Finish()
+	parent.Finish()
+	require.NoError(t, tr.FlushTraces())
+
+	require.Len(t, env.reqs, 1)
+	var traces [][]*tracer.Span
+	require.NoError(t, json.NewDecoder(env.reqs[0].Body).Decode(&traces))
+	require.Len(t, traces, 1)
+	require.Len(t, traces[0], 2)
+	for _, span := range traces[0] {
+		assert.Equal(t, "env-service", span.Service)
+		assert.Equal(t, "staging", span.Meta["env"])
+		assert.Equal(t, "1.2.3", span.Meta["version"])
+	}
+}
+
+func TestEnvConfigSampleRate(t *testing.T) {
+	for value, rate := range map[string]float64{
+		"0.5":  0.5,
+		"0":    0,
+		"1.5":  1,
+		"-1":   1,
+		"nope": 1,
+	} {
+		restore := setenv(map[string]string{sampleRateEnv: value})
+		c := &config{sampleRate: 1, tags: make(map[string]string)}
+		c.loadEnv()
+		restore()
+
+		assert.Equal(t, rate, c.sampleRate, value)
+	}
+}
+
+func TestEnvConfigOptionsPrecedence(t *testing.T) {
+	defer setenv(map[string]string{
+		serviceEnv: "env-service",
+		envEnv:     "staging",
+	})()
+
+	tr := NewTracerWithOptions(
+		WithServiceName("opt-service"),
+		WithGlobalTags(map[string]string{"env": "prod"}),
+	).(*Tracer)
+
+	span := tr.StartSpan("op").(*Span)
+	assert.Equal(t, "opt-service", span.Service)
+	assert.Equal(t, "prod", span.GetMeta("env"))
+}
+
+func TestEnvPropagationStyle(t *testing.T) {
+	defer setenv(map[string]string{
+		propagationStyleEnv:        "datadog",
+		propagationStyleExtractEnv: "b3,datadog",
+	})()
+
+	c := &config{tags: make(map[string]string)}
+	c.loadEnv()
+	assert.Equal(t, []string{"datadog"}, c.propagationInject)
+	assert.Equal(t, []string{"b3", "datadog"}, c.propagationExtract)
+}
+
+func TestEnvDogstatsdPort(t *testing.T) {
+	c := &config{tags: make(map[string]string)}
+	c.loadEnv()
+	assert.Empty(t, c.dogstatsdAddr)
+
+	defer setenv(map[string]string{
+		agentHostEnv:  "agent",
+		statsdPortEnv: "8125",
+	})()
+	c.loadEnv()
+	assert.Equal(t, "agent:8125", c.dogstatsdAddr)
+}
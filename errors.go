@@ -0,0 +1,111 @@
+package ddtracer
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+const (
+	errorMsgKey   = "error.msg"
+	errorTypeKey  = "error.type"
+	errorStackKey = "error.stack"
+
+	// OpenTracing's error conventions, see
+	// https://github.com/opentracing/specification/blob/master/semantic_conventions.md
+	errorObjectKey  = "error.object"
+	errorKindKey    = "error.kind"
+	errorEvent      = "error"
+	errorMessageKey = "message"
+	errorLogStack   = "stack"
+)
+
+// setErrorTag maps the OpenTracing error tags onto the DataDog error fields,
+// it reports whether key was an error tag.
+func (s *Span) setErrorTag(key string, value interface{}) bool {
+	switch key {
+	case string(ext.Error):
+		switch v := value.(type) {
+		case bool:
+			if v {
+				s.Error = 1
+			} else {
+				s.Error = 0
+			}
+		case error:
+			s.SetError(v)
+		default:
+			s.Error = 1
+			s.setMeta(errorMsgKey, fmt.Sprint(v))
+		}
+	case errorObjectKey:
+		if err, ok := value.(error); ok {
+			s.SetError(err)
+		} else {
+			s.Error = 1
+			s.setMeta(errorMsgKey, tagString(value))
+		}
+	case errorKindKey:
+		s.setMeta(errorTypeKey, tagString(value))
+	default:
+		return false
+	}
+
+	return true
+}
+
+// setErrorLog maps an "error" event log record onto the DataDog error fields,
+// it reports whether fields was an error record.
+func (s *Span) setErrorLog(fields []log.Field) bool {
+	var isError bool
+	for _, field := range fields {
+		if field.Key() == "event" && fmt.Sprint(field.Value()) == errorEvent {
+			isError = true
+			break
+		}
+	}
+	if !isError {
+		return false
+	}
+
+	s.Error = 1
+	for _, field := range fields {
+		switch field.Key() {
+		case "event":
+		case errorMessageKey:
+			s.setMeta(errorMsgKey, fmt.Sprint(field.Value()))
+		case errorLogStack:
+			s.setMeta(errorStackKey, fmt.Sprint(field.Value()))
+		default:
+			if !s.setErrorTag(field.Key(), field.Value()) {
+				s.setTag(field.Key(), field.Value())
+			}
+		}
+	}
+
+	return true
+}
+
+// FinishWithRecover finishes span, tagging it with the message and the stack
+// trace of the panic being recovered, if any, before panicking again. It must
+// be deferred, i.e. defer FinishWithRecover(span), so the crashed handlers
+// still produce complete error spans.
+func FinishWithRecover(span opentracing.Span) {
+	r := recover()
+	if r == nil {
+		span.Finish()
+		return
+	}
+
+	span.LogFields(
+		log.String("event", errorEvent),
+		log.String(errorMessageKey, fmt.Sprint(r)),
+		log.String(errorKindKey, fmt.Sprintf("panic: %T", r)),
+		log.String(errorLogStack, string(debug.Stack())),
+	)
+	span.Finish()
+	panic(r)
+}
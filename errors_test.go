@@ -0,0 +1,95 @@
+package ddtracer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpanErrorConventions(t *testing.T) {
+	tr := NewTracer()
+
+	t.Run("error tag", func(t *testing.T) {
+		span := tr.StartSpan("test").(*Span)
+		ext.Error.Set(span, true)
+		assert.Equal(t, int32(1), span.Error)
+		assert.Empty(t, span.GetMeta("error"))
+
+		ext.Error.Set(span, false)
+		assert.Equal(t, int32(0), span.Error)
+	})
+
+	t.Run("error.object and error.kind tags", func(t *testing.T) {
+		span := tr.StartSpan("test").(*Span)
+		span.SetTag("error.object", errors.New("boom"))
+		span.SetTag("error.kind", "Exception")
+
+		assert.Equal(t, int32(1), span.Error)
+		assert.Equal(t, "boom", span.GetMeta("error.msg"))
+		assert.Equal(t, "Exception", span.GetMeta("error.type"))
+		assert.NotEmpty(t, span.GetMeta("error.stack"))
+	})
+
+	t.Run("error log field", func(t *testing.T) {
+		span := tr.StartSpan("test").(*Span)
+		span.LogFields(log.Error(errors.New("boom")))
+
+		assert.Equal(t, int32(1), span.Error)
+		assert.Equal(t, "boom", span.GetMeta("error.msg"))
+		assert.Equal(t, "*errors.errorString", span.GetMeta("error.type"))
+	})
+
+	t.Run("error log event", func(t *testing.T) {
+		span := tr.StartSpan("test").(*Span)
+		span.LogKV(
+			"event", "error",
+			"message", "something failed",
+			"error.kind", "Timeout",
+			"stack", "main.go:1",
+		)
+
+		assert.Equal(t, int32(1), span.Error)
+		assert.Equal(t, "something failed", span.GetMeta("error.msg"))
+		assert.Equal(t, "Timeout", span.GetMeta("error.type"))
+		assert.Equal(t, "main.go:1", span.GetMeta("error.stack"))
+	})
+
+	t.Run("non error value", func(t *testing.T) {
+		span := tr.StartSpan("test").(*Span)
+		assert.NotPanics(t, func() {
+			span.LogKV("error", "not an error")
+		})
+		assert.Equal(t, int32(1), span.Error)
+		assert.Equal(t, "not an error", span.GetMeta("error.msg"))
+	})
+}
+
+func TestFinishWithRecover(t *testing.T) {
+	tr := NewTracer()
+
+	span := tr.StartSpan("handler").(*Span)
+	func() {
+		defer func() {
+			assert.Equal(t, "boom", recover())
+		}()
+		defer FinishWithRecover(span)
+		panic("boom")
+	}()
+	assert.NotZero(t, span.Duration)
+	assert.Equal(t, int32(1), span.Error)
+	assert.Equal(t, "boom", span.GetMeta("error.msg"))
+	assert.Equal(t, "panic: string", span.GetMeta("error.type"))
+	assert.Contains(t, span.GetMeta("error.stack"), "TestFinishWithRecover")
+
+	t.Run("No panic", func(t *testing.T) {
+		span := tr.StartSpan("handler").(*Span)
+		func() {
+			defer FinishWithRecover(span)
+		}()
+		assert.NotZero(t, span.Duration)
+		assert.Equal(t, int32(0), span.Error)
+	})
+}
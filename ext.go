@@ -0,0 +1,119 @@
+package ddtracer
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+// The helpers of the opentracing ext package, tuned to the DataDog fields,
+// so that the code written against ext only has to switch package:
+//
+//	span := tr.StartSpan("http.request", ddtracer.RPCServerOption(parent))
+//	ddtracer.HTTPMethod.Set(span, req.Method)
+//	ddtracer.HTTPUrl.Set(span, req.URL.String())
+var (
+	// HTTPMethod is ext.HTTPMethod, upper casing the method. Once the URL is
+	// set as well, the resource of the span is set to the method followed by
+	// its normalized path, i.e. "GET /users/?", unless it has been set.
+	HTTPMethod = httpTagName(ext.HTTPMethod)
+
+	// HTTPUrl is ext.HTTPUrl, stripping the credentials, the query string and
+	// the fragment of the URL, which are likely to hold secrets. See
+	// HTTPMethod for the resource.
+	HTTPUrl = httpTagName(ext.HTTPUrl)
+)
+
+// RPCServerOption is ext.RPCServerOption, starting a server span, of type
+// SpanTypeWeb, child of the context extracted from the client when there's
+// one. Unlike ext's, it accepts the nil *SpanContext of a failed extraction.
+func RPCServerOption(client opentracing.SpanContext) opentracing.StartSpanOption {
+	return rpcServerOption{client}
+}
+
+type rpcServerOption struct {
+	client opentracing.SpanContext
+}
+
+func (r rpcServerOption) Apply(o *opentracing.StartSpanOptions) {
+	if r.client != nil && r.client != (*SpanContext)(nil) {
+		opentracing.ChildOf(r.client).Apply(o)
+	}
+	ext.SpanKindRPCServer.Apply(o)
+	SpanType(SpanTypeWeb).Apply(o)
+}
+
+type httpTagName string
+
+func (tag httpTagName) Set(span opentracing.Span, value string) {
+	switch string(tag) {
+	case string(ext.HTTPMethod):
+		value = strings.ToUpper(value)
+	case string(ext.HTTPUrl):
+		value = SanitizeURL(value)
+	}
+	span.SetTag(string(tag), value)
+
+	if s, ok := span.(*Span); ok {
+		s.setHTTPResource()
+	}
+}
+
+// SanitizeURL strips the credentials, the query string and the fragment of
+// rawurl, which may hold secrets, to tag the spans with it. The unparsable
+// URLs are only stripped of their query string and fragment.
+func SanitizeURL(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		if i := strings.IndexAny(rawurl, "?#"); i >= 0 {
+			return rawurl[:i]
+		}
+		return rawurl
+	}
+	u.User, u.RawQuery, u.ForceQuery, u.Fragment = nil, "", false, ""
+	return u.String()
+}
+
+// setHTTPResource sets the resource of the span to its HTTP method followed
+// by the normalized path of its URL, when both are set and the resource is
+// still the default one.
+func (s *Span) setHTTPResource() {
+	if !s.lock() {
+		return
+	}
+	defer s.mu.Unlock()
+
+	method, rawurl := s.Meta["http.method"], s.Meta["http.url"]
+	if method == "" || rawurl == "" || (s.tr != nil && s.Resource != s.tr.resourceName(s.Name)) {
+		return
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	s.Resource = method + " " + NormalizePath(path)
+}
+
+// idSegment matches the path segments that are likely IDs: numbers, UUIDs
+// and long hexadecimal strings.
+var idSegment = regexp.MustCompile(`^(?:\d+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,})$`)
+
+// NormalizePath replaces the segments of path that are likely IDs by "?",
+// so the resources of the requests to the same route are grouped,
+// i.e. "/users/42/posts" becomes "/users/?/posts".
+func NormalizePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if idSegment.MatchString(s) {
+			segments[i] = "?"
+		}
+	}
+	return strings.Join(segments, "/")
+}
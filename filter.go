@@ -0,0 +1,83 @@
+package ddtracer
+
+import (
+	"net/http"
+	"regexp"
+	"sync/atomic"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+)
+
+// FilterFunc reports whether span has to be dropped, i.e. the health checks
+// or the traces of a noisy endpoint, so they don't consume the trace quota.
+// See WithSpanFilter and WithTraceFilter.
+type FilterFunc func(span *tracer.Span) bool
+
+// MatchResource returns a FilterFunc dropping the spans whose resource
+// matches re.
+func MatchResource(re *regexp.Regexp) FilterFunc {
+	return func(span *tracer.Span) bool {
+		return re.MatchString(span.Resource)
+	}
+}
+
+// filterSpan reports whether any of the Tracer's span filters drops span.
+func (t *Tracer) filterSpan(span *tracer.Span) bool {
+	for _, f := range t.spanFilters {
+		if f(span) {
+			return true
+		}
+	}
+	return false
+}
+
+// localRootKey marks the local root spans, the roots of the traces and the
+// children of remote spans, for the filterTransport which removes it.
+const localRootKey = "_dd.filter.local_root"
+
+// filterTransport drops the traces whose local root span matches any of its
+// filters before sending them.
+type filterTransport struct {
+	tracer.Transport
+	filters []FilterFunc
+	stats   *stats
+}
+
+func (t *filterTransport) SendTraces(traces [][]*tracer.Span) (*http.Response, error) {
+	kept := traces[:0]
+	for _, trace := range traces {
+		if t.drop(trace) {
+			atomic.AddUint64(&t.stats.spansDropped, uint64(len(trace)))
+			continue
+		}
+		kept = append(kept, trace)
+	}
+	if len(kept) == 0 {
+		return nil, nil
+	}
+	return t.Transport.SendTraces(kept)
+}
+
+// drop reports whether the local root span of trace, the root of the trace
+// or the entry span of the service continuing a remote trace, matches any
+// filter. The traces flushed without their local root, which finishes
+// later, are kept.
+func (t *filterTransport) drop(trace []*tracer.Span) bool {
+	drop := false
+	for _, span := range trace {
+		_, marked := span.Metrics[localRootKey]
+		if marked {
+			delete(span.Metrics, localRootKey)
+		}
+		if drop || (span.ParentID != 0 && !marked) {
+			continue
+		}
+		for _, f := range t.filters {
+			if f(span) {
+				drop = true
+				break
+			}
+		}
+	}
+	return drop
+}
@@ -0,0 +1,88 @@
+package ddtracer
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpanFilter(t *testing.T) {
+	rec := &recordingTransport{}
+	tr := NewTracerWithOptions(WithTransport(rec), WithSpanFilter(func(span *tracer.Span) bool {
+		return span.Name == "cache.get"
+	})).(*Tracer)
+
+	root := tr.StartSpan("http.request")
+	child := tr.StartSpan("cache.get", opentracing.ChildOf(root.Context()))
+	child.Finish()
+	root.Finish()
+	require.NoError(t, tr.FlushTraces())
+
+	traces := rec.traces()
+	require.Len(t, traces, 1)
+	require.Len(t, traces[0], 1)
+	assert.Equal(t, "http.request", traces[0][0].Name)
+	assert.EqualValues(t, 1, tr.Stats().SpansDropped)
+}
+
+func TestTraceFilter(t *testing.T) {
+	rec := &recordingTransport{}
+	tr := NewTracerWithOptions(WithTransport(rec), WithTraceFilter(MatchResource(regexp.MustCompile("^GET /health")))).(*Tracer)
+
+	for _, resource := range []string{"GET /health", "GET /users"} {
+		root := tr.StartSpan("http.request", ResourceName(resource))
+		tr.StartSpan("db.query", opentracing.ChildOf(root.Context())).Finish()
+		root.Finish()
+	}
+	require.NoError(t, tr.FlushTraces())
+
+	traces := rec.traces()
+	require.Len(t, traces, 1)
+	require.Len(t, traces[0], 2)
+	for _, span := range traces[0] {
+		if span.ParentID == 0 {
+			assert.Equal(t, "GET /users", span.Resource)
+		}
+	}
+	assert.EqualValues(t, 2, tr.Stats().SpansDropped)
+}
+
+func TestTraceFilterLocalRoot(t *testing.T) {
+	rec := &recordingTransport{}
+	tr := NewTracerWithOptions(WithTransport(rec), WithTraceFilter(MatchResource(regexp.MustCompile("^GET /health")))).(*Tracer)
+
+	for i, resource := range []string{"GET /health", "GET /users"} {
+		remote := NewRemoteSpanContext(0, uint64(42+i), 7, true)
+		server := tr.StartSpan("http.request", opentracing.ChildOf(remote), ResourceName(resource))
+		tr.StartSpan("db.query", opentracing.ChildOf(server.Context())).Finish()
+		server.Finish()
+	}
+	require.NoError(t, tr.FlushTraces())
+
+	traces := rec.traces()
+	require.Len(t, traces, 1)
+	require.Len(t, traces[0], 2)
+	for _, span := range traces[0] {
+		assert.NotContains(t, span.Metrics, localRootKey)
+		if span.ParentID == 7 {
+			assert.Equal(t, "GET /users", span.Resource)
+		}
+	}
+	assert.EqualValues(t, 2, tr.Stats().SpansDropped)
+}
+
+func TestFilterTransportKeepsTracesWithoutRoot(t *testing.T) {
+	rec, st := &recordingTransport{}, &stats{}
+	ft := &filterTransport{Transport: rec, stats: st, filters: []FilterFunc{func(*tracer.Span) bool { return true }}}
+
+	child := &tracer.Span{Name: "child", ParentID: 1}
+	_, err := ft.SendTraces([][]*tracer.Span{{child}, {&tracer.Span{Name: "root"}}})
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]*tracer.Span{{child}}, rec.traces())
+	assert.EqualValues(t, 1, st.spansDropped)
+}
@@ -0,0 +1,115 @@
+package ddtracer
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// CloseTimeout is the time given to Close to flush the remaining traces.
+var CloseTimeout = 5 * time.Second
+
+// Flush sends the buffered traces to the agent, it gives up when ctx is done
+// returning ctx.Err().
+func (t *Tracer) Flush(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- t.FlushTraces()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// FlushTraces sends the buffered traces to the agent, including the queued
+// ones when configured with WithMaxQueueSize or the like, and the ones to
+// retry when configured with WithRetry, whatever their backoff. It returns
+// the first error, every step being done regardless.
+func (t *Tracer) FlushTraces() error {
+	err := t.Tracer.FlushTraces()
+	if t.queue != nil {
+		if e := t.queue.flush(); err == nil {
+			err = e
+		}
+	}
+	for _, r := range t.retries() {
+		if e := r.send(true); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// retries returns the retry transports of the Tracer, the ones of the
+// destinations of its router included.
+func (t *Tracer) retries() []*retryTransport {
+	var retries []*retryTransport
+	if t.retry != nil {
+		retries = append(retries, t.retry)
+	}
+	if t.router != nil {
+		retries = append(retries, t.router.retries...)
+	}
+	return retries
+}
+
+// partialFlush flushes the finished spans in the background once there are
+// partialFlushSpans of them, unless a partial flush is already running.
+func (t *Tracer) partialFlush() {
+	if t.partialFlushSpans == 0 || atomic.AddUint64(&t.finishedSpans, 1) < t.partialFlushSpans {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&t.partialFlushing, 0, 1) {
+		return
+	}
+
+	atomic.StoreUint64(&t.finishedSpans, 0)
+	go func() {
+		defer atomic.StoreInt32(&t.partialFlushing, 0)
+		t.FlushTraces()
+	}()
+}
+
+// Close flushes the buffered traces, waiting up to CloseTimeout, and stops
+// the Tracer. It's safe to call it more than once. The Tracer created by
+// Default is only flushed, see CloseDefault.
+func (t *Tracer) Close() error {
+	if t.shared {
+		ctx, cancel := context.WithTimeout(context.Background(), CloseTimeout)
+		defer cancel()
+		return t.Flush(ctx)
+	}
+	return t.close()
+}
+
+// close implements Close.
+func (t *Tracer) close() error {
+	t.closeOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), CloseTimeout)
+		defer cancel()
+
+		t.closeErr = t.Flush(ctx)
+		t.Stop()
+		if t.queue != nil {
+			t.queue.close()
+		}
+		for _, r := range t.retries() {
+			r.close()
+		}
+		if t.health != nil {
+			t.health.stop()
+		}
+		if t.abandoned != nil {
+			t.abandoned.stop()
+		}
+		t.statsd.Close()
+		if t.shared {
+			atomic.StoreInt32(&defaultRunning, 0)
+		}
+	})
+	return t.closeErr
+}
@@ -0,0 +1,64 @@
+package ddtracer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracerClose(t *testing.T) {
+	env := newEnv(t)
+	defer env.close()
+
+	env.tr.StartSpan("test").Finish()
+	require.NoError(t, env.tr.(ClosableTracer).Close())
+	assert.Len(t, env.reqs, 1)
+
+	t.Run("Idempotent", func(t *testing.T) {
+		assert.NoError(t, env.tr.(ClosableTracer).Close())
+	})
+}
+
+func TestTracerFlushDeadline(t *testing.T) {
+	unblock := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-unblock
+	}))
+	defer ts.Close()
+	defer close(unblock)
+
+	u, _ := url.Parse(ts.URL)
+	tr := NewTracerWithOptions(WithAgentAddr(u.Host))
+	tr.StartSpan("test").Finish()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, tr.Flush(ctx))
+}
+
+func TestPartialFlush(t *testing.T) {
+	rec := &recordingTransport{}
+	tr := NewTracerWithOptions(WithTransport(rec), WithPartialFlush(2))
+	defer tr.Close()
+
+	root := tr.StartSpan("root")
+	tr.StartSpan("child", opentracing.ChildOf(root.Context())).Finish()
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, rec.traces())
+
+	tr.StartSpan("child", opentracing.ChildOf(root.Context())).Finish()
+	for i := 0; i < 100 && len(rec.traces()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	traces := rec.traces()
+	require.Len(t, traces, 1)
+	assert.Len(t, traces[0], 2)
+	assert.Equal(t, "child", traces[0][0].Name)
+}
@@ -0,0 +1,71 @@
+// Package gintrace traces the requests served by a gin router with a
+// ddtracer.Tracer, using the matched route as the resource of the spans.
+package gintrace
+
+import (
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	"github.com/gchaincl/dd-go-opentracing/nethttp"
+	"github.com/gin-gonic/gin"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+const defaultOperation = "http.request"
+
+// Option configures the Middleware.
+type Option func(*options)
+
+type options struct {
+	operation string
+}
+
+// OperationName sets the operation name of the server spans, "http.request" by default.
+func OperationName(name string) Option {
+	return func(o *options) {
+		o.operation = name
+	}
+}
+
+// Middleware traces every request with a server span child of the context
+// propagated by the client, if any. Its resource is the request method
+// followed by the route, i.e. "GET /users/:id", or by the normalized path
+// when no route matched. The span is reachable from the request context
+// through ddtracer.SpanFromContext, and the last error of the gin context is
+// logged on it.
+func Middleware(tr opentracing.Tracer, opts ...Option) gin.HandlerFunc {
+	o := &options{operation: defaultOperation}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(c *gin.Context) {
+		r := c.Request
+		sso := []opentracing.StartSpanOption{
+			ext.SpanKindRPCServer,
+			ddtracer.SpanType(ddtracer.SpanTypeWeb),
+		}
+		if sc, err := tr.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header)); err == nil {
+			sso = append(sso, opentracing.ChildOf(sc))
+		}
+
+		span := tr.StartSpan(o.operation, sso...)
+		defer span.Finish()
+
+		route := c.FullPath()
+		if route == "" {
+			route = nethttp.NormalizePath(r.URL.Path)
+		}
+		ext.Component.Set(span, r.Method+" "+route)
+		ext.HTTPMethod.Set(span, r.Method)
+		ext.HTTPUrl.Set(span, r.URL.Path)
+
+		c.Request = r.WithContext(ddtracer.ContextWithSpan(r.Context(), span))
+		c.Next()
+
+		ext.HTTPStatusCode.Set(span, uint16(c.Writer.Status()))
+		if err := c.Errors.Last(); err != nil {
+			span.LogFields(log.Error(err.Err))
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package gintrace
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	"github.com/gchaincl/dd-go-opentracing/mocktracer"
+	"github.com/gin-gonic/gin"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	tr := mocktracer.New()
+
+	router := gin.New()
+	router.Use(Middleware(tr))
+	var active opentracing.Span
+	router.GET("/users/:id", func(c *gin.Context) {
+		active = ddtracer.SpanFromContext(c.Request.Context())
+		c.Status(http.StatusTeapot)
+	})
+
+	parent := tr.StartSpan("parent").(*ddtracer.Span)
+	req := httptest.NewRequest("GET", "/users/42", nil)
+	require.NoError(t, tr.Inject(parent.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header)))
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := tr.FinishedSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	require.NotNil(t, active)
+	assert.Equal(t, active.(*ddtracer.Span).SpanID, span.SpanID)
+	assert.Equal(t, "http.request", span.Name)
+	assert.Equal(t, parent.TraceID, span.TraceID)
+	assert.Equal(t, parent.SpanID, span.ParentID)
+	assert.Equal(t, "GET /users/:id", span.Resource)
+	assert.Equal(t, "web", span.Type)
+	assert.Equal(t, "/users/42", span.Tags["http.url"])
+	assert.Equal(t, "418", span.Tags["http.status_code"])
+}
+
+func TestMiddlewareNotFound(t *testing.T) {
+	tr := mocktracer.New()
+
+	router := gin.New()
+	router.Use(Middleware(tr, OperationName("gin.request")))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+
+	spans := tr.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "gin.request", spans[0].Name)
+	assert.Equal(t, "GET /users/?", spans[0].Resource)
+	assert.Equal(t, "404", spans[0].Tags["http.status_code"])
+}
+
+func TestMiddlewareError(t *testing.T) {
+	tr := mocktracer.New()
+
+	router := gin.New()
+	router.Use(Middleware(tr))
+	router.GET("/", func(c *gin.Context) {
+		c.Error(errors.New("boom"))
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	spans := tr.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.True(t, spans[0].Error)
+	assert.Equal(t, "boom", spans[0].Tags["error.msg"])
+}
@@ -0,0 +1,155 @@
+package ddtracer
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// Init creates a Tracer configured by opts and registers it as the
+// opentracing global tracer. The returned Closer flushes the remaining
+// traces and stops the Tracer, it should be called before exiting.
+func Init(opts ...Option) (opentracing.Tracer, io.Closer) {
+	t := NewTracerWithOptions(opts...)
+	opentracing.SetGlobalTracer(t)
+	return t, t
+}
+
+var (
+	defaultOnce sync.Once
+	// defaultMu guards defaultTracer for CloseDefault, which mustn't create it.
+	defaultMu     sync.Mutex
+	defaultTracer *Tracer
+
+	// defaultRunning is set while the Tracer created by Default runs,
+	// duplicateTracers warning once about the Tracers duplicating it.
+	defaultRunning   int32
+	duplicateTracers sync.Once
+)
+
+// Default returns the Tracer shared by the packages of the program, so that
+// the libraries don't each create a Tracer running its own flush
+// goroutines. It's created by the first call, safe for concurrent use, as
+// the opentracing global tracer when it's a Tracer, i.e. set by Init, or
+// otherwise as configured by the DD_* environment variables. Unlike
+// NewTracer, it's disabled rather than a NoopTracer when DD_TRACE_ENABLED is
+// false.
+//
+// The Tracer it creates is owned by the program rather than by the
+// libraries using it: its Close only flushes it, CloseDefault stops it. While
+// it runs, the creation of another Tracer flushing to the agent on its own is
+// logged, once per process, as it duplicates its flush goroutines. As
+// Go programs don't fork without exec, the subprocesses never inherit it nor
+// its goroutines, see InjectCmd to continue the trace in them.
+func Default() *Tracer {
+	defaultOnce.Do(func() {
+		t, ok := opentracing.GlobalTracer().(*Tracer)
+		if !ok {
+			t = NewTracerWithOptions(WithTracingEnabled(true)).(*Tracer)
+			t.SetEnabled(tracingEnabled())
+			t.shared = true
+			atomic.StoreInt32(&defaultRunning, 1)
+		}
+		defaultMu.Lock()
+		defaultTracer = t
+		defaultMu.Unlock()
+	})
+	return defaultTracer
+}
+
+// CloseDefault flushes and stops the Tracer created by Default, if any. It's
+// meant to be called by the main function before exiting, the spans finished
+// afterwards being only sent by explicit flushes.
+func CloseDefault() error {
+	defaultMu.Lock()
+	t := defaultTracer
+	defaultMu.Unlock()
+	if t == nil || !t.shared {
+		return nil
+	}
+	return t.close()
+}
+
+// checkDuplicate logs, once per process, the creation of a Tracer flushing to
+// the agent on its own while the one of Default runs, i.e. by a library not
+// sharing it. The Tracers with their own transport, i.e. the mocktracer ones,
+// are not checked.
+func checkDuplicate(logger Logger) {
+	if atomic.LoadInt32(&defaultRunning) == 0 {
+		return
+	}
+	duplicateTracers.Do(func() {
+		logger.Printf("tracer created while ddtracer.Default() runs, each flushing on its own: the libraries should share ddtracer.Default()")
+	})
+}
+
+// StartSpanFromContext starts a span using the opentracing global tracer,
+// child of the span found in ctx, if any. It returns the span and a copy of
+// ctx holding it.
+func StartSpanFromContext(ctx context.Context, op string, opts ...opentracing.StartSpanOption) (opentracing.Span, context.Context) {
+	opts = append([]opentracing.StartSpanOption{ChildOfContext(ctx)}, opts...)
+	span := opentracing.GlobalTracer().StartSpan(op, opts...)
+	return span, ContextWithSpan(ctx, span)
+}
+
+// SpanFromContext returns the span stored in ctx by either opentracing or
+// DataDog's tracer, or nil if there's none.
+//
+// When ctx holds different spans for each of them, which happens when mixing
+// instrumentations that don't use ContextWithSpan, the most recently started
+// one is returned, as it's the descendant of the other. The DataDog's spans
+// are returned as the Span wrapping them when stored by ContextWithSpan,
+// otherwise as a Span of the Tracer which started them, if known.
+func SpanFromContext(ctx context.Context) opentracing.Span {
+	span := opentracing.SpanFromContext(ctx)
+	ddspan, ok := tracer.SpanFromContext(ctx)
+	if !ok {
+		return span
+	}
+	if span == nil {
+		return wrapSpan(ctx, ddspan, nil)
+	}
+	if s, ok := span.(*Span); ok && s.Span != nil && s.Span != ddspan && ddspan.Start > s.Start {
+		return wrapSpan(ctx, ddspan, s.tr)
+	}
+	return span
+}
+
+// spanKey is the context key of the Span stored by ContextWithSpan.
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of ctx holding span, reachable by both
+// opentracing and DataDog's tracer context helpers.
+func ContextWithSpan(ctx context.Context, span opentracing.Span) context.Context {
+	if s, ok := span.(*Span); ok && s.Span != nil {
+		ctx = s.Span.Context(ctx)
+		ctx = context.WithValue(ctx, spanKey{}, s)
+	}
+	return opentracing.ContextWithSpan(ctx, span)
+}
+
+// wrapSpan returns the Span wrapping ddspan stored in ctx by ContextWithSpan
+// or, for the spans started by DataDog's tracer directly, a new one bound to
+// the Tracer owning it among tr, the global tracer and Default's, if any.
+func wrapSpan(ctx context.Context, ddspan *tracer.Span, tr *Tracer) *Span {
+	if s, ok := ctx.Value(spanKey{}).(*Span); ok && s.Span == ddspan {
+		return s
+	}
+	s := &Span{Span: ddspan, start: time.Unix(0, ddspan.Start)}
+	global, _ := opentracing.GlobalTracer().(*Tracer)
+	defaultMu.Lock()
+	owners := []*Tracer{tr, global, defaultTracer}
+	defaultMu.Unlock()
+	for _, t := range owners {
+		if t != nil && t.Tracer == ddspan.Tracer() {
+			s.tr = t
+			break
+		}
+	}
+	return s
+}
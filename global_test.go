@@ -0,0 +1,226 @@
+package ddtracer
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInit(t *testing.T) {
+	prev := opentracing.GlobalTracer()
+	defer opentracing.SetGlobalTracer(prev)
+
+	env := newEnv(t)
+	defer env.close()
+	u, _ := url.Parse(env.ts.URL)
+
+	tr, closer := Init(WithServiceName("init"), WithAgentAddr(u.Host))
+	assert.Equal(t, tr, opentracing.GlobalTracer())
+
+	span, ctx := StartSpanFromContext(context.Background(), "parent")
+	assert.Equal(t, "init", span.(*Span).Service)
+	assert.Equal(t, span, SpanFromContext(ctx))
+
+	ddspan, ok := tracer.SpanFromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, span.(*Span).Span, ddspan)
+
+	child, _ := StartSpanFromContext(ctx, "child")
+	assert.Equal(t, span.(*Span).SpanID, child.(*Span).ParentID)
+	assert.Equal(t, span.(*Span).TraceID, child.(*Span).TraceID)
+
+	child.Finish()
+	span.Finish()
+	assert.NoError(t, closer.Close())
+	assert.Len(t, env.reqs, 1)
+}
+
+func TestSpanFromContext(t *testing.T) {
+	assert.Nil(t, SpanFromContext(context.Background()))
+
+	span := NewTracer().StartSpan("test").(*Span)
+	ctx := span.Span.Context(context.Background())
+	assert.Equal(t, span.Span, SpanFromContext(ctx).(*Span).Span)
+}
+
+func TestSpanFromContextMixed(t *testing.T) {
+	tr := NewTracer()
+
+	t.Run("DataDog child of opentracing", func(t *testing.T) {
+		parent := tr.StartSpan("parent").(*Span)
+		ctx := opentracing.ContextWithSpan(context.Background(), parent)
+
+		ddchild := tr.(*Tracer).NewChildSpan("ddchild", parent.Span)
+		ddchild.Start = parent.Start + 1
+		ctx = ddchild.Context(ctx)
+		assert.Equal(t, ddchild, SpanFromContext(ctx).(*Span).Span)
+
+		child := tr.StartSpan("child", ChildOfContext(ctx)).(*Span)
+		assert.Equal(t, ddchild.SpanID, child.ParentID)
+		assert.Equal(t, parent.TraceID, child.TraceID)
+	})
+
+	t.Run("opentracing child of DataDog", func(t *testing.T) {
+		parent := tr.StartSpan("parent").(*Span)
+		ctx := parent.Span.Context(context.Background())
+
+		otchild := tr.StartSpan("otchild", ChildOfContext(ctx)).(*Span)
+		otchild.Start = parent.Start + 1
+		ctx = opentracing.ContextWithSpan(ctx, otchild)
+		assert.Equal(t, otchild, SpanFromContext(ctx))
+
+		child := tr.StartSpan("child", ChildOfContext(ctx)).(*Span)
+		assert.Equal(t, otchild.SpanID, child.ParentID)
+		assert.Equal(t, parent.TraceID, child.TraceID)
+	})
+
+	t.Run("Both keys", func(t *testing.T) {
+		span := tr.StartSpan("span")
+		ctx := ContextWithSpan(context.Background(), span)
+		assert.Equal(t, span, SpanFromContext(ctx))
+	})
+}
+
+func TestSpanFromContextBridged(t *testing.T) {
+	prev := opentracing.GlobalTracer()
+	defer opentracing.SetGlobalTracer(prev)
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}), WithLogger(NopLogger)).(*Tracer)
+	opentracing.SetGlobalTracer(tr)
+
+	t.Run("Started by ContextWithSpan", func(t *testing.T) {
+		span := tr.StartSpan("span").(*Span)
+		ctx := ContextWithSpan(context.Background(), span)
+		ctx = opentracing.ContextWithSpan(ctx, nil)
+		assert.True(t, span == SpanFromContext(ctx))
+	})
+
+	t.Run("Started by DataDog", func(t *testing.T) {
+		ddspan := tr.NewRootSpan("ddspan", "service", "resource")
+		span, ok := SpanFromContext(ddspan.Context(context.Background())).(*Span)
+		require.True(t, ok)
+		assert.Equal(t, tr, span.tr)
+
+		finished := tr.Stats().SpansFinished
+		assert.NotPanics(t, func() {
+			span.SetTag("key", "val")
+			span.LogKV("odd")
+			span.Finish()
+			span.Finish()
+		})
+		assert.Equal(t, "val", ddspan.GetMeta("key"))
+		assert.Equal(t, finished+1, tr.Stats().SpansFinished)
+	})
+}
+
+// resetDefault forgets the Tracer created by Default, restoring the global
+// tracer when done.
+func resetDefault() func() {
+	prev := opentracing.GlobalTracer()
+	defaultOnce, defaultTracer = sync.Once{}, nil
+	return func() {
+		CloseDefault()
+		defaultOnce, defaultTracer = sync.Once{}, nil
+		opentracing.SetGlobalTracer(prev)
+	}
+}
+
+func TestDefault(t *testing.T) {
+	defer resetDefault()()
+	opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer ts.Close()
+	host, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	defer setenv(map[string]string{agentHostEnv: host, agentPortEnv: port})()
+
+	tracers := make(chan *Tracer, 10)
+	for i := 0; i < cap(tracers); i++ {
+		go func() { tracers <- Default() }()
+	}
+	d := Default()
+	require.NotNil(t, d)
+	for i := 0; i < cap(tracers); i++ {
+		assert.True(t, d == <-tracers)
+	}
+	assert.True(t, d.Enabled())
+
+	// The libraries closing it only flush it.
+	d.StartSpan("a").Finish()
+	require.NoError(t, d.Close())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	d.StartSpan("b").Finish()
+	require.NoError(t, d.Close())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	require.NoError(t, CloseDefault())
+	assert.True(t, d == Default())
+}
+
+func TestDefaultGlobal(t *testing.T) {
+	defer resetDefault()()
+
+	tr := NewTracerWithOptions(WithTransport(&recordingTransport{})).(*Tracer)
+	opentracing.SetGlobalTracer(tr)
+	assert.True(t, tr == Default())
+
+	// It's owned by the program which set it.
+	require.NoError(t, CloseDefault())
+	assert.False(t, tr.shared)
+}
+
+func TestDefaultDisabled(t *testing.T) {
+	defer resetDefault()()
+	opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+	defer setenv(map[string]string{enabledEnv: "false"})()
+
+	d := Default()
+	require.NotNil(t, d)
+	assert.False(t, d.Enabled())
+}
+
+func TestDuplicateTracerWarning(t *testing.T) {
+	defer resetDefault()()
+	opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+	duplicateTracers = sync.Once{}
+
+	ts := httptest.NewServer(nil)
+	defer ts.Close()
+	host, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	defer setenv(map[string]string{agentHostEnv: host, agentPortEnv: port})()
+	logger := &recordingLogger{}
+
+	// Without Default, the Tracers are owned by their creator.
+	NewTracerWithOptions(WithLogger(logger)).Close()
+	a := NewTracerWithOptions(WithLogger(logger))
+	defer a.Close()
+	Default()
+	assert.Empty(t, logger.lines)
+
+	// The Tracers with a transport of their own don't flush to the agent.
+	NewTracerWithOptions(WithLogger(logger), WithTransport(&recordingTransport{})).Close()
+	assert.Empty(t, logger.lines)
+
+	for i := 0; i < 2; i++ {
+		NewTracerWithOptions(WithLogger(logger)).Close()
+	}
+	require.Len(t, logger.lines, 1)
+	assert.Contains(t, logger.lines[0], "created while ddtracer.Default() runs")
+
+	duplicateTracers = sync.Once{}
+	require.NoError(t, CloseDefault())
+	NewTracerWithOptions(WithLogger(logger)).Close()
+	assert.Len(t, logger.lines, 1)
+}
@@ -0,0 +1,133 @@
+// Package graphqltrace traces the queries executed by graph-gophers/graphql-go
+// with spans child of the span in the context, i.e. the one of the HTTP
+// handler serving them.
+//
+//	schema := graphql.MustParseSchema(s, resolver, graphql.Tracer(graphqltrace.NewTracer(tr)))
+package graphqltrace
+
+import (
+	"context"
+	"strings"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	"github.com/graph-gophers/graphql-go/errors"
+	"github.com/graph-gophers/graphql-go/introspection"
+	"github.com/graph-gophers/graphql-go/trace"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+const (
+	defaultService = "graphql"
+
+	queryOperation = "graphql.query"
+	fieldOperation = "graphql.field"
+
+	spanType = "graphql"
+
+	// TagQuery is the query document.
+	TagQuery = "graphql.query"
+	// TagOperationName is the name of the operation executed.
+	TagOperationName = "graphql.operation.name"
+	// TagType is the type whose field is resolved.
+	TagType = "graphql.type"
+	// TagField is the field resolved.
+	TagField = "graphql.field"
+	// TagErrors is the number of errors of the query.
+	TagErrors = "graphql.errors"
+)
+
+// Option configures the Tracer.
+type Option func(*tracer)
+
+// ServiceName sets the service of the spans, "graphql" by default.
+func ServiceName(name string) Option {
+	return func(t *tracer) {
+		t.service = name
+	}
+}
+
+// WithFieldSpans traces the resolution of every non-trivial field with a
+// span, child of the query one. It's off by default as large queries
+// resolve many fields.
+func WithFieldSpans() Option {
+	return func(t *tracer) {
+		t.fields = true
+	}
+}
+
+type tracer struct {
+	tr      opentracing.Tracer
+	service string
+	fields  bool
+}
+
+// NewTracer returns a graphql-go Tracer tracing every query with a span whose
+// resource is the operation name, or the query itself for the anonymous
+// operations. The errors of the query are logged on the span.
+func NewTracer(tr opentracing.Tracer, opts ...Option) trace.Tracer {
+	t := &tracer{tr: tr, service: defaultService}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+func (t *tracer) TraceQuery(ctx context.Context, queryString string, operationName string, variables map[string]interface{}, varTypes map[string]*introspection.Type) (context.Context, trace.TraceQueryFinishFunc) {
+	resource := operationName
+	if resource == "" {
+		resource = queryString
+	}
+	span := t.tr.StartSpan(queryOperation,
+		ddtracer.ChildOfContext(ctx),
+		ddtracer.ServiceName(t.service),
+		ddtracer.ResourceName(resource),
+		ddtracer.SpanType(spanType),
+		opentracing.Tag{Key: TagQuery, Value: queryString},
+	)
+	if operationName != "" {
+		span.SetTag(TagOperationName, operationName)
+	}
+
+	return ddtracer.ContextWithSpan(ctx, span), func(errs []*errors.QueryError) {
+		if len(errs) > 0 {
+			msgs := make([]string, len(errs))
+			for i, err := range errs {
+				msgs[i] = err.Message
+			}
+			span.SetTag(TagErrors, len(errs))
+			span.LogFields(log.Error(queryErrors(msgs)))
+		}
+		span.Finish()
+	}
+}
+
+func (t *tracer) TraceField(ctx context.Context, label, typeName, fieldName string, trivial bool, args map[string]interface{}) (context.Context, trace.TraceFieldFinishFunc) {
+	if !t.fields || trivial {
+		return ctx, func(*errors.QueryError) {}
+	}
+
+	span := t.tr.StartSpan(fieldOperation,
+		ddtracer.ChildOfContext(ctx),
+		ddtracer.ServiceName(t.service),
+		ddtracer.ResourceName(typeName+"."+fieldName),
+		ddtracer.SpanType(spanType),
+		opentracing.Tag{Key: TagType, Value: typeName},
+		opentracing.Tag{Key: TagField, Value: fieldName},
+	)
+
+	return ddtracer.ContextWithSpan(ctx, span), func(err *errors.QueryError) {
+		if err != nil {
+			span.LogFields(log.Error(err))
+		}
+		span.Finish()
+	}
+}
+
+// queryErrors are the messages of the errors of a query, joined as the
+// error message of its span.
+type queryErrors []string
+
+func (e queryErrors) Error() string {
+	return strings.Join(e, "; ")
+}
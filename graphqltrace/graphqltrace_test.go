@@ -0,0 +1,69 @@
+package graphqltrace
+
+import (
+	"context"
+	"testing"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	"github.com/gchaincl/dd-go-opentracing/mocktracer"
+	"github.com/graph-gophers/graphql-go/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracer(t *testing.T) {
+	tr := mocktracer.New()
+	gt := NewTracer(tr, WithFieldSpans())
+
+	parent := tr.StartSpan("http.request").(*ddtracer.Span)
+	ctx := ddtracer.ContextWithSpan(context.Background(), parent)
+
+	query := `query GetUser { user(id: 1) { name } }`
+	ctx, finishQuery := gt.TraceQuery(ctx, query, "GetUser", nil, nil)
+	_, finishTrivial := gt.TraceField(ctx, "", "User", "name", true, nil)
+	finishTrivial(nil)
+	_, finishField := gt.TraceField(ctx, "", "Query", "user", false, map[string]interface{}{"id": 1})
+	finishField(&errors.QueryError{Message: "not found"})
+	finishQuery([]*errors.QueryError{{Message: "not found"}, {Message: "denied"}})
+
+	// The field span finishes first.
+	spans := tr.FinishedSpans()
+	require.Len(t, spans, 2)
+	field, q := spans[0], spans[1]
+
+	assert.Equal(t, "graphql.query", q.Name)
+	assert.Equal(t, "GetUser", q.Resource)
+	assert.Equal(t, "graphql", q.Service)
+	assert.Equal(t, "graphql", q.Type)
+	assert.Equal(t, query, q.Tags[TagQuery])
+	assert.Equal(t, "GetUser", q.Tags[TagOperationName])
+	assert.Equal(t, float64(2), q.Metrics[TagErrors])
+	assert.True(t, q.Error)
+	assert.Equal(t, "not found; denied", q.Tags["error.msg"])
+	assert.Equal(t, parent.SpanID, q.ParentID)
+
+	assert.Equal(t, "graphql.field", field.Name)
+	assert.Equal(t, "Query.user", field.Resource)
+	assert.Equal(t, "Query", field.Tags[TagType])
+	assert.Equal(t, "user", field.Tags[TagField])
+	assert.True(t, field.Error)
+	assert.Equal(t, q.SpanID, field.ParentID)
+}
+
+func TestTracerAnonymousQuery(t *testing.T) {
+	tr := mocktracer.New()
+	gt := NewTracer(tr, ServiceName("api"))
+
+	query := `{ user(id: 1) { name } }`
+	ctx, finishQuery := gt.TraceQuery(context.Background(), query, "", nil, nil)
+	_, finishField := gt.TraceField(ctx, "", "Query", "user", false, nil)
+	finishField(nil)
+	finishQuery(nil)
+
+	spans := tr.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, query, spans[0].Resource)
+	assert.Equal(t, "api", spans[0].Service)
+	assert.NotContains(t, spans[0].Tags, TagOperationName)
+	assert.False(t, spans[0].Error)
+}
@@ -0,0 +1,260 @@
+// Package grpc provides gRPC interceptors tracing the calls with a ddtracer.Tracer,
+// the span context is propagated through the gRPC metadata.
+package grpc
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	serverOperation = "grpc.server"
+	clientOperation = "grpc.client"
+
+	// TagMethod is the full gRPC method, i.e. "/package.Service/Method".
+	TagMethod = "grpc.method"
+	// TagCode is the gRPC status code of the call, i.e. "NotFound".
+	TagCode = "grpc.code"
+
+	// HealthCheckMethod is the method of the standard gRPC health checking
+	// protocol called by the probes, see SkipMethods.
+	HealthCheckMethod = "/grpc.health.v1.Health/Check"
+)
+
+// Option configures the interceptors.
+type Option func(*options)
+
+type options struct {
+	skip map[string]bool
+}
+
+// SkipMethods disables the tracing of the calls to the given full methods,
+// i.e. HealthCheckMethod, so that the probes don't pay for the spans.
+func SkipMethods(methods ...string) Option {
+	return func(o *options) {
+		if o.skip == nil {
+			o.skip = make(map[string]bool, len(methods))
+		}
+		for _, m := range methods {
+			o.skip[m] = true
+		}
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// UnaryServerInterceptor traces the unary calls with a server span child of
+// the context propagated by the client, if any.
+func UnaryServerInterceptor(tr opentracing.Tracer, opts ...Option) gogrpc.UnaryServerInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, req interface{}, info *gogrpc.UnaryServerInfo, handler gogrpc.UnaryHandler) (interface{}, error) {
+		if o.skip[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		span, ctx := startServerSpan(ctx, tr, info.FullMethod)
+		resp, err := handler(ctx, req)
+		finish(span, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor traces the streams with a server span lasting until
+// the handler returns.
+func StreamServerInterceptor(tr opentracing.Tracer, opts ...Option) gogrpc.StreamServerInterceptor {
+	o := newOptions(opts)
+	return func(srv interface{}, ss gogrpc.ServerStream, info *gogrpc.StreamServerInfo, handler gogrpc.StreamHandler) error {
+		if o.skip[info.FullMethod] {
+			return handler(srv, ss)
+		}
+		span, ctx := startServerSpan(ss.Context(), tr, info.FullMethod)
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		finish(span, err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor traces the unary calls with a client span child of
+// the span in the call context, and injects it in the outgoing metadata.
+func UnaryClientInterceptor(tr opentracing.Tracer, opts ...Option) gogrpc.UnaryClientInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *gogrpc.ClientConn, invoker gogrpc.UnaryInvoker, opts ...gogrpc.CallOption) error {
+		if o.skip[method] {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		span, ctx := startClientSpan(ctx, tr, method)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		finish(span, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor traces the streams with a client span lasting until
+// the stream is drained, the single response of the client streaming calls
+// is received, the call fails or its context is canceled.
+func StreamClientInterceptor(tr opentracing.Tracer, opts ...Option) gogrpc.StreamClientInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, desc *gogrpc.StreamDesc, cc *gogrpc.ClientConn, method string, streamer gogrpc.Streamer, opts ...gogrpc.CallOption) (gogrpc.ClientStream, error) {
+		if o.skip[method] {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+		span, ctx := startClientSpan(ctx, tr, method)
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			finish(span, err)
+			return nil, err
+		}
+		return newClientStream(ctx, cs, desc, span), nil
+	}
+}
+
+func startServerSpan(ctx context.Context, tr opentracing.Tracer, method string) (opentracing.Span, context.Context) {
+	sso := []opentracing.StartSpanOption{
+		ext.SpanKindRPCServer,
+		opentracing.Tag{Key: TagMethod, Value: method},
+		opentracing.Tag{Key: string(ext.Component), Value: method},
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if sc, err := tr.Extract(opentracing.TextMap, mdCarrier(md)); err == nil {
+			sso = append(sso, opentracing.ChildOf(sc))
+		}
+	}
+
+	span := tr.StartSpan(serverOperation, sso...)
+	return span, ddtracer.ContextWithSpan(ctx, span)
+}
+
+func startClientSpan(ctx context.Context, tr opentracing.Tracer, method string) (opentracing.Span, context.Context) {
+	span := tr.StartSpan(clientOperation,
+		ddtracer.ChildOfContext(ctx),
+		ext.SpanKindRPCClient,
+		opentracing.Tag{Key: TagMethod, Value: method},
+		opentracing.Tag{Key: string(ext.Component), Value: method},
+	)
+
+	// The outgoing metadata must not be modified in place, it might be shared.
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	tr.Inject(span.Context(), opentracing.TextMap, mdCarrier(md))
+
+	return span, metadata.NewOutgoingContext(ddtracer.ContextWithSpan(ctx, span), md)
+}
+
+// finish tags span with the status of err and finishes it.
+func finish(span opentracing.Span, err error) {
+	code := status.Code(err)
+	span.SetTag(TagCode, code.String())
+	if code != codes.OK {
+		span.LogFields(log.Error(err))
+	}
+	span.Finish()
+}
+
+type serverStream struct {
+	gogrpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// clientStream finishes its span once, when the call is over.
+type clientStream struct {
+	gogrpc.ClientStream
+	span          opentracing.Span
+	serverStreams bool
+
+	once sync.Once
+	done chan struct{}
+}
+
+// newClientStream returns cs finishing span, and watching ctx so that the
+// span of the streams neither drained nor failed is finished when the call
+// is canceled, as gRPC requires the context to be canceled to release them.
+func newClientStream(ctx context.Context, cs gogrpc.ClientStream, desc *gogrpc.StreamDesc, span opentracing.Span) *clientStream {
+	s := &clientStream{
+		ClientStream:  cs,
+		span:          span,
+		serverStreams: desc.ServerStreams,
+		done:          make(chan struct{}),
+	}
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				s.finish(contextError(ctx.Err()))
+			case <-s.done:
+			}
+		}()
+	}
+	return s
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF:
+		s.finish(nil)
+	case err != nil:
+		s.finish(err)
+	case !s.serverStreams:
+		// The single response of the call, i.e. of CloseAndRecv.
+		s.finish(nil)
+	}
+	return err
+}
+
+func (s *clientStream) finish(err error) {
+	s.once.Do(func() {
+		finish(s.span, err)
+		close(s.done)
+	})
+}
+
+// contextError returns the gRPC status error of the context error err.
+func contextError(err error) error {
+	if err == context.DeadlineExceeded {
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return status.Error(codes.Canceled, err.Error())
+}
+
+// mdCarrier is a TextMap carrier on top of the gRPC metadata, whose keys are lowercase.
+type mdCarrier metadata.MD
+
+func (c mdCarrier) Set(key, val string) {
+	key = strings.ToLower(key)
+	c[key] = append(c[key], val)
+}
+
+func (c mdCarrier) ForeachKey(handler func(key, val string) error) error {
+	for k, vals := range c {
+		for _, v := range vals {
+			if err := handler(k, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
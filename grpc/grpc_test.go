@@ -0,0 +1,228 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const method = "/test.Service/Method"
+
+func TestUnaryInterceptors(t *testing.T) {
+	tr := ddtracer.NewTracer()
+
+	parent := tr.StartSpan("parent").(*ddtracer.Span)
+	ctx := ddtracer.ContextWithSpan(context.Background(), parent)
+
+	var client, server *ddtracer.Span
+	serverInterceptor := UnaryServerInterceptor(tr)
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *gogrpc.ClientConn, opts ...gogrpc.CallOption) error {
+		client = ddtracer.SpanFromContext(ctx).(*ddtracer.Span)
+
+		// Hand the outgoing metadata to the server as the transport would do.
+		md, _ := metadata.FromOutgoingContext(ctx)
+		ctx = metadata.NewIncomingContext(context.Background(), md)
+		info := &gogrpc.UnaryServerInfo{FullMethod: method}
+		_, err := serverInterceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			server = ddtracer.SpanFromContext(ctx).(*ddtracer.Span)
+			return nil, status.Error(codes.NotFound, "not found")
+		})
+		return err
+	}
+
+	err := UnaryClientInterceptor(tr)(ctx, method, nil, nil, nil, invoker)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	require.NotNil(t, client)
+	require.NotNil(t, server)
+	assert.Equal(t, parent.TraceID, client.TraceID)
+	assert.Equal(t, parent.SpanID, client.ParentID)
+	assert.Equal(t, client.TraceID, server.TraceID)
+	assert.Equal(t, client.SpanID, server.ParentID)
+
+	for _, s := range []*ddtracer.Span{client, server} {
+		assert.Equal(t, method, s.GetMeta(TagMethod))
+		assert.Equal(t, method, s.Resource)
+		assert.Equal(t, "NotFound", s.GetMeta(TagCode))
+		assert.Equal(t, int32(1), s.Error)
+	}
+	assert.Equal(t, "client", client.GetMeta("span.kind"))
+	assert.Equal(t, "server", server.GetMeta("span.kind"))
+}
+
+func TestUnaryClientInterceptorKeepsMetadata(t *testing.T) {
+	tr := ddtracer.NewTracer()
+
+	md := metadata.Pairs("key", "value")
+	ctx := metadata.NewOutgoingContext(context.Background(), md)
+
+	var out metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *gogrpc.ClientConn, opts ...gogrpc.CallOption) error {
+		out, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	require.NoError(t, UnaryClientInterceptor(tr)(ctx, method, nil, nil, nil, invoker))
+
+	assert.Equal(t, []string{"value"}, out.Get("key"))
+	assert.NotEmpty(t, out.Get("x-datadog-trace-id"))
+	assert.Empty(t, md.Get("x-datadog-trace-id"))
+}
+
+type fakeServerStream struct {
+	gogrpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	tr := ddtracer.NewTracer()
+
+	var server *ddtracer.Span
+	ss := &fakeServerStream{ctx: context.Background()}
+	info := &gogrpc.StreamServerInfo{FullMethod: method}
+	err := StreamServerInterceptor(tr)(nil, ss, info, func(srv interface{}, stream gogrpc.ServerStream) error {
+		server = ddtracer.SpanFromContext(stream.Context()).(*ddtracer.Span)
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, server)
+	assert.Equal(t, "OK", server.GetMeta(TagCode))
+	assert.Equal(t, int32(0), server.Error)
+}
+
+func TestSkipMethods(t *testing.T) {
+	tr := ddtracer.NewTracer()
+
+	var server interface{}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		server = ddtracer.SpanFromContext(ctx)
+		return nil, nil
+	}
+	interceptor := UnaryServerInterceptor(tr, SkipMethods(HealthCheckMethod))
+
+	_, err := interceptor(context.Background(), nil, &gogrpc.UnaryServerInfo{FullMethod: HealthCheckMethod}, handler)
+	require.NoError(t, err)
+	assert.Nil(t, server)
+
+	_, err = interceptor(context.Background(), nil, &gogrpc.UnaryServerInfo{FullMethod: method}, handler)
+	require.NoError(t, err)
+	assert.NotNil(t, server)
+
+	var client interface{}
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *gogrpc.ClientConn, opts ...gogrpc.CallOption) error {
+		client = ddtracer.SpanFromContext(ctx)
+		return nil
+	}
+	err = UnaryClientInterceptor(tr, SkipMethods(HealthCheckMethod))(context.Background(), HealthCheckMethod, nil, nil, nil, invoker)
+	require.NoError(t, err)
+	assert.Nil(t, client)
+}
+
+// fakeClientStream returns the responses then io.EOF, or err.
+type fakeClientStream struct {
+	gogrpc.ClientStream
+	responses int
+	err       error
+}
+
+func (s *fakeClientStream) SendMsg(m interface{}) error { return nil }
+
+func (s *fakeClientStream) RecvMsg(m interface{}) error {
+	if s.responses == 0 {
+		if s.err != nil {
+			return s.err
+		}
+		return io.EOF
+	}
+	s.responses--
+	return nil
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	finished := make(chan *ddtracer.Span, 10)
+	tr := ddtracer.NewTracerWithOptions(ddtracer.WithSpanObserver(finishedSpans(finished)))
+
+	open := func(ctx context.Context, desc *gogrpc.StreamDesc, cs *fakeClientStream) gogrpc.ClientStream {
+		streamer := func(ctx context.Context, desc *gogrpc.StreamDesc, cc *gogrpc.ClientConn, method string, opts ...gogrpc.CallOption) (gogrpc.ClientStream, error) {
+			return cs, nil
+		}
+		stream, err := StreamClientInterceptor(tr)(ctx, desc, nil, method, streamer)
+		require.NoError(t, err)
+		return stream
+	}
+	next := func(t *testing.T) *ddtracer.Span {
+		select {
+		case span := <-finished:
+			return span
+		case <-time.After(time.Second):
+			t.Fatal("no span finished")
+			return nil
+		}
+	}
+
+	t.Run("Server streaming", func(t *testing.T) {
+		stream := open(context.Background(), &gogrpc.StreamDesc{ServerStreams: true}, &fakeClientStream{responses: 2})
+		require.NoError(t, stream.RecvMsg(nil))
+		require.NoError(t, stream.RecvMsg(nil))
+		assert.Empty(t, finished)
+		assert.Equal(t, io.EOF, stream.RecvMsg(nil))
+
+		span := next(t)
+		assert.Equal(t, "OK", span.GetMeta(TagCode))
+		assert.Equal(t, io.EOF, stream.RecvMsg(nil))
+		assert.Empty(t, finished)
+	})
+
+	t.Run("Client streaming", func(t *testing.T) {
+		stream := open(context.Background(), &gogrpc.StreamDesc{ClientStreams: true}, &fakeClientStream{responses: 1})
+		require.NoError(t, stream.SendMsg(nil))
+		assert.Empty(t, finished)
+
+		// CloseAndRecv never sees io.EOF.
+		require.NoError(t, stream.RecvMsg(nil))
+		span := next(t)
+		assert.Equal(t, "OK", span.GetMeta(TagCode))
+		assert.Equal(t, int32(0), span.Error)
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		stream := open(context.Background(), &gogrpc.StreamDesc{ServerStreams: true}, &fakeClientStream{err: status.Error(codes.Unavailable, "unavailable")})
+		assert.Error(t, stream.RecvMsg(nil))
+		span := next(t)
+		assert.Equal(t, "Unavailable", span.GetMeta(TagCode))
+		assert.Equal(t, int32(1), span.Error)
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		stream := open(ctx, &gogrpc.StreamDesc{ServerStreams: true}, &fakeClientStream{responses: 10})
+		require.NoError(t, stream.RecvMsg(nil))
+		assert.Empty(t, finished)
+
+		cancel()
+		span := next(t)
+		assert.Equal(t, "Canceled", span.GetMeta(TagCode))
+		require.NoError(t, stream.RecvMsg(nil))
+		assert.Empty(t, finished)
+	})
+}
+
+// finishedSpans is a SpanObserver sending the finished spans.
+type finishedSpans chan *ddtracer.Span
+
+func (finishedSpans) OnStart(*ddtracer.Span) {}
+
+func (c finishedSpans) OnFinish(span *ddtracer.Span) { c <- span }
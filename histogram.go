@@ -0,0 +1,140 @@
+package ddtracer
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// durationBounds are the upper bounds of the buckets of the duration
+// histograms, doubling from 1µs to about a minute, the longer durations
+// being counted by a last bucket.
+var durationBounds = func() (bounds [27]time.Duration) {
+	for i := range bounds {
+		bounds[i] = time.Microsecond << uint(i)
+	}
+	return bounds
+}()
+
+// DurationHistogram is the distribution of the durations of the spans of an
+// operation, see WithDurationHistograms.
+type DurationHistogram struct {
+	Count uint64
+	Sum   time.Duration
+	Min   time.Duration
+	Max   time.Duration
+
+	// Buckets count the spans by duration, only the non-empty ones being
+	// listed, in increasing order.
+	Buckets []DurationBucket
+}
+
+// DurationBucket counts the spans lasting up to UpperBound, and more than
+// the bound of the previous bucket. The last bucket has no UpperBound.
+type DurationBucket struct {
+	UpperBound time.Duration
+	Count      uint64
+}
+
+// Mean returns the mean duration of the spans.
+func (h DurationHistogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile estimates the q quantile of the durations, q being between 0 and
+// 1, as the upper bound of the bucket holding it, capped at Max.
+func (h DurationHistogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := uint64(q*float64(h.Count) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+
+	var seen uint64
+	for _, b := range h.Buckets {
+		seen += b.Count
+		if seen >= rank {
+			if b.UpperBound == 0 || b.UpperBound > h.Max {
+				return h.Max
+			}
+			return b.UpperBound
+		}
+	}
+	return h.Max
+}
+
+// histogram is the DurationHistogram being recorded of an operation.
+type histogram struct {
+	count    uint64
+	sum      time.Duration
+	min, max time.Duration
+	buckets  [len(durationBounds) + 1]uint64
+}
+
+func (h *histogram) record(d time.Duration) {
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.count++
+	h.sum += d
+	h.buckets[sort.Search(len(durationBounds), func(i int) bool { return durationBounds[i] >= d })]++
+}
+
+func (h *histogram) snapshot() DurationHistogram {
+	s := DurationHistogram{Count: h.count, Sum: h.sum, Min: h.min, Max: h.max}
+	for i, n := range h.buckets {
+		if n == 0 {
+			continue
+		}
+		b := DurationBucket{Count: n}
+		if i < len(durationBounds) {
+			b.UpperBound = durationBounds[i]
+		}
+		s.Buckets = append(s.Buckets, b)
+	}
+	return s
+}
+
+// durationHistograms is a SpanObserver recording the durations of the
+// finished spans per operation name.
+type durationHistograms struct {
+	mu         sync.Mutex
+	operations map[string]*histogram
+}
+
+func newDurationHistograms() *durationHistograms {
+	return &durationHistograms{operations: make(map[string]*histogram)}
+}
+
+func (o *durationHistograms) OnStart(span *Span) {}
+
+func (o *durationHistograms) OnFinish(span *Span) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	h, ok := o.operations[span.Name]
+	if !ok {
+		h = &histogram{}
+		o.operations[span.Name] = h
+	}
+	h.record(time.Duration(span.Duration))
+}
+
+func (o *durationHistograms) snapshot() map[string]DurationHistogram {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	snapshot := make(map[string]DurationHistogram, len(o.operations))
+	for op, h := range o.operations {
+		snapshot[op] = h.snapshot()
+	}
+	return snapshot
+}
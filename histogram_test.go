@@ -0,0 +1,84 @@
+package ddtracer
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDurationHistogram(t *testing.T) {
+	h := &histogram{}
+	assert.Zero(t, h.snapshot().Quantile(0.5))
+	assert.Zero(t, h.snapshot().Mean())
+
+	for _, d := range []time.Duration{
+		3 * time.Microsecond,
+		100 * time.Microsecond, 100 * time.Microsecond, 120 * time.Microsecond,
+		5 * time.Millisecond,
+		2 * time.Minute,
+	} {
+		h.record(d)
+	}
+
+	s := h.snapshot()
+	assert.Equal(t, uint64(6), s.Count)
+	assert.Equal(t, 3*time.Microsecond, s.Min)
+	assert.Equal(t, 2*time.Minute, s.Max)
+	assert.Equal(t, []DurationBucket{
+		{UpperBound: 4 * time.Microsecond, Count: 1},
+		{UpperBound: 128 * time.Microsecond, Count: 3},
+		{UpperBound: 8192 * time.Microsecond, Count: 1},
+		{Count: 1},
+	}, s.Buckets)
+
+	assert.Equal(t, 4*time.Microsecond, s.Quantile(0))
+	assert.Equal(t, 128*time.Microsecond, s.Quantile(0.5))
+	assert.Equal(t, 8192*time.Microsecond, s.Quantile(0.8))
+	assert.Equal(t, 2*time.Minute, s.Quantile(1))
+	assert.Equal(t, s.Sum/6, s.Mean())
+
+	t.Run("Capped at max", func(t *testing.T) {
+		h := &histogram{}
+		h.record(5 * time.Microsecond)
+		assert.Equal(t, 5*time.Microsecond, h.snapshot().Quantile(0.99))
+	})
+}
+
+func TestWithDurationHistograms(t *testing.T) {
+	tr := NewTracerWithOptions(WithTransport(&recordingTransport{}), WithDurationHistograms(true)).(*Tracer)
+	start := time.Now()
+	for i, op := range []string{"fast", "fast", "slow"} {
+		d := time.Duration(i+1) * time.Millisecond
+		if op == "slow" {
+			d = time.Second
+		}
+		tr.StartSpan(op, opentracing.StartTime(start)).FinishWithOptions(opentracing.FinishOptions{FinishTime: start.Add(d)})
+	}
+
+	durations := tr.Stats().Durations
+	require.Len(t, durations, 2)
+	assert.Equal(t, uint64(2), durations["fast"].Count)
+	assert.Equal(t, time.Millisecond, durations["fast"].Min)
+	assert.Equal(t, 2*time.Millisecond, durations["fast"].Max)
+	assert.Equal(t, time.Second, durations["slow"].Quantile(0.99))
+
+	rec := httptest.NewRecorder()
+	tr.DebugHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	var state debugState
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&state))
+	require.Contains(t, state.Durations, "slow")
+	assert.Equal(t, uint64(1), state.Durations["slow"].Count)
+	assert.Equal(t, 1.0, state.Durations["slow"].P50Seconds)
+	assert.Len(t, state.Durations["fast"].Buckets, 2)
+
+	t.Run("Disabled", func(t *testing.T) {
+		tr := NewTracerWithOptions(WithTransport(&recordingTransport{})).(*Tracer)
+		tr.StartSpan("op").Finish()
+		assert.Nil(t, tr.Stats().Durations)
+	})
+}
@@ -0,0 +1,58 @@
+package ddtracer
+
+import (
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// SpanHook intercepts the spans started and finished by a Tracer, i.e. to
+// add tags to every span, to audit them, or to drop them by rule setting
+// their sampling priority. See Tracer.RegisterSpanHook.
+//
+// Unlike the SpanObservers, the hooks may modify the spans: they are called
+// before the observers, while the span can still be tagged.
+type SpanHook interface {
+	// OnStart is called once the span is started, with the options it was
+	// started with. The options must not be kept after returning.
+	OnStart(span *Span, opts opentracing.StartSpanOptions)
+
+	// OnFinish is called once when the span is finished, with its duration
+	// already set.
+	OnFinish(span *Span)
+}
+
+// SpanHookFuncs is a SpanHook calling its non-nil funcs.
+type SpanHookFuncs struct {
+	Start  func(span *Span, opts opentracing.StartSpanOptions)
+	Finish func(span *Span)
+}
+
+func (h SpanHookFuncs) OnStart(span *Span, opts opentracing.StartSpanOptions) {
+	if h.Start != nil {
+		h.Start(span, opts)
+	}
+}
+
+func (h SpanHookFuncs) OnFinish(span *Span) {
+	if h.Finish != nil {
+		h.Finish(span)
+	}
+}
+
+// RegisterSpanHook adds hook to the ones called for every span started and
+// finished by the Tracer, in registration order. It's safe to call it
+// concurrently with StartSpan, the spans already started are not hooked.
+func (t *Tracer) RegisterSpanHook(hook SpanHook) {
+	t.hooksMu.Lock()
+	defer t.hooksMu.Unlock()
+
+	hooks := make([]SpanHook, len(t.hooks), len(t.hooks)+1)
+	copy(hooks, t.hooks)
+	t.hooks = append(hooks, hook)
+}
+
+// spanHooks returns the registered hooks, which must not be modified.
+func (t *Tracer) spanHooks() []SpanHook {
+	t.hooksMu.RLock()
+	defer t.hooksMu.RUnlock()
+	return t.hooks
+}
@@ -0,0 +1,64 @@
+package ddtracer
+
+import (
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpanHook(t *testing.T) {
+	tr := NewTracer().(*Tracer)
+
+	var calls []string
+	tr.RegisterSpanHook(SpanHookFuncs{
+		Start: func(span *Span, opts opentracing.StartSpanOptions) {
+			calls = append(calls, "start")
+			span.SetTag("team", "payments")
+			assert.Equal(t, "value", opts.Tags["tag"])
+		},
+		Finish: func(span *Span) {
+			calls = append(calls, "finish")
+			assert.NotZero(t, span.Duration)
+			if span.Resource == "/health" {
+				span.SetTag(string(ext.SamplingPriority), 0)
+			}
+		},
+	})
+	tr.RegisterSpanHook(SpanHookFuncs{
+		Start: func(span *Span, opts opentracing.StartSpanOptions) {
+			calls = append(calls, "start 2")
+		},
+	})
+
+	span := tr.StartSpan("http.request", opentracing.Tag{Key: "tag", Value: "value"}, ResourceName("/health")).(*Span)
+	assert.Equal(t, "payments", span.GetMeta("team"))
+
+	span.Finish()
+	span.Finish()
+	assert.Equal(t, []string{"start", "start 2", "finish"}, calls)
+
+	priority, ok := span.SamplingPriority()
+	require.True(t, ok)
+	assert.Equal(t, 0, priority)
+}
+
+func TestSpanHookReentrantFinish(t *testing.T) {
+	tr := NewTracer().(*Tracer)
+
+	finished := 0
+	tr.RegisterSpanHook(SpanHookFuncs{
+		Finish: func(span *Span) {
+			finished++
+			span.Finish()
+		},
+	})
+
+	span := tr.StartSpan("test")
+	span.Finish()
+	span.SetTag("late", "tag")
+	assert.Equal(t, 1, finished)
+	assert.Empty(t, span.(*Span).GetMeta("late"))
+}
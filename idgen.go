@@ -0,0 +1,70 @@
+package ddtracer
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// IDGenerator generates the IDs of the spans and of the traces started by a
+// Tracer, see WithIDGenerator. It must be safe for concurrent use.
+type IDGenerator interface {
+	// NewID returns a new non-zero ID.
+	NewID() uint64
+}
+
+// IDGeneratorFunc is an IDGenerator calling the func.
+type IDGeneratorFunc func() uint64
+
+func (f IDGeneratorFunc) NewID() uint64 {
+	return f()
+}
+
+// maxID keeps the IDs within 63 bits, as some DataDog components handle them
+// as signed integers.
+const maxID = 1<<63 - 1
+
+// randIDGenerator is the default IDGenerator, a pseudo-random one owned by the
+// Tracer so it doesn't contend with the rest of the process.
+type randIDGenerator struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func newRandIDGenerator() *randIDGenerator {
+	var b [8]byte
+	seed := time.Now().UnixNano()
+	if _, err := cryptorand.Read(b[:]); err == nil {
+		seed = int64(binary.LittleEndian.Uint64(b[:]))
+	}
+	return &randIDGenerator{rand: rand.New(rand.NewSource(seed))}
+}
+
+func (g *randIDGenerator) NewID() uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for {
+		if id := uint64(g.rand.Int63()); id != 0 {
+			return id
+		}
+	}
+}
+
+// CryptoIDGenerator returns an IDGenerator reading crypto/rand, which makes
+// the IDs unpredictable at the cost of being slower than the default one.
+func CryptoIDGenerator() IDGenerator {
+	return IDGeneratorFunc(func() uint64 {
+		var b [8]byte
+		for {
+			if _, err := cryptorand.Read(b[:]); err != nil {
+				panic("ddtracer: reading crypto/rand: " + err.Error())
+			}
+			if id := binary.LittleEndian.Uint64(b[:]) & maxID; id != 0 {
+				return id
+			}
+		}
+	})
+}
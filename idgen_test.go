@@ -0,0 +1,57 @@
+package ddtracer
+
+import (
+	"sync/atomic"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIDGenerator(t *testing.T) {
+	var next uint64
+	tr := NewTracerWithOptions(WithIDGenerator(IDGeneratorFunc(func() uint64 {
+		return atomic.AddUint64(&next, 1)
+	})))
+
+	root := tr.StartSpan("root").(*Span)
+	assert.Equal(t, uint64(1), root.SpanID)
+	assert.Equal(t, uint64(1), root.TraceID)
+
+	child := tr.StartSpan("child", opentracing.ChildOf(root.Context())).(*Span)
+	assert.Equal(t, uint64(2), child.SpanID)
+	assert.Equal(t, uint64(1), child.TraceID)
+
+	remote := tr.StartSpan("remote", opentracing.ChildOf(NewRemoteSpanContext(0, 42, 43, true))).(*Span)
+	assert.Equal(t, uint64(3), remote.SpanID)
+	assert.Equal(t, uint64(42), remote.TraceID)
+	assert.Equal(t, uint64(43), remote.ParentID)
+}
+
+func TestDefaultIDGenerators(t *testing.T) {
+	for name, g := range map[string]IDGenerator{
+		"Random": newRandIDGenerator(),
+		"Crypto": CryptoIDGenerator(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			seen := make(map[uint64]bool)
+			for i := 0; i < 1000; i++ {
+				id := g.NewID()
+				assert.NotZero(t, id)
+				assert.True(t, id <= maxID)
+				assert.False(t, seen[id])
+				seen[id] = true
+			}
+		})
+	}
+}
+
+func BenchmarkIDGenerator(b *testing.B) {
+	g := newRandIDGenerator()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			g.NewID()
+		}
+	})
+}
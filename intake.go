@@ -0,0 +1,149 @@
+package ddtracer
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+)
+
+const (
+	defaultSite = "datadoghq.com"
+
+	// maxIntakePayload is the maximum size of the uncompressed payloads sent
+	// to the intake, larger batches of traces are split, see encodePayloads.
+	maxIntakePayload = 3 << 20
+
+	intakeRetries = 3
+	intakeBackoff = 100 * time.Millisecond
+)
+
+// intakeURL returns the URL of the traces intake of the DataDog site.
+func intakeURL(site string) string {
+	if site == "" {
+		site = defaultSite
+	}
+	return "https://trace.agent." + site + "/api/v0.2/traces"
+}
+
+// intakeTransport sends the traces straight to the DataDog intake,
+// authenticated by an API key, for the environments without an agent.
+type intakeTransport struct {
+	url    string
+	apiKey string
+	client *http.Client
+
+	maxPayload int
+	retries    int
+	backoff    time.Duration
+
+	headersMu sync.RWMutex
+	headers   map[string]string
+}
+
+// newIntakeTransport returns a transport to the intake at url. A nil client
+// is replaced by one with a 10 seconds timeout.
+func newIntakeTransport(url, apiKey string, client *http.Client) *intakeTransport {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &intakeTransport{
+		url:        url,
+		apiKey:     apiKey,
+		client:     client,
+		maxPayload: maxIntakePayload,
+		retries:    intakeRetries,
+		backoff:    intakeBackoff,
+		headers:    map[string]string{},
+	}
+}
+
+// SendTraces sends the traces in batches no larger than maxPayload once
+// encoded, chunking the traces larger than that on their own.
+func (t *intakeTransport) SendTraces(traces [][]*tracer.Span) (*http.Response, error) {
+	payloads, err := encodePayloads(EncodingJSON, traces, t.maxPayload)
+	var errs []error
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	resp := &http.Response{}
+	for _, p := range payloads {
+		r, err := t.send(p.body, p.traces)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		resp = r
+	}
+
+	if len(errs) > 0 {
+		return resp, fmt.Errorf("sending traces to the intake: %v", errs)
+	}
+	return resp, nil
+}
+
+// SendServices is a no-op, the intake doesn't take the services metadata.
+func (t *intakeTransport) SendServices(services map[string]tracer.Service) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusOK}, nil
+}
+
+func (t *intakeTransport) SetHeader(key, value string) {
+	t.headersMu.Lock()
+	t.headers[key] = value
+	t.headersMu.Unlock()
+}
+
+// send posts the payload gzipped, retrying with exponential backoff on
+// network errors, throttling and server errors.
+func (t *intakeTransport) send(payload []byte, count int) (*http.Response, error) {
+	body, err := gzipped(payload)
+	if err != nil {
+		return &http.Response{}, err
+	}
+
+	var resp *http.Response
+	for attempt := 0; ; attempt++ {
+		resp, err = t.post(body, count)
+		if err == nil && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			break
+		}
+		if attempt == t.retries {
+			break
+		}
+		time.Sleep(t.backoff << uint(attempt))
+	}
+
+	if err != nil {
+		return &http.Response{}, err
+	}
+	if resp.StatusCode >= 400 {
+		return resp, fmt.Errorf("intake responded %s", resp.Status)
+	}
+	return resp, nil
+}
+
+func (t *intakeTransport) post(body []byte, count int) (*http.Response, error) {
+	req, err := http.NewRequest("POST", t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	t.headersMu.RLock()
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	t.headersMu.RUnlock()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("DD-API-KEY", t.apiKey)
+	req.Header.Set("X-Datadog-Trace-Count", fmt.Sprint(count))
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
@@ -0,0 +1,126 @@
+package ddtracer
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// intake is a fake DataDog intake, failing the first requests with status.
+type intake struct {
+	failures int
+	status   int
+
+	requests int
+	apiKeys  []string
+	traces   [][]*tracer.Span
+}
+
+func (in *intake) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	in.requests++
+	if in.failures > 0 {
+		in.failures--
+		w.WriteHeader(in.status)
+		return
+	}
+
+	zr, err := gzip.NewReader(req.Body)
+	if err != nil || req.Header.Get("Content-Encoding") != "gzip" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var traces [][]*tracer.Span
+	if err := json.NewDecoder(zr).Decode(&traces); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	in.apiKeys = append(in.apiKeys, req.Header.Get("DD-API-KEY"))
+	in.traces = append(in.traces, traces...)
+}
+
+func newTestIntake(in *intake) (*intakeTransport, func()) {
+	ts := httptest.NewServer(in)
+	tr := newIntakeTransport(ts.URL, "key", nil)
+	tr.backoff = 0
+	return tr, ts.Close
+}
+
+func trace(name string) []*tracer.Span {
+	return []*tracer.Span{{Name: name, TraceID: 1, SpanID: 1}}
+}
+
+func TestIntakeTransport(t *testing.T) {
+	t.Run("Agentless", func(t *testing.T) {
+		in := &intake{}
+		ts := httptest.NewServer(in)
+		defer ts.Close()
+
+		tr := NewTracerWithOptions(WithAgentless("secret"), WithIntakeURL(ts.URL)).(*Tracer)
+		tr.StartSpan("test").Finish()
+		require.NoError(t, tr.FlushTraces())
+
+		require.Len(t, in.traces, 1)
+		assert.Equal(t, "test", in.traces[0][0].Name)
+		assert.Equal(t, []string{"secret"}, in.apiKeys)
+	})
+
+	t.Run("Retries", func(t *testing.T) {
+		in := &intake{failures: 2, status: http.StatusServiceUnavailable}
+		tr, done := newTestIntake(in)
+		defer done()
+
+		_, err := tr.SendTraces([][]*tracer.Span{trace("a")})
+		require.NoError(t, err)
+		assert.Equal(t, 3, in.requests)
+		assert.Len(t, in.traces, 1)
+
+		in = &intake{failures: 10, status: http.StatusTooManyRequests}
+		tr, done = newTestIntake(in)
+		defer done()
+
+		resp, err := tr.SendTraces([][]*tracer.Span{trace("a")})
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, intakeRetries+1, in.requests)
+	})
+
+	t.Run("Client errors aren't retried", func(t *testing.T) {
+		in := &intake{failures: 1, status: http.StatusForbidden}
+		tr, done := newTestIntake(in)
+		defer done()
+
+		_, err := tr.SendTraces([][]*tracer.Span{trace("a")})
+		assert.Error(t, err)
+		assert.Equal(t, 1, in.requests)
+	})
+
+	t.Run("Payload limit", func(t *testing.T) {
+		in := &intake{}
+		tr, done := newTestIntake(in)
+		defer done()
+
+		b, _ := json.Marshal(trace("a"))
+		tr.maxPayload = 2*len(b) + 3
+
+		_, err := tr.SendTraces([][]*tracer.Span{trace("a"), trace("b"), trace("c")})
+		require.NoError(t, err)
+		assert.Equal(t, 2, in.requests)
+		assert.Len(t, in.traces, 3)
+
+		_, err = tr.SendTraces([][]*tracer.Span{trace("a"), trace(strings.Repeat("x", 3*len(b)))})
+		assert.Error(t, err)
+		assert.Len(t, in.traces, 4)
+	})
+
+	t.Run("Intake URL", func(t *testing.T) {
+		assert.Equal(t, "https://trace.agent.datadoghq.com/api/v0.2/traces", intakeURL(""))
+		assert.Equal(t, "https://trace.agent.datadoghq.eu/api/v0.2/traces", intakeURL("datadoghq.eu"))
+	})
+}
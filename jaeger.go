@@ -0,0 +1,143 @@
+package ddtracer
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+const (
+	jaegerTraceIDKey    = "uber-trace-id"
+	jaegerBaggagePrefix = "uberctx-"
+	jaegerBaggageKey    = "jaeger-baggage"
+	jaegerFlagSampled   = 0x1
+)
+
+// jaegerPropagator implements Jaeger's uber-trace-id header, i.e.
+// "uber-trace-id: {trace-id}:{span-id}:{parent-span-id}:{flags}", along with
+// its uberctx-{key} baggage headers and the jaeger-baggage ad-hoc one.
+// The upper 64 bits of 128-bit trace IDs are kept apart.
+type jaegerPropagator struct {
+	// httpHeaders URL-encodes the values, as the Jaeger clients do.
+	httpHeaders bool
+}
+
+func (p *jaegerPropagator) Inject(sc *SpanContext, carrier interface{}) error {
+	tm, ok := carrier.(opentracing.TextMapWriter)
+	if !ok {
+		return opentracing.ErrInvalidCarrier
+	}
+
+	traceID := strconv.FormatUint(sc.traceID, 16)
+	if sc.traceIDHigh != 0 {
+		traceID = fmt.Sprintf("%x%016x", sc.traceIDHigh, sc.traceID)
+	}
+	var flags uint64
+	if sc.sampled {
+		flags |= jaegerFlagSampled
+	}
+	tm.Set(jaegerTraceIDKey, p.encode(fmt.Sprintf("%s:%x:%x:%x", traceID, sc.spanID, sc.parentID, flags)))
+
+	for k, v := range sc.baggage {
+		tm.Set(jaegerBaggagePrefix+k, p.encode(v))
+	}
+
+	return nil
+}
+
+func (p *jaegerPropagator) Extract(carrier interface{}) (opentracing.SpanContext, error) {
+	tm, ok := carrier.(opentracing.TextMapReader)
+	if !ok {
+		return nil, opentracing.ErrInvalidCarrier
+	}
+
+	var header string
+	baggage := make(map[string]string)
+	tm.ForeachKey(func(k, v string) error {
+		switch key := lowerKey(k); {
+		case key == jaegerTraceIDKey:
+			header = p.decode(v)
+		case key == jaegerBaggageKey:
+			for _, kv := range strings.Split(v, ",") {
+				if kv := strings.SplitN(strings.TrimSpace(kv), "=", 2); len(kv) == 2 {
+					baggage[kv[0]] = kv[1]
+				}
+			}
+		case strings.HasPrefix(key, jaegerBaggagePrefix):
+			baggage[strings.TrimPrefix(key, jaegerBaggagePrefix)] = p.decode(v)
+		}
+		return nil
+	})
+	if header == "" {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+
+	parts := strings.Split(header, ":")
+	if len(parts) != 4 || len(parts[0]) > 32 {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+
+	var err error
+	var traceIDHigh uint64
+	v := parts[0]
+	if len(v) > 16 {
+		if traceIDHigh, err = strconv.ParseUint(v[:len(v)-16], 16, 64); err != nil {
+			return nil, opentracing.ErrSpanContextCorrupted
+		}
+		v = v[len(v)-16:]
+	}
+	traceID, err := strconv.ParseUint(v, 16, 64)
+	if err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	spanID, err := strconv.ParseUint(parts[1], 16, 64)
+	if err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	parentID, err := strconv.ParseUint(parts[2], 16, 64)
+	if err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+
+	if traceID == 0 || spanID == 0 {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+
+	span := &Span{
+		Span: &tracer.Span{
+			SpanID:   spanID,
+			ParentID: parentID,
+			TraceID:  traceID,
+			Sampled:  flags&jaegerFlagSampled != 0,
+		},
+		baggage:     baggage,
+		traceIDHigh: traceIDHigh,
+	}
+
+	return span.Context(), nil
+}
+
+func (p *jaegerPropagator) encode(v string) string {
+	if p.httpHeaders {
+		return url.QueryEscape(v)
+	}
+	return v
+}
+
+func (p *jaegerPropagator) decode(v string) string {
+	if !p.httpHeaders {
+		return v
+	}
+	if d, err := url.QueryUnescape(v); err == nil {
+		return d
+	}
+	return v
+}
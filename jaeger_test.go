@@ -0,0 +1,77 @@
+package ddtracer
+
+import (
+	"net/http"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJaegerPropagation(t *testing.T) {
+	tr := NewTracerWithOptions(WithPropagationStyle("jaeger")).(*Tracer)
+
+	sc, err := tr.ExtractHTTPHeader(http.Header{
+		"Uber-Trace-Id":   []string{"4bf92f3577b34da6a3ce929d0e0e4736%3A53995c3f42cd8ad8%3A0%3A1"},
+		"Uberctx-User-Id": []string{"a%20b"},
+		"Jaeger-Baggage":  []string{"k1=v1, k2=v2"},
+	})
+	require.NoError(t, err)
+
+	ctx := sc.(*SpanContext)
+	assert.Equal(t, uint64(0xa3ce929d0e0e4736), ctx.traceID)
+	assert.Equal(t, uint64(0x4bf92f3577b34da6), ctx.traceIDHigh)
+	assert.Equal(t, uint64(0x53995c3f42cd8ad8), ctx.spanID)
+	assert.True(t, ctx.sampled)
+	assert.Equal(t, map[string]string{"user-id": "a b", "k1": "v1", "k2": "v2"}, ctx.baggage)
+
+	child := tr.StartSpan("child", opentracing.ChildOf(sc)).(*Span)
+	child.SpanID = 0xaa
+
+	h := http.Header{}
+	require.NoError(t, tr.InjectHTTPHeader(child.Context(), h))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736%3Aaa%3A53995c3f42cd8ad8%3A1", h.Get("Uber-Trace-Id"))
+	assert.Equal(t, "a+b", h.Get("Uberctx-User-Id"))
+	assert.Empty(t, h.Get("X-Datadog-Trace-Id"))
+
+	t.Run("TextMap", func(t *testing.T) {
+		tm := opentracing.TextMapCarrier{}
+		span := tr.StartSpan("root").(*Span)
+		span.TraceID, span.SpanID = 0xbb, 0xcc
+		span.Sampled = false
+		require.NoError(t, tr.Inject(span.Context(), opentracing.TextMap, tm))
+		assert.Equal(t, "bb:cc:0:0", tm["uber-trace-id"])
+
+		sc, err := tr.Extract(opentracing.TextMap, tm)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(0xbb), sc.(*SpanContext).traceID)
+		assert.False(t, sc.(*SpanContext).sampled)
+	})
+
+	t.Run("Corrupted", func(t *testing.T) {
+		for _, v := range []string{
+			"bb:cc:0",
+			"bz:cc:0:1",
+			"bb:cc:0:1:1",
+		} {
+			_, err := tr.Extract(opentracing.TextMap, opentracing.TextMapCarrier{"uber-trace-id": v})
+			assert.Equal(t, opentracing.ErrSpanContextCorrupted, err, v)
+		}
+	})
+}
+
+func TestPropagationStyle(t *testing.T) {
+	tr := NewTracerWithOptions(WithPropagationStyle("datadog, jaeger, unknown")).(*Tracer)
+
+	span := tr.StartSpan("root").(*Span)
+	h := http.Header{}
+	require.NoError(t, tr.InjectHTTPHeader(span.Context(), h))
+	assert.NotEmpty(t, h.Get("X-Datadog-Trace-Id"))
+	assert.NotEmpty(t, h.Get("Uber-Trace-Id"))
+	assert.Empty(t, h.Get("X-B3-Traceid"))
+
+	sc, err := tr.ExtractHTTPHeader(http.Header{"Uber-Trace-Id": []string{"bb:cc:0:1"}})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0xbb), sc.(*SpanContext).traceID)
+}
@@ -0,0 +1,119 @@
+package ddtracer
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+const (
+	jobOperation  = "job.run"
+	jobQueueKey   = "job.queue"
+	jobAttemptKey = "job.attempt"
+)
+
+// JobOption configures TraceJob.
+type JobOption func(*jobConfig)
+
+type jobConfig struct {
+	tracer  opentracing.Tracer
+	queue   string
+	attempt int
+	parent  string
+}
+
+// JobTracer sets the Tracer starting the job spans, the opentracing global
+// tracer by default.
+func JobTracer(tr opentracing.Tracer) JobOption {
+	return func(c *jobConfig) {
+		c.tracer = tr
+	}
+}
+
+// JobQueue tags the job span with the name of the queue the job comes from.
+func JobQueue(name string) JobOption {
+	return func(c *jobConfig) {
+		c.queue = name
+	}
+}
+
+// JobAttempt tags the job span with its attempt, starting at 1, so the
+// retries can be told apart.
+func JobAttempt(n int) JobOption {
+	return func(c *jobConfig) {
+		c.attempt = n
+	}
+}
+
+// JobParent re-parents the job span onto the context encoded by
+// SpanContextToString when the job was enqueued, typically stored with the
+// job payload. It takes precedence over the span of the TraceJob context,
+// and is ignored if it can't be decoded.
+func JobParent(sc string) JobOption {
+	return func(c *jobConfig) {
+		c.parent = sc
+	}
+}
+
+// TraceJob runs fn, a background task, within a consumer span of type queue
+// whose resource is name. The span follows from the JobParent context or
+// from the span of ctx, as the task isn't awaited by the code which scheduled
+// it, or is the root of a new trace when there's none.
+// The span is reachable from the context fn is called with. The error
+// returned by fn is logged on the span and returned, and so are the panics,
+// recovered as errors so a worker pool survives the failing jobs.
+func TraceJob(ctx context.Context, name string, fn func(context.Context) error, opts ...JobOption) (err error) {
+	c := &jobConfig{tracer: opentracing.GlobalTracer()}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	sso := []opentracing.StartSpanOption{
+		ResourceName(name),
+		ext.SpanKindConsumer,
+	}
+	var parent opentracing.SpanContext
+	if c.parent != "" {
+		parent, _ = SpanContextFromString(c.parent)
+	}
+	if parent == nil {
+		if span := SpanFromContext(ctx); span != nil {
+			parent = span.Context()
+		}
+	}
+	if parent != nil {
+		sso = append(sso, opentracing.FollowsFrom(parent))
+	}
+	if c.queue != "" {
+		sso = append(sso, opentracing.Tag{Key: jobQueueKey, Value: c.queue})
+	}
+	if c.attempt > 0 {
+		sso = append(sso, opentracing.Tag{Key: jobAttemptKey, Value: c.attempt})
+	}
+
+	span := c.tracer.StartSpan(jobOperation, sso...)
+	defer span.Finish()
+
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		span.LogFields(
+			log.String("event", errorEvent),
+			log.String(errorMessageKey, fmt.Sprint(r)),
+			log.String(errorKindKey, fmt.Sprintf("panic: %T", r)),
+			log.String(errorLogStack, string(debug.Stack())),
+		)
+		err = fmt.Errorf("job %s panicked: %v", name, r)
+	}()
+
+	if err = fn(ContextWithSpan(ctx, span)); err != nil {
+		span.LogFields(log.Error(err))
+	}
+	return err
+}
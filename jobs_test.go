@@ -0,0 +1,102 @@
+package ddtracer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceJob(t *testing.T) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}))
+
+	var job *Span
+	err := TraceJob(context.Background(), "send-email", func(ctx context.Context) error {
+		job = SpanFromContext(ctx).(*Span)
+		return nil
+	}, JobTracer(tr), JobQueue("emails"), JobAttempt(2))
+	require.NoError(t, err)
+
+	require.NotNil(t, job)
+	assert.Equal(t, "job.run", job.Name)
+	assert.Equal(t, "send-email", job.Resource)
+	assert.Equal(t, uint64(0), job.ParentID)
+	assert.Equal(t, "queue", job.Type)
+	assert.Equal(t, "consumer", job.GetMeta("span.kind"))
+	assert.Equal(t, "emails", job.GetMeta("job.queue"))
+	assert.Equal(t, float64(2), job.Metrics["job.attempt"])
+	assert.True(t, job.finished)
+}
+
+func TestTraceJobParent(t *testing.T) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}))
+
+	t.Run("context", func(t *testing.T) {
+		parent := tr.StartSpan("schedule").(*Span)
+		ctx := ContextWithSpan(context.Background(), parent)
+
+		var job *Span
+		TraceJob(ctx, "cleanup", func(ctx context.Context) error {
+			job = SpanFromContext(ctx).(*Span)
+			return nil
+		}, JobTracer(tr))
+
+		assert.Equal(t, parent.TraceID, job.TraceID)
+		assert.Equal(t, parent.SpanID, job.ParentID)
+		assert.Equal(t, followsFromRefType, job.GetMeta(refTypeTag))
+	})
+
+	t.Run("serialized", func(t *testing.T) {
+		enqueue := tr.StartSpan("enqueue").(*Span)
+		payload, err := SpanContextToString(enqueue.Context())
+		require.NoError(t, err)
+		enqueue.Finish()
+
+		other := tr.StartSpan("other").(*Span)
+		ctx := ContextWithSpan(context.Background(), other)
+
+		var job *Span
+		TraceJob(ctx, "cleanup", func(ctx context.Context) error {
+			job = SpanFromContext(ctx).(*Span)
+			return nil
+		}, JobTracer(tr), JobParent(payload))
+
+		assert.Equal(t, enqueue.TraceID, job.TraceID)
+		assert.Equal(t, enqueue.SpanID, job.ParentID)
+	})
+
+	t.Run("corrupted", func(t *testing.T) {
+		var job *Span
+		TraceJob(context.Background(), "cleanup", func(ctx context.Context) error {
+			job = SpanFromContext(ctx).(*Span)
+			return nil
+		}, JobTracer(tr), JobParent("!nope"))
+
+		assert.Equal(t, uint64(0), job.ParentID)
+	})
+}
+
+func TestTraceJobErrors(t *testing.T) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}))
+
+	var job *Span
+	err := TraceJob(context.Background(), "sync", func(ctx context.Context) error {
+		job = SpanFromContext(ctx).(*Span)
+		return errors.New("timeout")
+	}, JobTracer(tr))
+	assert.EqualError(t, err, "timeout")
+	assert.Equal(t, int32(1), job.Error)
+	assert.Equal(t, "timeout", job.GetMeta(errorMsgKey))
+
+	err = TraceJob(context.Background(), "sync", func(ctx context.Context) error {
+		job = SpanFromContext(ctx).(*Span)
+		panic("boom")
+	}, JobTracer(tr))
+	assert.EqualError(t, err, "job sync panicked: boom")
+	assert.Equal(t, int32(1), job.Error)
+	assert.Equal(t, "boom", job.GetMeta(errorMsgKey))
+	assert.NotEmpty(t, job.GetMeta(errorStackKey))
+	assert.True(t, job.finished)
+}
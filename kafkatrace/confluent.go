@@ -0,0 +1,54 @@
+package kafkatrace
+
+import (
+	"strconv"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// ConfluentHeaders is a TextMap carrier for the headers of a confluent-kafka-go
+// message, i.e. (*ConfluentHeaders)(&msg.Headers).
+type ConfluentHeaders []kafka.Header
+
+// Set replaces the header key, so a message can be injected more than once.
+func (h *ConfluentHeaders) Set(key, val string) {
+	for i := range *h {
+		if (*h)[i].Key == key {
+			(*h)[i].Value = []byte(val)
+			return
+		}
+	}
+	*h = append(*h, kafka.Header{Key: key, Value: []byte(val)})
+}
+
+func (h ConfluentHeaders) ForeachKey(handler func(key, val string) error) error {
+	for _, r := range h {
+		if err := handler(r.Key, string(r.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func topic(msg *kafka.Message) string {
+	if msg.TopicPartition.Topic == nil {
+		return ""
+	}
+	return *msg.TopicPartition.Topic
+}
+
+// InjectConfluentMessage starts a producer span for msg and injects it in its
+// headers, the span has to be finished once the message is delivered.
+func InjectConfluentMessage(tr opentracing.Tracer, msg *kafka.Message, opts ...opentracing.StartSpanOption) (opentracing.Span, error) {
+	span := StartProducerSpan(tr, topic(msg), opts...)
+	return span, InjectKafkaHeaders(tr, span.Context(), (*ConfluentHeaders)(&msg.Headers))
+}
+
+// StartConfluentConsumerSpan starts a consumer span for msg, see StartConsumerSpan.
+func StartConfluentConsumerSpan(tr opentracing.Tracer, msg *kafka.Message, opts ...opentracing.StartSpanOption) opentracing.Span {
+	span := StartConsumerSpan(tr, topic(msg), ConfluentHeaders(msg.Headers), opts...)
+	span.SetTag(TagPartition, strconv.Itoa(int(msg.TopicPartition.Partition)))
+	span.SetTag(TagOffset, strconv.FormatInt(int64(msg.TopicPartition.Offset), 10))
+	return span
+}
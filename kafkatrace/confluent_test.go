@@ -0,0 +1,44 @@
+package kafkatrace
+
+import (
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfluent(t *testing.T) {
+	tr := ddtracer.NewTracer()
+
+	topic := "events"
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 1, Offset: 7},
+		Headers:        []kafka.Header{{Key: "app", Value: []byte("x")}},
+	}
+	producer, err := InjectConfluentMessage(tr, msg)
+	require.NoError(t, err)
+	producer.Finish()
+	assert.Equal(t, "app", msg.Headers[0].Key)
+
+	consumer := StartConfluentConsumerSpan(tr, msg).(*ddtracer.Span)
+	defer consumer.Finish()
+
+	p := producer.(*ddtracer.Span)
+	assert.Equal(t, "Produce Topic events", p.Resource)
+	assert.Equal(t, p.TraceID, consumer.TraceID)
+	assert.Equal(t, p.SpanID, consumer.ParentID)
+	assert.Equal(t, "1", consumer.GetMeta(TagPartition))
+	assert.Equal(t, "7", consumer.GetMeta(TagOffset))
+}
+
+func TestStartConsumerSpanWithoutContext(t *testing.T) {
+	tr := ddtracer.NewTracer()
+
+	span := StartConsumerSpan(tr, "events", ConfluentHeaders(nil)).(*ddtracer.Span)
+	defer span.Finish()
+
+	assert.Equal(t, uint64(0), span.ParentID)
+	assert.Empty(t, span.GetMeta("opentracing.ref_type"))
+}
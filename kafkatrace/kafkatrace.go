@@ -0,0 +1,58 @@
+// Package kafkatrace propagates the spans across Kafka through the message
+// headers of the sarama and confluent-kafka-go clients.
+//
+// The consumer spans follow from the producer ones, as the producer doesn't
+// wait for the messages to be consumed.
+package kafkatrace
+
+import (
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+const (
+	produceOperation = "kafka.produce"
+	consumeOperation = "kafka.consume"
+
+	// TagTopic is the topic of the message.
+	TagTopic = "kafka.topic"
+	// TagPartition is the partition of the consumed message.
+	TagPartition = "kafka.partition"
+	// TagOffset is the offset of the consumed message.
+	TagOffset = "kafka.offset"
+)
+
+// StartProducerSpan starts a span for producing a message to topic,
+// it has to be injected in the message with InjectKafkaHeaders.
+func StartProducerSpan(tr opentracing.Tracer, topic string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	opts = append(opts,
+		ext.SpanKindProducer,
+		ddtracer.SpanType(ddtracer.SpanTypeQueue),
+		opentracing.Tag{Key: TagTopic, Value: topic},
+		opentracing.Tag{Key: string(ext.Component), Value: "Produce Topic " + topic},
+	)
+	return tr.StartSpan(produceOperation, opts...)
+}
+
+// InjectKafkaHeaders injects sc into the message headers of carrier,
+// i.e. InjectKafkaHeaders(tracer, span.Context(), (*SaramaHeaders)(&msg.Headers)).
+func InjectKafkaHeaders(tr opentracing.Tracer, sc opentracing.SpanContext, carrier opentracing.TextMapWriter) error {
+	return tr.Inject(sc, opentracing.TextMap, carrier)
+}
+
+// StartConsumerSpan starts a span for consuming a message of topic, following
+// from the span extracted from the message headers of carrier, if any.
+func StartConsumerSpan(tr opentracing.Tracer, topic string, carrier opentracing.TextMapReader, opts ...opentracing.StartSpanOption) opentracing.Span {
+	if sc, err := tr.Extract(opentracing.TextMap, carrier); err == nil {
+		opts = append(opts, opentracing.FollowsFrom(sc))
+	}
+
+	opts = append(opts,
+		ext.SpanKindConsumer,
+		ddtracer.SpanType(ddtracer.SpanTypeQueue),
+		opentracing.Tag{Key: TagTopic, Value: topic},
+		opentracing.Tag{Key: string(ext.Component), Value: "Consume Topic " + topic},
+	)
+	return tr.StartSpan(consumeOperation, opts...)
+}
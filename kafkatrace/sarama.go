@@ -0,0 +1,62 @@
+package kafkatrace
+
+import (
+	"strconv"
+
+	"github.com/Shopify/sarama"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// SaramaHeaders is a TextMap carrier for the headers of a sarama.ProducerMessage,
+// i.e. (*SaramaHeaders)(&msg.Headers).
+type SaramaHeaders []sarama.RecordHeader
+
+// Set replaces the header key, so a message can be injected more than once.
+func (h *SaramaHeaders) Set(key, val string) {
+	for i := range *h {
+		if string((*h)[i].Key) == key {
+			(*h)[i].Value = []byte(val)
+			return
+		}
+	}
+	*h = append(*h, sarama.RecordHeader{Key: []byte(key), Value: []byte(val)})
+}
+
+func (h SaramaHeaders) ForeachKey(handler func(key, val string) error) error {
+	for _, r := range h {
+		if err := handler(string(r.Key), string(r.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaramaConsumerHeaders is a TextMap reader for the headers of a sarama.ConsumerMessage.
+type SaramaConsumerHeaders []*sarama.RecordHeader
+
+func (h SaramaConsumerHeaders) ForeachKey(handler func(key, val string) error) error {
+	for _, r := range h {
+		if r == nil {
+			continue
+		}
+		if err := handler(string(r.Key), string(r.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InjectSaramaMessage starts a producer span for msg and injects it in its headers,
+// the span has to be finished once the message is sent.
+func InjectSaramaMessage(tr opentracing.Tracer, msg *sarama.ProducerMessage, opts ...opentracing.StartSpanOption) (opentracing.Span, error) {
+	span := StartProducerSpan(tr, msg.Topic, opts...)
+	return span, InjectKafkaHeaders(tr, span.Context(), (*SaramaHeaders)(&msg.Headers))
+}
+
+// StartSaramaConsumerSpan starts a consumer span for msg, see StartConsumerSpan.
+func StartSaramaConsumerSpan(tr opentracing.Tracer, msg *sarama.ConsumerMessage, opts ...opentracing.StartSpanOption) opentracing.Span {
+	span := StartConsumerSpan(tr, msg.Topic, SaramaConsumerHeaders(msg.Headers), opts...)
+	span.SetTag(TagPartition, strconv.Itoa(int(msg.Partition)))
+	span.SetTag(TagOffset, strconv.FormatInt(msg.Offset, 10))
+	return span
+}
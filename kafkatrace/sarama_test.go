@@ -0,0 +1,53 @@
+package kafkatrace
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSarama(t *testing.T) {
+	tr := ddtracer.NewTracer()
+
+	msg := &sarama.ProducerMessage{Topic: "events"}
+	producer, err := InjectSaramaMessage(tr, msg)
+	require.NoError(t, err)
+	require.NoError(t, InjectKafkaHeaders(tr, producer.Context(), (*SaramaHeaders)(&msg.Headers)))
+	producer.Finish()
+
+	keys := map[string]int{}
+	for _, h := range msg.Headers {
+		keys[string(h.Key)]++
+	}
+	assert.Equal(t, 1, keys["x-datadog-trace-id"])
+
+	headers := make([]*sarama.RecordHeader, len(msg.Headers))
+	for i := range msg.Headers {
+		headers[i] = &msg.Headers[i]
+	}
+	consumer := StartSaramaConsumerSpan(tr, &sarama.ConsumerMessage{
+		Topic:     "events",
+		Partition: 3,
+		Offset:    42,
+		Headers:   headers,
+	}).(*ddtracer.Span)
+	defer consumer.Finish()
+
+	p := producer.(*ddtracer.Span)
+	assert.Equal(t, "kafka.produce", p.Name)
+	assert.Equal(t, "Produce Topic events", p.Resource)
+	assert.Equal(t, "queue", p.Type)
+	assert.Equal(t, "producer", p.GetMeta("span.kind"))
+
+	assert.Equal(t, "kafka.consume", consumer.Name)
+	assert.Equal(t, "Consume Topic events", consumer.Resource)
+	assert.Equal(t, "consumer", consumer.GetMeta("span.kind"))
+	assert.Equal(t, "follows_from", consumer.GetMeta("opentracing.ref_type"))
+	assert.Equal(t, "3", consumer.GetMeta(TagPartition))
+	assert.Equal(t, "42", consumer.GetMeta(TagOffset))
+	assert.Equal(t, "events", consumer.GetMeta(TagTopic))
+	assert.Equal(t, p.TraceID, consumer.TraceID)
+}
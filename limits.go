@@ -0,0 +1,125 @@
+package ddtracer
+
+import (
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+const (
+	// truncatedMarker is appended to the meta values truncated by
+	// WithMaxTagValueLength.
+	truncatedMarker = "...(truncated)"
+
+	// droppedTagsKey and droppedLogsKey are the metrics counting the tags
+	// and log records dropped by WithMaxTags and WithMaxLogRecords.
+	droppedTagsKey = "_dd.dropped_tags"
+	droppedLogsKey = "_dd.dropped_logs"
+
+	// intakeMaxValueLength and intakeMaxResourceLength are the lengths
+	// beyond which the intake truncates the meta values and the resources,
+	// or even rejects the spans.
+	intakeMaxValueLength    = 25000
+	intakeMaxResourceLength = 5000
+)
+
+// limits bound the tags and log records of the spans, zero being unlimited.
+type limits struct {
+	maxTags           int
+	maxValueLength    int
+	maxResourceLength int
+	maxLogRecords     int
+}
+
+// defaultLimits enforce the intake limits.
+var defaultLimits = limits{
+	maxValueLength:    intakeMaxValueLength,
+	maxResourceLength: intakeMaxResourceLength,
+}
+
+// setMeta sets the meta sanitized and within the limits of the Tracer.
+// The span must be locked.
+func (s *Span) setMeta(key, value string) {
+	if s.tr != nil {
+		if sanitize := s.tr.sanitizer; sanitize != nil {
+			var ok bool
+			if value, ok = sanitize(key, value); !ok {
+				return
+			}
+		}
+		if !s.tagFits(key) {
+			return
+		}
+		value = s.truncate(value, s.tr.limits.maxValueLength)
+	}
+	s.Span.SetMeta(key, value)
+}
+
+// truncate truncates value to max bytes, counting the truncations.
+func (s *Span) truncate(value string, max int) string {
+	if max <= 0 || len(value) <= max {
+		return value
+	}
+	atomic.AddUint64(&s.tr.stats.truncations, 1)
+	return truncate(value, max)
+}
+
+// setMetric sets the metric within the limits of the Tracer. The span must be locked.
+func (s *Span) setMetric(key string, value float64) {
+	if s.tr != nil && !s.tagFits(key) {
+		return
+	}
+	s.Span.SetMetric(key, value)
+}
+
+// tagFits reports whether key can be set without exceeding the maximum
+// number of tags, counting the dropped ones otherwise.
+func (s *Span) tagFits(key string) bool {
+	max := s.tr.limits.maxTags
+	if max <= 0 {
+		return true
+	}
+	if _, ok := s.Meta[key]; ok {
+		return true
+	}
+	if _, ok := s.Metrics[key]; ok {
+		return true
+	}
+	if len(s.Meta)+len(s.Metrics) < max {
+		return true
+	}
+
+	s.Span.SetMetric(droppedTagsKey, s.Metrics[droppedTagsKey]+1)
+	return false
+}
+
+// logFits reports whether one more log record can be kept, counting the
+// dropped ones otherwise.
+func (s *Span) logFits() bool {
+	max := s.tr.limits.maxLogRecords
+	s.logsMu.Lock()
+	logs := s.logs
+	s.logsMu.Unlock()
+	if max <= 0 || logs < max {
+		return true
+	}
+
+	s.Span.SetMetric(droppedLogsKey, s.Metrics[droppedLogsKey]+1)
+	return false
+}
+
+// truncate shortens value to max bytes, marker included, without splitting
+// any UTF-8 character. Zero max means unlimited.
+func truncate(value string, max int) string {
+	if max <= 0 || len(value) <= max {
+		return value
+	}
+
+	n := max - len(truncatedMarker)
+	if n < 0 {
+		n = 0
+	}
+	for n > 0 && !utf8.RuneStart(value[n]) {
+		n--
+	}
+	return value[:n] + truncatedMarker
+}
@@ -0,0 +1,79 @@
+package ddtracer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpanLimits(t *testing.T) {
+	t.Run("Max tags", func(t *testing.T) {
+		span := NewTracerWithOptions(WithMaxTags(3)).StartSpan("test").(*Span)
+		span.SetTag("a", "1")
+		span.SetTag("b", 2)
+		span.SetTag("c", "3")
+		span.SetTag("d", 4)
+		span.SetTag("a", "updated")
+
+		assert.Equal(t, "updated", span.GetMeta("a"))
+		assert.Equal(t, float64(2), span.Metrics["b"])
+		assert.Equal(t, "3", span.GetMeta("c"))
+		assert.NotContains(t, span.Metrics, "d")
+		assert.Equal(t, float64(1), span.Metrics[droppedTagsKey])
+	})
+
+	t.Run("Max value length", func(t *testing.T) {
+		span := NewTracerWithOptions(WithMaxTagValueLength(20)).StartSpan("test").(*Span)
+		span.SetTag("short", "value")
+		span.SetTag("long", strings.Repeat("x", 1<<20))
+
+		assert.Equal(t, "value", span.GetMeta("short"))
+		assert.Equal(t, "xxxxxx"+truncatedMarker, span.GetMeta("long"))
+		assert.Equal(t, uint64(1), span.tr.Stats().Truncations)
+	})
+
+	t.Run("Intake limits", func(t *testing.T) {
+		tr := NewTracerWithOptions(WithTransport(discardTransport{})).(*Tracer)
+		span := tr.StartSpan("test").(*Span)
+		span.SetTag("long", strings.Repeat("x", 1<<20))
+		span.SetTag("resource.name", strings.Repeat("y", 1<<20))
+		span.Finish()
+
+		assert.Len(t, span.GetMeta("long"), intakeMaxValueLength)
+		assert.Len(t, span.Resource, intakeMaxResourceLength)
+		assert.True(t, strings.HasSuffix(span.Resource, truncatedMarker))
+		assert.Equal(t, uint64(2), tr.Stats().Truncations)
+	})
+
+	t.Run("Max resource length", func(t *testing.T) {
+		tr := NewTracerWithOptions(WithTransport(discardTransport{}), WithMaxResourceLength(0)).(*Tracer)
+		span := tr.StartSpan("test").(*Span)
+		span.Resource = strings.Repeat("y", 1<<20)
+		span.Finish()
+
+		assert.Len(t, span.Resource, 1<<20)
+		assert.Equal(t, uint64(0), tr.Stats().Truncations)
+	})
+
+	t.Run("Max log records", func(t *testing.T) {
+		span := NewTracerWithOptions(WithMaxLogRecords(1)).StartSpan("test").(*Span)
+		span.LogFields(log.String("event", "first"))
+		span.LogFields(log.String("event", "second"))
+		span.LogFields(log.String("event", "third"))
+
+		assert.Contains(t, span.GetMeta("log.0"), "first")
+		assert.Empty(t, span.GetMeta("log.1"))
+		assert.Equal(t, "third", span.GetMeta("event"))
+		assert.Equal(t, float64(2), span.Metrics[droppedLogsKey])
+	})
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "abc", truncate("abc", 0))
+	assert.Equal(t, "abc", truncate("abc", 3))
+	assert.Equal(t, truncatedMarker, truncate("abcdef", 2))
+	// "é" is 2 bytes, it's not split.
+	assert.Equal(t, "a"+truncatedMarker, truncate("aé"+strings.Repeat("b", 20), len(truncatedMarker)+2))
+}
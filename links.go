@@ -0,0 +1,87 @@
+package ddtracer
+
+import (
+	"encoding/json"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+const (
+	// spanLinksKey is the meta holding the JSON encoded span links.
+	spanLinksKey = "_dd.span_links"
+
+	// linksKey is the tag of WithSpanLinks.
+	linksKey = "span.links"
+)
+
+// spanLink points to a span other than the parent of a span, i.e. the
+// producers of the messages aggregated by a batch consumer.
+type spanLink struct {
+	TraceID     uint64            `json:"trace_id"`
+	TraceIDHigh uint64            `json:"trace_id_high,omitempty"`
+	SpanID      uint64            `json:"span_id"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+}
+
+// setSpanLinks records the references of span but its parent as span links,
+// which would be dropped otherwise as a span only has one parent.
+func setSpanLinks(span *tracer.Span, refs []opentracing.SpanReference, parent *SpanContext) {
+	var links []spanLink
+	for _, ref := range refs {
+		p, ok := ref.ReferencedContext.(*SpanContext)
+		if !ok || p == parent || p.traceID == 0 {
+			continue
+		}
+
+		refType := "child_of"
+		if ref.Type == opentracing.FollowsFromRef {
+			refType = followsFromRefType
+		}
+		links = append(links, spanLink{
+			TraceID:     p.traceID,
+			TraceIDHigh: p.traceIDHigh,
+			SpanID:      p.spanID,
+			Attributes:  map[string]string{refTypeTag: refType},
+		})
+	}
+	appendSpanLinks(span, links)
+}
+
+// appendSpanLinks adds links to the ones of span.
+func appendSpanLinks(span *tracer.Span, links []spanLink) {
+	if len(links) == 0 {
+		return
+	}
+	if meta, ok := span.Meta[spanLinksKey]; ok {
+		var existing []spanLink
+		if err := json.Unmarshal([]byte(meta), &existing); err == nil {
+			links = append(existing, links...)
+		}
+	}
+
+	if b, err := json.Marshal(links); err == nil {
+		span.SetMeta(spanLinksKey, string(b))
+	}
+}
+
+// WithSpanLinks returns a StartSpanOption linking the span to the spans of
+// ctxs, without making any of them its parent as a FollowsFrom reference
+// does, i.e. for a new root span related to other traces.
+func WithSpanLinks(ctxs ...opentracing.SpanContext) opentracing.StartSpanOption {
+	return opentracing.Tag{Key: linksKey, Value: ctxs}
+}
+
+func mapSpanLinks(span *Span, value interface{}) {
+	ctxs, _ := value.([]opentracing.SpanContext)
+
+	var links []spanLink
+	for _, ctx := range ctxs {
+		sc, ok := ctx.(*SpanContext)
+		if !ok || sc.traceID == 0 {
+			continue
+		}
+		links = append(links, spanLink{TraceID: sc.traceID, TraceIDHigh: sc.traceIDHigh, SpanID: sc.spanID})
+	}
+	appendSpanLinks(span.Span, links)
+}
@@ -0,0 +1,73 @@
+package ddtracer
+
+import (
+	"encoding/json"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpanLinks(t *testing.T) {
+	tr := NewTracer()
+
+	parent := tr.StartSpan("parent").(*Span)
+	producer1 := tr.StartSpan("produce").(*Span)
+	producer2 := tr.StartSpan("produce").(*Span)
+
+	span := tr.StartSpan("consume",
+		opentracing.FollowsFrom(producer1.Context()),
+		opentracing.ChildOf(parent.Context()),
+		opentracing.FollowsFrom(producer2.Context()),
+	).(*Span)
+	assert.Equal(t, parent.TraceID, span.TraceID)
+	assert.Equal(t, parent.SpanID, span.ParentID)
+
+	var links []spanLink
+	require.NoError(t, json.Unmarshal([]byte(span.GetMeta(spanLinksKey)), &links))
+	assert.Equal(t, []spanLink{
+		{TraceID: producer1.TraceID, SpanID: producer1.SpanID, Attributes: map[string]string{refTypeTag: "follows_from"}},
+		{TraceID: producer2.TraceID, SpanID: producer2.SpanID, Attributes: map[string]string{refTypeTag: "follows_from"}},
+	}, links)
+
+	t.Run("Single reference", func(t *testing.T) {
+		span := tr.StartSpan("child", opentracing.ChildOf(parent.Context())).(*Span)
+		assert.Empty(t, span.GetMeta(spanLinksKey))
+	})
+}
+
+func TestWithSpanLinks(t *testing.T) {
+	tr := NewTracer()
+
+	parent := tr.StartSpan("parent").(*Span)
+	producer1 := tr.StartSpan("produce").(*Span)
+	producer2 := tr.StartSpan("produce").(*Span)
+
+	root := tr.StartSpan("consume", WithSpanLinks(producer1.Context(), producer2.Context())).(*Span)
+	assert.Zero(t, root.ParentID)
+	assert.NotEqual(t, producer1.TraceID, root.TraceID)
+
+	var links []spanLink
+	require.NoError(t, json.Unmarshal([]byte(root.GetMeta(spanLinksKey)), &links))
+	assert.Equal(t, []spanLink{
+		{TraceID: producer1.TraceID, SpanID: producer1.SpanID},
+		{TraceID: producer2.TraceID, SpanID: producer2.SpanID},
+	}, links)
+
+	t.Run("References", func(t *testing.T) {
+		span := tr.StartSpan("consume",
+			opentracing.ChildOf(parent.Context()),
+			opentracing.FollowsFrom(producer1.Context()),
+			WithSpanLinks(producer2.Context()),
+		).(*Span)
+		assert.Equal(t, parent.SpanID, span.ParentID)
+
+		var links []spanLink
+		require.NoError(t, json.Unmarshal([]byte(span.GetMeta(spanLinksKey)), &links))
+		assert.Equal(t, []spanLink{
+			{TraceID: producer1.TraceID, SpanID: producer1.SpanID, Attributes: map[string]string{refTypeTag: "follows_from"}},
+			{TraceID: producer2.TraceID, SpanID: producer2.SpanID},
+		}, links)
+	})
+}
@@ -0,0 +1,54 @@
+package ddtracer
+
+import (
+	"fmt"
+	stdlog "log"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// Logger reports the errors the Tracer can't return, such as failed flushes,
+// corrupted propagated contexts or dropped spans. See WithLogger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// NopLogger discards everything.
+var NopLogger Logger = nopLogger{}
+
+type nopLogger struct{}
+
+func (nopLogger) Printf(format string, v ...interface{}) {}
+
+func formatName(format interface{}) string {
+	switch format {
+	case opentracing.Binary:
+		return "Binary"
+	case opentracing.TextMap:
+		return "TextMap"
+	case opentracing.HTTPHeaders:
+		return "HTTPHeaders"
+	}
+	return fmt.Sprint(format)
+}
+
+// StdLogger returns a Logger writing to l, or to the standard logger when nil.
+// The messages are prefixed by "ddtracer: ".
+func StdLogger(l *stdlog.Logger) Logger {
+	if l == nil {
+		return stdLogger{}
+	}
+	return stdLogger{l}
+}
+
+type stdLogger struct {
+	l *stdlog.Logger
+}
+
+func (s stdLogger) Printf(format string, v ...interface{}) {
+	if s.l == nil {
+		stdlog.Printf("ddtracer: "+format, v...)
+		return
+	}
+	s.l.Printf("ddtracer: "+format, v...)
+}
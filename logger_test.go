@@ -0,0 +1,62 @@
+package ddtracer
+
+import (
+	"bytes"
+	"fmt"
+	stdlog "log"
+	"net/http/httptest"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingLogger struct {
+	lines []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestLogger(t *testing.T) {
+	ts := httptest.NewServer(nil)
+	ts.Close()
+
+	l := &recordingLogger{}
+	tr := NewTracerWithOptions(WithLogger(l), WithAgentAddr(ts.Listener.Addr().String())).(*Tracer)
+
+	span := tr.StartSpan("op")
+	span.LogKV("key")
+	require.Len(t, l.lines, 1)
+	assert.Contains(t, l.lines[0], `span "op": dropping LogKV`)
+
+	_, err := tr.Extract(opentracing.TextMap, opentracing.TextMapCarrier{fieldDatadogTraceID: "nope"})
+	assert.Equal(t, opentracing.ErrSpanContextCorrupted, err)
+	require.Len(t, l.lines, 2)
+
+	assert.Contains(t, l.lines[1], "extract TextMap")
+
+	_, err = tr.Extract(opentracing.Binary, &bytes.Buffer{})
+	assert.Equal(t, opentracing.ErrSpanContextNotFound, err)
+	assert.Len(t, l.lines, 2)
+
+	span.Finish()
+	assert.Error(t, tr.FlushTraces())
+	require.Len(t, l.lines, 3)
+	assert.Contains(t, l.lines[2], "flushing 1 traces")
+
+	tr.SetEnabled(false)
+	tr.StartSpan("disabled").Finish()
+	require.Len(t, l.lines, 4)
+	assert.Equal(t, `span "disabled": dropped, the tracer is disabled`, l.lines[3])
+}
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	StdLogger(stdlog.New(&buf, "", 0)).Printf("hello %s", "world")
+	assert.Equal(t, "ddtracer: hello world\n", buf.String())
+
+	NopLogger.Printf("nothing")
+}
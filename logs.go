@@ -0,0 +1,91 @@
+package ddtracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/opentracing/opentracing-go/log"
+)
+
+const (
+	// logRecordPrefix is the meta key prefix under which every log record is
+	// stored, suffixed by its index (i.e. log.0, log.1, ...).
+	logRecordPrefix  = "log."
+	logRecordTimeKey = "timestamp"
+	logRecordTimeFmt = time.RFC3339Nano
+)
+
+// recordLog stores fields as a timestamped JSON entry, so repeated keys
+// across log records are preserved.
+func (s *Span) recordLog(ts time.Time, fields []log.Field) {
+	if len(fields) == 0 || (s.tr != nil && !s.logFits()) {
+		return
+	}
+
+	enc := logRecordEncoder{
+		logRecordTimeKey: ts.UTC().Format(logRecordTimeFmt),
+	}
+	for _, field := range fields {
+		field.Marshal(enc)
+	}
+	if s.tr != nil && s.tr.sanitizer != nil {
+		enc.sanitize(s.tr.sanitizer)
+	}
+
+	buf, err := json.Marshal(enc)
+	if err != nil {
+		return
+	}
+
+	s.logsMu.Lock()
+	key := logRecordPrefix + strconv.Itoa(s.logs)
+	s.logs++
+	s.logsMu.Unlock()
+
+	s.setMeta(key, string(buf))
+}
+
+// logRecordEncoder implements log.Encoder, keeping the values JSON friendly.
+type logRecordEncoder map[string]interface{}
+
+// sanitize applies sanitize to the string values of the record.
+func (e logRecordEncoder) sanitize(sanitize TagSanitizer) {
+	for k, v := range e {
+		str, ok := v.(string)
+		if !ok || k == logRecordTimeKey {
+			continue
+		}
+		if str, ok = sanitize(k, str); ok {
+			e[k] = str
+		} else {
+			delete(e, k)
+		}
+	}
+}
+
+func (e logRecordEncoder) EmitString(key, value string)             { e[key] = value }
+func (e logRecordEncoder) EmitBool(key string, value bool)          { e[key] = value }
+func (e logRecordEncoder) EmitInt(key string, value int)            { e[key] = value }
+func (e logRecordEncoder) EmitInt32(key string, value int32)        { e[key] = value }
+func (e logRecordEncoder) EmitInt64(key string, value int64)        { e[key] = value }
+func (e logRecordEncoder) EmitUint32(key string, value uint32)      { e[key] = value }
+func (e logRecordEncoder) EmitUint64(key string, value uint64)      { e[key] = value }
+func (e logRecordEncoder) EmitFloat32(key string, value float32)    { e[key] = value }
+func (e logRecordEncoder) EmitFloat64(key string, value float64)    { e[key] = value }
+func (e logRecordEncoder) EmitLazyLogger(value log.LazyLogger)      { value(e) }
+func (e logRecordEncoder) EmitObject(key string, value interface{}) { e[key] = encodePayload(value) }
+
+func (e logRecordEncoder) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(e))
+	for k, v := range e {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		} else if s, ok := v.(fmt.Stringer); ok {
+			v = s.String()
+		}
+		m[k] = v
+	}
+	return json.Marshal(m)
+}
@@ -0,0 +1,48 @@
+package ddtracer
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpanLogRecords(t *testing.T) {
+	span := NewTracer().StartSpan("test").(*Span)
+	span.LogFields(log.String("event", "first"), log.Int("n", 1))
+	span.LogFields(log.String("event", "second"), log.Error(errors.New("boom")))
+
+	var first, second map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(span.GetMeta("log.0")), &first))
+	require.NoError(t, json.Unmarshal([]byte(span.GetMeta("log.1")), &second))
+
+	assert.Equal(t, "first", first["event"])
+	assert.Equal(t, float64(1), first["n"])
+	assert.Equal(t, "second", second["event"])
+	assert.Equal(t, "boom", second["error"])
+
+	for _, record := range []map[string]interface{}{first, second} {
+		_, err := time.Parse(time.RFC3339Nano, record["timestamp"].(string))
+		assert.NoError(t, err)
+	}
+
+	t.Run("FinishWithOptions keeps the records timestamp", func(t *testing.T) {
+		ts := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+		span := NewTracer().StartSpan("test").(*Span)
+		span.FinishWithOptions(opentracing.FinishOptions{
+			LogRecords: []opentracing.LogRecord{
+				{Timestamp: ts, Fields: []log.Field{log.String("event", "buffered")}},
+			},
+		})
+
+		assert.JSONEq(t,
+			`{"event":"buffered","timestamp":"2017-01-01T00:00:00Z"}`,
+			span.GetMeta("log.0"),
+		)
+	})
+}
@@ -0,0 +1,23 @@
+package logtrace
+
+import "github.com/sirupsen/logrus"
+
+// LogrusHook adds the correlation fields of the span in the entry's context
+// to the entries logged with WithContext, i.e. logger.AddHook(LogrusHook{}).
+type LogrusHook struct{}
+
+// Levels implements logrus.Hook, firing at every level.
+func (LogrusHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook.
+func (LogrusHook) Fire(e *logrus.Entry) error {
+	for _, f := range fields(e.Context) {
+		if e.Data == nil {
+			e.Data = logrus.Fields{}
+		}
+		e.Data[f.Key()] = f.Value()
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package logtrace
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogrusHook(t *testing.T) {
+	traceID, spanID, ctx := startSpan()
+
+	e := &logrus.Entry{Context: ctx}
+	require.NoError(t, LogrusHook{}.Fire(e))
+	assert.Equal(t, traceID, e.Data["dd.trace_id"])
+	assert.Equal(t, spanID, e.Data["dd.span_id"])
+
+	e = &logrus.Entry{Context: context.Background(), Data: logrus.Fields{}}
+	require.NoError(t, LogrusHook{}.Fire(e))
+	assert.Empty(t, e.Data)
+
+	require.NoError(t, LogrusHook{}.Fire(&logrus.Entry{}))
+}
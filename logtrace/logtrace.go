@@ -0,0 +1,18 @@
+// Package logtrace adds the dd.trace_id and dd.span_id fields of the span in
+// the context to the logrus and zap loggers, correlating logs and traces.
+package logtrace
+
+import (
+	"context"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// fields returns the correlation fields of ctx, which might be nil.
+func fields(ctx context.Context) []log.Field {
+	if ctx == nil {
+		return nil
+	}
+	return ddtracer.LogCorrelationFields(ctx)
+}
@@ -0,0 +1,24 @@
+package logtrace
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	"github.com/stretchr/testify/assert"
+)
+
+// startSpan returns the trace and span IDs of a new span, and a context holding it.
+func startSpan() (string, string, context.Context) {
+	span := ddtracer.NewTracer().StartSpan("test").(*ddtracer.Span)
+	ctx := ddtracer.ContextWithSpan(context.Background(), span)
+	return strconv.FormatUint(span.TraceID, 10), strconv.FormatUint(span.SpanID, 10), ctx
+}
+
+func TestFields(t *testing.T) {
+	_, _, ctx := startSpan()
+	assert.Len(t, fields(ctx), 2)
+	assert.Empty(t, fields(context.Background()))
+	assert.Empty(t, fields(nil))
+}
@@ -0,0 +1,18 @@
+package logtrace
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// ZapFields returns the correlation fields of the span in ctx, or nil if
+// there's none, i.e. logger.With(ZapFields(ctx)...).Info("msg").
+func ZapFields(ctx context.Context) []zap.Field {
+	var zf []zap.Field
+	for _, f := range fields(ctx) {
+		zf = append(zf, zap.String(f.Key(), fmt.Sprint(f.Value())))
+	}
+	return zf
+}
@@ -0,0 +1,19 @@
+package logtrace
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestZapFields(t *testing.T) {
+	traceID, spanID, ctx := startSpan()
+
+	assert.Equal(t, []zap.Field{
+		zap.String("dd.trace_id", traceID),
+		zap.String("dd.span_id", spanID),
+	}, ZapFields(ctx))
+	assert.Nil(t, ZapFields(context.Background()))
+}
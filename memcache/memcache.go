@@ -0,0 +1,153 @@
+// Package memcache traces the gomemcache client with cache spans, child of
+// the span in the context.
+package memcache
+
+import (
+	"context"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+const (
+	defaultService = "memcached"
+	operation      = "memcached.query"
+)
+
+// Option configures the Client.
+type Option func(*Client)
+
+// ServiceName sets the service of the spans, "memcached" by default.
+func ServiceName(name string) Option {
+	return func(c *Client) {
+		c.service = name
+	}
+}
+
+// Client is a gomemcache client tracing its commands.
+type Client struct {
+	*memcache.Client
+	tr      opentracing.Tracer
+	service string
+	ctx     context.Context
+}
+
+// WrapClient returns c tracing its commands, use WithContext to set their parent.
+func WrapClient(c *memcache.Client, tr opentracing.Tracer, opts ...Option) *Client {
+	tc := &Client{
+		Client:  c,
+		tr:      tr,
+		service: defaultService,
+		ctx:     context.Background(),
+	}
+	for _, opt := range opts {
+		opt(tc)
+	}
+	return tc
+}
+
+// WithContext returns a copy of c whose spans are children of the span in ctx.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	cc := *c
+	cc.ctx = ctx
+	return &cc
+}
+
+func (c *Client) start(command string) opentracing.Span {
+	span := c.tr.StartSpan(operation, ddtracer.ChildOfContext(c.ctx), ext.SpanKindRPCClient)
+	ext.DBType.Set(span, "memcached")
+	ext.PeerService.Set(span, c.service)
+	ext.Component.Set(span, command)
+	return span
+}
+
+// finish finishes span, marking it as an error unless err is nil or a cache miss.
+func finish(span opentracing.Span, err error) {
+	if err != nil && err != memcache.ErrCacheMiss {
+		span.LogFields(log.Error(err))
+	}
+	span.Finish()
+}
+
+// Get traces memcache.Client.Get.
+func (c *Client) Get(key string) (*memcache.Item, error) {
+	span := c.start("Get")
+	item, err := c.Client.Get(key)
+	finish(span, err)
+	return item, err
+}
+
+// GetMulti traces memcache.Client.GetMulti.
+func (c *Client) GetMulti(keys []string) (map[string]*memcache.Item, error) {
+	span := c.start("GetMulti")
+	items, err := c.Client.GetMulti(keys)
+	finish(span, err)
+	return items, err
+}
+
+// Set traces memcache.Client.Set.
+func (c *Client) Set(item *memcache.Item) error {
+	span := c.start("Set")
+	err := c.Client.Set(item)
+	finish(span, err)
+	return err
+}
+
+// Add traces memcache.Client.Add.
+func (c *Client) Add(item *memcache.Item) error {
+	span := c.start("Add")
+	err := c.Client.Add(item)
+	finish(span, err)
+	return err
+}
+
+// Replace traces memcache.Client.Replace.
+func (c *Client) Replace(item *memcache.Item) error {
+	span := c.start("Replace")
+	err := c.Client.Replace(item)
+	finish(span, err)
+	return err
+}
+
+// CompareAndSwap traces memcache.Client.CompareAndSwap.
+func (c *Client) CompareAndSwap(item *memcache.Item) error {
+	span := c.start("CompareAndSwap")
+	err := c.Client.CompareAndSwap(item)
+	finish(span, err)
+	return err
+}
+
+// Delete traces memcache.Client.Delete.
+func (c *Client) Delete(key string) error {
+	span := c.start("Delete")
+	err := c.Client.Delete(key)
+	finish(span, err)
+	return err
+}
+
+// Increment traces memcache.Client.Increment.
+func (c *Client) Increment(key string, delta uint64) (uint64, error) {
+	span := c.start("Increment")
+	v, err := c.Client.Increment(key, delta)
+	finish(span, err)
+	return v, err
+}
+
+// Decrement traces memcache.Client.Decrement.
+func (c *Client) Decrement(key string, delta uint64) (uint64, error) {
+	span := c.start("Decrement")
+	v, err := c.Client.Decrement(key, delta)
+	finish(span, err)
+	return v, err
+}
+
+// Touch traces memcache.Client.Touch.
+func (c *Client) Touch(key string, seconds int32) error {
+	span := c.start("Touch")
+	err := c.Client.Touch(key, seconds)
+	finish(span, err)
+	return err
+}
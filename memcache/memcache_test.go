@@ -0,0 +1,45 @@
+package memcache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTracer keeps the spans it starts.
+type recordingTracer struct {
+	opentracing.Tracer
+	spans []*ddtracer.Span
+}
+
+func (t *recordingTracer) StartSpan(op string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	span := t.Tracer.StartSpan(op, opts...)
+	t.spans = append(t.spans, span.(*ddtracer.Span))
+	return span
+}
+
+func TestClient(t *testing.T) {
+	tr := &recordingTracer{Tracer: ddtracer.NewTracer()}
+	parent := tr.StartSpan("parent").(*ddtracer.Span)
+	ctx := ddtracer.ContextWithSpan(context.Background(), parent)
+
+	c := WrapClient(memcache.New("127.0.0.1:1"), tr, ServiceName("cache")).WithContext(ctx)
+	c.Get("key")
+	c.Delete("key")
+
+	require.Len(t, tr.spans, 3)
+	for i, resource := range []string{"Get", "Delete"} {
+		span := tr.spans[i+1]
+		assert.Equal(t, "memcached.query", span.Name)
+		assert.Equal(t, resource, span.Resource)
+		assert.Equal(t, "cache", span.Type)
+		assert.Equal(t, "cache", span.Service)
+		assert.Equal(t, parent.TraceID, span.TraceID)
+		assert.Equal(t, parent.SpanID, span.ParentID)
+	}
+}
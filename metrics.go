@@ -0,0 +1,135 @@
+package ddtracer
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+)
+
+// Stats are the counters of a Tracer, to monitor the health of the trace pipeline.
+type Stats struct {
+	// SpansStarted and SpansFinished count the spans started and finished.
+	SpansStarted  uint64
+	SpansFinished uint64
+
+	// SpansDropped counts the finished spans not sent to the agent, as they
+	// were not sampled or filtered, the Tracer was disabled or its queue was
+	// full.
+	SpansDropped uint64
+
+	// SpansLimited counts the spans not started because of WithSpanRateLimit.
+	SpansLimited uint64
+
+	// Truncations counts the meta values and resources truncated to fit
+	// the limits, see WithMaxTagValueLength and WithMaxResourceLength.
+	Truncations uint64
+
+	// Flushes counts the attempts to send the traces to the agent, and
+	// FlushErrors the failed ones.
+	Flushes     uint64
+	FlushErrors uint64
+
+	// FlushLatency is the duration of the last flush.
+	FlushLatency time.Duration
+
+	// Durations are the histograms of the durations of the finished spans
+	// per operation name, nil unless enabled by WithDurationHistograms.
+	Durations map[string]DurationHistogram
+}
+
+type stats struct {
+	spansStarted  uint64
+	spansFinished uint64
+	spansDropped  uint64
+	spansLimited  uint64
+	truncations   uint64
+	flushes       uint64
+	flushErrors   uint64
+	flushLatency  int64
+
+	// lastFlush and lastFlushErr are the time and the error of the last
+	// flush, see Tracer.DebugHandler.
+	lastFlushMu  sync.Mutex
+	lastFlush    time.Time
+	lastFlushErr error
+}
+
+// Stats returns a snapshot of the Tracer counters.
+func (t *Tracer) Stats() Stats {
+	s := Stats{
+		SpansStarted:  atomic.LoadUint64(&t.stats.spansStarted),
+		SpansFinished: atomic.LoadUint64(&t.stats.spansFinished),
+		SpansDropped:  atomic.LoadUint64(&t.stats.spansDropped),
+		SpansLimited:  atomic.LoadUint64(&t.stats.spansLimited),
+		Truncations:   atomic.LoadUint64(&t.stats.truncations),
+		Flushes:       atomic.LoadUint64(&t.stats.flushes),
+		FlushErrors:   atomic.LoadUint64(&t.stats.flushErrors),
+		FlushLatency:  time.Duration(atomic.LoadInt64(&t.stats.flushLatency)),
+	}
+	if t.durations != nil {
+		s.Durations = t.durations.snapshot()
+	}
+	return s
+}
+
+// PublishExpvar publishes the Tracer Stats as the expvar name, i.e. at /debug/vars.
+// Like expvar.Publish, it panics if name is already registered.
+func (t *Tracer) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return t.Stats()
+	}))
+}
+
+// MetricsHandler returns an http.Handler exposing the Tracer Stats in the
+// Prometheus text format, to be scraped by Prometheus compatible agents.
+func (t *Tracer) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := t.Stats()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		for _, m := range []struct {
+			name, typ, help string
+			value           interface{}
+		}{
+			{"ddtracer_spans_started_total", "counter", "Spans started.", s.SpansStarted},
+			{"ddtracer_spans_finished_total", "counter", "Spans finished.", s.SpansFinished},
+			{"ddtracer_spans_dropped_total", "counter", "Finished spans not sent to the agent.", s.SpansDropped},
+			{"ddtracer_spans_limited_total", "counter", "Spans not started because of the rate limit.", s.SpansLimited},
+			{"ddtracer_truncations_total", "counter", "Meta values and resources truncated to fit the limits.", s.Truncations},
+			{"ddtracer_flushes_total", "counter", "Attempts to send the traces to the agent.", s.Flushes},
+			{"ddtracer_flush_errors_total", "counter", "Failed attempts to send the traces to the agent.", s.FlushErrors},
+			{"ddtracer_flush_latency_seconds", "gauge", "Duration of the last flush.", s.FlushLatency.Seconds()},
+		} {
+			fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", m.name, m.help, m.name, m.typ, m.name, m.value)
+		}
+	})
+}
+
+// statsTransport counts the traces sent by the driver, including the ones
+// flushed in the background.
+type statsTransport struct {
+	tracer.Transport
+	stats  *stats
+	logger Logger
+}
+
+func (t *statsTransport) SendTraces(traces [][]*tracer.Span) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.Transport.SendTraces(traces)
+
+	atomic.StoreInt64(&t.stats.flushLatency, int64(time.Since(start)))
+	t.stats.lastFlushMu.Lock()
+	t.stats.lastFlush, t.stats.lastFlushErr = start, err
+	t.stats.lastFlushMu.Unlock()
+	atomic.AddUint64(&t.stats.flushes, 1)
+	if err != nil {
+		atomic.AddUint64(&t.stats.flushErrors, 1)
+		t.logger.Printf("flushing %d traces: %v", len(traces), err)
+	}
+	return resp, err
+}
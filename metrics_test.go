@@ -0,0 +1,69 @@
+package ddtracer
+
+import (
+	"expvar"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	env := newEnv(t)
+	defer env.close()
+	tr := env.tr.(*Tracer)
+
+	kept := tr.StartSpan("kept").(*Span)
+	dropped := tr.StartSpan("dropped").(*Span)
+	dropped.Sampled = false
+	tr.StartSpan("unfinished")
+
+	kept.Finish()
+	kept.Finish()
+	dropped.Finish()
+	require.NoError(t, tr.FlushTraces())
+
+	s := tr.Stats()
+	assert.Equal(t, uint64(3), s.SpansStarted)
+	assert.Equal(t, uint64(2), s.SpansFinished)
+	assert.Equal(t, uint64(1), s.SpansDropped)
+	assert.Equal(t, uint64(1), s.Flushes)
+	assert.Equal(t, uint64(0), s.FlushErrors)
+	assert.True(t, s.FlushLatency > 0)
+}
+
+func TestStatsFlushErrors(t *testing.T) {
+	ts := httptest.NewServer(nil)
+	ts.Close()
+
+	tr := NewTracerWithOptions(WithAgentAddr(ts.Listener.Addr().String())).(*Tracer)
+	tr.StartSpan("op").Finish()
+	assert.Error(t, tr.FlushTraces())
+
+	s := tr.Stats()
+	assert.Equal(t, uint64(1), s.Flushes)
+	assert.Equal(t, uint64(1), s.FlushErrors)
+}
+
+func TestPublishExpvar(t *testing.T) {
+	tr := NewTracerWithOptions(WithTransport(tracer.NewTransport("localhost", "0"))).(*Tracer)
+	tr.PublishExpvar("ddtracer_test")
+	tr.StartSpan("op")
+
+	assert.Contains(t, expvar.Get("ddtracer_test").String(), `"SpansStarted":1`)
+}
+
+func TestMetricsHandler(t *testing.T) {
+	tr := NewTracerWithOptions().(*Tracer)
+	tr.StartSpan("op").Finish()
+
+	w := httptest.NewRecorder()
+	tr.MetricsHandler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := w.Body.String()
+	assert.Contains(t, body, "# TYPE ddtracer_spans_started_total counter\nddtracer_spans_started_total 1\n")
+	assert.Contains(t, body, "ddtracer_spans_finished_total 1\n")
+	assert.Contains(t, body, "# TYPE ddtracer_flush_latency_seconds gauge\n")
+}
@@ -0,0 +1,175 @@
+// Package mocktracer provides a ddtracer.Tracer recording the spans in memory
+// instead of sending them to the agent, to assert on the tracing of the code
+// under test.
+//
+//	tr := mocktracer.New()
+//	// ... code under test using tr ...
+//	spans := tr.FinishedSpans()
+package mocktracer
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+)
+
+// logRecordPrefix is the meta key prefix of the span log records.
+const logRecordPrefix = "log."
+
+// Tracer is a ddtracer.Tracer recording the finished spans.
+type Tracer struct {
+	*ddtracer.Tracer
+	transport *transport
+}
+
+// New creates a Tracer configured by opts, it's always enabled and its
+// transport can't be changed.
+func New(opts ...ddtracer.Option) *Tracer {
+	tt := &transport{}
+	opts = append(opts, ddtracer.WithTransport(tt), ddtracer.WithTracingEnabled(true))
+
+	return &Tracer{
+		Tracer:    ddtracer.NewTracerWithOptions(opts...).(*ddtracer.Tracer),
+		transport: tt,
+	}
+}
+
+// FinishedSpans returns the spans finished so far, sorted by finish time.
+// Unsampled spans are not recorded.
+func (t *Tracer) FinishedSpans() []*Span {
+	t.FlushTraces()
+
+	t.transport.mu.Lock()
+	spans := make([]*Span, len(t.transport.spans))
+	copy(spans, t.transport.spans)
+	t.transport.mu.Unlock()
+
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].FinishTime().Before(spans[j].FinishTime())
+	})
+	return spans
+}
+
+// Reset discards the spans finished so far.
+func (t *Tracer) Reset() {
+	t.FlushTraces()
+
+	t.transport.mu.Lock()
+	t.transport.spans = nil
+	t.transport.mu.Unlock()
+}
+
+// Span is a finished span.
+type Span struct {
+	Name     string
+	Service  string
+	Resource string
+	Type     string
+
+	SpanID   uint64
+	TraceID  uint64
+	ParentID uint64
+
+	Start    time.Time
+	Duration time.Duration
+	Error    bool
+
+	// Tags is the span meta, except the log records which are in Logs.
+	Tags    map[string]string
+	Metrics map[string]float64
+	Logs    []LogRecord
+}
+
+// FinishTime returns the time the span was finished at.
+func (s *Span) FinishTime() time.Time {
+	return s.Start.Add(s.Duration)
+}
+
+// LogRecord is a log record of a span.
+type LogRecord struct {
+	Timestamp time.Time
+	Fields    map[string]interface{}
+}
+
+func newSpan(s *tracer.Span) *Span {
+	span := &Span{
+		Name:     s.Name,
+		Service:  s.Service,
+		Resource: s.Resource,
+		Type:     s.Type,
+		SpanID:   s.SpanID,
+		TraceID:  s.TraceID,
+		ParentID: s.ParentID,
+		Start:    time.Unix(0, s.Start),
+		Duration: time.Duration(s.Duration),
+		Error:    s.Error != 0,
+		Tags:     make(map[string]string, len(s.Meta)),
+		Metrics:  make(map[string]float64, len(s.Metrics)),
+	}
+	for k, v := range s.Metrics {
+		span.Metrics[k] = v
+	}
+
+	var logs []string
+	for k, v := range s.Meta {
+		if strings.HasPrefix(k, logRecordPrefix) {
+			logs = append(logs, k)
+			continue
+		}
+		span.Tags[k] = v
+	}
+
+	// Log records are keyed by index, log.10 comes after log.9.
+	sort.Slice(logs, func(i, j int) bool {
+		if len(logs[i]) != len(logs[j]) {
+			return len(logs[i]) < len(logs[j])
+		}
+		return logs[i] < logs[j]
+	})
+	for _, k := range logs {
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(s.Meta[k]), &fields); err != nil {
+			span.Tags[k] = s.Meta[k]
+			continue
+		}
+
+		record := LogRecord{Fields: fields}
+		if ts, ok := fields["timestamp"].(string); ok {
+			record.Timestamp, _ = time.Parse(time.RFC3339Nano, ts)
+			delete(fields, "timestamp")
+		}
+		span.Logs = append(span.Logs, record)
+	}
+
+	return span
+}
+
+// transport records the spans flushed by the tracer.
+type transport struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func (t *transport) SendTraces(traces [][]*tracer.Span) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, trace := range traces {
+		for _, s := range trace {
+			t.spans = append(t.spans, newSpan(s))
+		}
+	}
+	return nil, nil
+}
+
+func (t *transport) SendServices(services map[string]tracer.Service) (*http.Response, error) {
+	return nil, nil
+}
+
+func (t *transport) SetHeader(key, value string) {}
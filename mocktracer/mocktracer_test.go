@@ -0,0 +1,80 @@
+package mocktracer
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFinishedSpans(t *testing.T) {
+	tr := New(ddtracer.WithServiceName("svc"))
+
+	parent := tr.StartSpan("parent")
+	parent.SetTag("key", "value")
+	child := tr.StartSpan("child", opentracing.ChildOf(parent.Context()))
+	child.LogFields(log.String("event", "cache miss"), log.Int("attempt", 1))
+	child.SetTag("error", errors.New("boom"))
+	child.Finish()
+
+	assert.Len(t, tr.FinishedSpans(), 1)
+	parent.Finish()
+
+	spans := tr.FinishedSpans()
+	require.Len(t, spans, 2)
+	c, p := spans[0], spans[1]
+
+	assert.Equal(t, "parent", p.Name)
+	assert.Equal(t, "svc", p.Service)
+	assert.Equal(t, "value", p.Tags["key"])
+	assert.False(t, p.Error)
+	assert.Equal(t, uint64(0), p.ParentID)
+
+	assert.Equal(t, "child", c.Name)
+	assert.Equal(t, p.SpanID, c.ParentID)
+	assert.Equal(t, p.TraceID, c.TraceID)
+	assert.True(t, c.Error)
+	assert.Equal(t, "boom", c.Tags["error.msg"])
+
+	require.Len(t, c.Logs, 1)
+	assert.Equal(t, "cache miss", c.Logs[0].Fields["event"])
+	assert.Equal(t, float64(1), c.Logs[0].Fields["attempt"])
+	assert.False(t, c.Logs[0].Timestamp.IsZero())
+	for k := range c.Tags {
+		assert.NotContains(t, k, logRecordPrefix)
+	}
+
+	tr.Reset()
+	assert.Empty(t, tr.FinishedSpans())
+}
+
+func TestLogsOrder(t *testing.T) {
+	tr := New()
+
+	span := tr.StartSpan("op")
+	for i := 0; i < 12; i++ {
+		span.LogFields(log.Int("i", i))
+	}
+	span.Finish()
+
+	spans := tr.FinishedSpans()
+	require.Len(t, spans, 1)
+	require.Len(t, spans[0].Logs, 12)
+	for i, record := range spans[0].Logs {
+		assert.Equal(t, float64(i), record.Fields["i"])
+	}
+}
+
+func TestNewIgnoresDisabled(t *testing.T) {
+	os.Setenv("DD_TRACE_ENABLED", "false")
+	defer os.Unsetenv("DD_TRACE_ENABLED")
+
+	tr := New()
+	tr.StartSpan("op").Finish()
+	assert.Len(t, tr.FinishedSpans(), 1)
+}
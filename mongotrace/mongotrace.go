@@ -0,0 +1,110 @@
+// Package mongotrace traces the commands of the official MongoDB driver with
+// db spans, child of the span in the context, through its CommandMonitor.
+//
+//	opts := options.Client().SetMonitor(mongotrace.NewMonitor(tr))
+package mongotrace
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+const (
+	defaultService = "mongodb"
+
+	queryOperation = "mongodb.query"
+
+	// TagCollection is the collection the command applies to.
+	TagCollection = "mongodb.collection"
+)
+
+// Option configures the CommandMonitor.
+type Option func(*monitor)
+
+// ServiceName sets the service of the spans, "mongodb" by default.
+func ServiceName(name string) Option {
+	return func(m *monitor) {
+		m.service = name
+	}
+}
+
+// spanKey identifies a command, the request IDs being unique per connection.
+type spanKey struct {
+	connectionID string
+	requestID    int64
+}
+
+type monitor struct {
+	tr      opentracing.Tracer
+	service string
+
+	mu    sync.Mutex
+	spans map[spanKey]opentracing.Span
+}
+
+// NewMonitor returns a CommandMonitor tracing every command with a span whose
+// resource is the command, its values obfuscated by ddtracer.ObfuscateJSON.
+func NewMonitor(tr opentracing.Tracer, opts ...Option) *event.CommandMonitor {
+	m := &monitor{
+		tr:      tr,
+		service: defaultService,
+		spans:   make(map[spanKey]opentracing.Span),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return &event.CommandMonitor{
+		Started:   m.started,
+		Succeeded: m.succeeded,
+		Failed:    m.failed,
+	}
+}
+
+func (m *monitor) started(ctx context.Context, evt *event.CommandStartedEvent) {
+	span := m.tr.StartSpan(queryOperation, ddtracer.ChildOfContext(ctx), ext.SpanKindRPCClient)
+	ext.DBType.Set(span, "mongodb")
+	ext.DBInstance.Set(span, evt.DatabaseName)
+	ext.PeerService.Set(span, m.service)
+	ext.Component.Set(span, ddtracer.ObfuscateJSON(evt.Command.String()))
+	if collection, ok := evt.Command.Lookup(evt.CommandName).StringValueOK(); ok {
+		span.SetTag(TagCollection, collection)
+	}
+
+	m.mu.Lock()
+	m.spans[spanKey{evt.ConnectionID, evt.RequestID}] = span
+	m.mu.Unlock()
+}
+
+func (m *monitor) succeeded(ctx context.Context, evt *event.CommandSucceededEvent) {
+	m.finish(evt.CommandFinishedEvent, nil)
+}
+
+func (m *monitor) failed(ctx context.Context, evt *event.CommandFailedEvent) {
+	m.finish(evt.CommandFinishedEvent, errors.New(evt.Failure))
+}
+
+// finish finishes the span of the command, marking it as an error unless
+// err is nil.
+func (m *monitor) finish(evt event.CommandFinishedEvent, err error) {
+	key := spanKey{evt.ConnectionID, evt.RequestID}
+	m.mu.Lock()
+	span, ok := m.spans[key]
+	delete(m.spans, key)
+	m.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	if err != nil {
+		span.LogFields(log.Error(err))
+	}
+	span.Finish()
+}
@@ -0,0 +1,78 @@
+package mongotrace
+
+import (
+	"context"
+	"testing"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	"github.com/gchaincl/dd-go-opentracing/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+func TestMonitor(t *testing.T) {
+	tr := mocktracer.New()
+	m := NewMonitor(tr, ServiceName("users-db"))
+
+	parent := tr.StartSpan("parent").(*ddtracer.Span)
+	ctx := ddtracer.ContextWithSpan(context.Background(), parent)
+
+	m.Started(ctx, &event.CommandStartedEvent{
+		Command:      bson.Raw(`{"find":"users","filter":{"email":"jane@example.com"}}`),
+		DatabaseName: "app",
+		CommandName:  "find",
+		RequestID:    1,
+		ConnectionID: "db:27017[-1]",
+	})
+	m.Succeeded(ctx, &event.CommandSucceededEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", RequestID: 1, ConnectionID: "db:27017[-1]"},
+	})
+
+	spans := tr.FinishedSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "mongodb.query", span.Name)
+	assert.Equal(t, "users-db", span.Service)
+	assert.Equal(t, "db", span.Type)
+	assert.Equal(t, `{"find":?,"filter":{"email":?}}`, span.Resource)
+	assert.Equal(t, "app", span.Tags["db.instance"])
+	assert.Equal(t, "users", span.Tags[TagCollection])
+	assert.Equal(t, parent.TraceID, span.TraceID)
+	assert.Equal(t, parent.SpanID, span.ParentID)
+	assert.False(t, span.Error)
+	assert.NotZero(t, span.Duration)
+}
+
+func TestMonitorFailed(t *testing.T) {
+	tr := mocktracer.New()
+	m := NewMonitor(tr)
+
+	// The request IDs are unique per connection only.
+	for _, conn := range []string{"a", "b"} {
+		m.Started(context.Background(), &event.CommandStartedEvent{
+			Command:      bson.Raw(`{"insert":"users"}`),
+			CommandName:  "insert",
+			RequestID:    1,
+			ConnectionID: conn,
+		})
+	}
+	m.Failed(context.Background(), &event.CommandFailedEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{RequestID: 1, ConnectionID: "b"},
+		Failure:              "duplicate key",
+	})
+
+	spans := tr.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "mongodb", spans[0].Service)
+	assert.True(t, spans[0].Error)
+	assert.Equal(t, "duplicate key", spans[0].Tags["error.msg"])
+
+	m.Succeeded(context.Background(), &event.CommandSucceededEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{RequestID: 1, ConnectionID: "a"},
+	})
+	spans = tr.FinishedSpans()
+	require.Len(t, spans, 2)
+	assert.False(t, spans[1].Error)
+}
@@ -0,0 +1,327 @@
+// Package nethttp instruments net/http servers and clients with a ddtracer.Tracer.
+package nethttp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+const (
+	defaultServerOperation = "http.request"
+	defaultClientOperation = "http.client.request"
+
+	// TagCanceled marks the spans finished as their request context was
+	// canceled, see FinishOnCancel.
+	TagCanceled = "canceled"
+)
+
+// MWOption configures the Middleware.
+type MWOption func(*mwOptions)
+
+type mwOptions struct {
+	operation      string
+	resource       func(*http.Request) string
+	skip           func(*http.Request) bool
+	finishOnCancel bool
+}
+
+// OperationName sets the operation name of the server spans, "http.request" by default.
+func OperationName(name string) MWOption {
+	return func(o *mwOptions) {
+		o.operation = name
+	}
+}
+
+// ResourceNameFunc sets the function computing the resource of the server spans,
+// it's meant to return the matched route (i.e. "GET /user/{id}") rather than the path.
+// By default it's the request method followed by its path, see NormalizePath.
+func ResourceNameFunc(fn func(*http.Request) string) MWOption {
+	return func(o *mwOptions) {
+		o.resource = fn
+	}
+}
+
+// SkipPaths disables the tracing of the requests to the given paths, i.e.
+// "/healthz", so that the probes don't pay for the spans.
+func SkipPaths(paths ...string) MWOption {
+	skip := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		skip[p] = true
+	}
+	return SkipFunc(func(r *http.Request) bool {
+		return skip[r.URL.Path]
+	})
+}
+
+// SkipFunc disables the tracing of the requests for which fn returns true,
+// no span is started for them.
+func SkipFunc(fn func(*http.Request) bool) MWOption {
+	return func(o *mwOptions) {
+		o.skip = fn
+	}
+}
+
+// FinishOnCancel finishes the server spans as soon as their request context
+// is canceled, i.e. when the client goes away, tagged with TagCanceled, so
+// that the requests abandoned by the clients show in the traces with the
+// time elapsed until then, even when their handler is stuck.
+func FinishOnCancel() MWOption {
+	return func(o *mwOptions) {
+		o.finishOnCancel = true
+	}
+}
+
+// Middleware wraps h, tracing every request with a server span child of the
+// context propagated by the client, if any. The span is reachable from
+// the request context through ddtracer.SpanFromContext.
+func Middleware(tr opentracing.Tracer, h http.Handler, opts ...MWOption) http.Handler {
+	o := &mwOptions{
+		operation: defaultServerOperation,
+		resource:  defaultResourceName,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if o.skip != nil && o.skip(r) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		sso := []opentracing.StartSpanOption{
+			ext.SpanKindRPCServer,
+			ddtracer.SpanType(ddtracer.SpanTypeWeb),
+		}
+		if sc, err := tr.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header)); err == nil {
+			sso = append(sso, opentracing.ChildOf(sc))
+		}
+
+		span := tr.StartSpan(o.operation, sso...)
+		f := newSpanFinisher(span)
+		defer f.finish(nil)
+		if o.finishOnCancel {
+			f.finishOnCancel(r.Context())
+		}
+
+		ext.Component.Set(span, o.resource(r))
+		ext.HTTPMethod.Set(span, r.Method)
+		ext.HTTPUrl.Set(span, r.URL.Path)
+
+		sw := NewStatusWriter(w)
+		h.ServeHTTP(sw, r.WithContext(ddtracer.ContextWithSpan(r.Context(), span)))
+
+		f.finish(func() {
+			ext.HTTPStatusCode.Set(span, uint16(sw.Status))
+		})
+	})
+}
+
+// spanFinisher finishes a span once, either when its request is done or
+// when the request context is canceled, whichever comes first.
+type spanFinisher struct {
+	span opentracing.Span
+	once sync.Once
+	done chan struct{}
+}
+
+func newSpanFinisher(span opentracing.Span) *spanFinisher {
+	return &spanFinisher{span: span}
+}
+
+// finish finishes the span after calling tag, when not nil, unless it's
+// been finished already.
+func (f *spanFinisher) finish(tag func()) {
+	f.once.Do(func() {
+		if tag != nil {
+			tag()
+		}
+		f.span.Finish()
+		if f.done != nil {
+			close(f.done)
+		}
+	})
+}
+
+// finishOnCancel finishes the span tagged with TagCanceled as soon as ctx is
+// canceled, unless it's been finished before.
+func (f *spanFinisher) finishOnCancel(ctx context.Context) {
+	if ctx.Done() == nil {
+		return
+	}
+	f.done = make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			f.finish(f.canceled)
+		case <-f.done:
+		}
+	}()
+}
+
+func (f *spanFinisher) canceled() {
+	f.span.SetTag(TagCanceled, true)
+}
+
+// fail finishes the span failed with err, or canceled when ctx is.
+func (f *spanFinisher) fail(ctx context.Context, err error) {
+	if ctx.Err() != nil {
+		f.finish(f.canceled)
+		return
+	}
+	f.finish(func() {
+		f.span.LogFields(log.Error(err))
+	})
+}
+
+// StatusWriter is an http.ResponseWriter recording the status code written
+// by the handler, for the middlewares tracing the other routers.
+type StatusWriter struct {
+	http.ResponseWriter
+	// Status is the status code written, http.StatusOK by default.
+	Status int
+}
+
+// NewStatusWriter returns a StatusWriter writing to w.
+func NewStatusWriter(w http.ResponseWriter) *StatusWriter {
+	return &StatusWriter{ResponseWriter: w, Status: http.StatusOK}
+}
+
+func (w *StatusWriter) WriteHeader(status int) {
+	w.Status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Flush implements http.Flusher, flushing the wrapped ResponseWriter when it
+// supports it.
+func (w *StatusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, failing when the wrapped ResponseWriter
+// doesn't support it.
+func (w *StatusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("nethttp: ResponseWriter doesn't support Hijack")
+	}
+	return h.Hijack()
+}
+
+// Push implements http.Pusher, failing with http.ErrNotSupported when the
+// wrapped ResponseWriter doesn't support it.
+func (w *StatusWriter) Push(target string, opts *http.PushOptions) error {
+	p, ok := w.ResponseWriter.(http.Pusher)
+	if !ok {
+		return http.ErrNotSupported
+	}
+	return p.Push(target, opts)
+}
+
+// Transport is an http.RoundTripper tracing every request with a client span,
+// child of the span found in the request context, and propagating it to the server.
+type Transport struct {
+	// Tracer starts the client spans, opentracing.GlobalTracer() is used when nil.
+	Tracer opentracing.Tracer
+
+	// RoundTripper sends the requests, http.DefaultTransport is used when nil.
+	RoundTripper http.RoundTripper
+
+	// FinishOnCancel keeps the client spans open until the response body
+	// is read or closed, rather than until the response headers are
+	// received, finishing them tagged with TagCanceled as soon as the
+	// request context is canceled, so that the requests abandoned by the
+	// client show in the traces.
+	FinishOnCancel bool
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tr := t.Tracer
+	if tr == nil {
+		tr = opentracing.GlobalTracer()
+	}
+	rt := t.RoundTripper
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	span := tr.StartSpan(defaultClientOperation,
+		ddtracer.ChildOfContext(req.Context()),
+		ext.SpanKindRPCClient,
+		ddtracer.SpanType(ddtracer.SpanTypeHTTP),
+	)
+	f := newSpanFinisher(span)
+	if t.FinishOnCancel {
+		f.finishOnCancel(req.Context())
+	}
+
+	ext.Component.Set(span, req.Method+" "+NormalizePath(req.URL.Path))
+	ext.HTTPMethod.Set(span, req.Method)
+	ext.HTTPUrl.Set(span, StripQuery(req.URL.String()))
+
+	// RoundTrippers must not modify the request, inject into a copy.
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	tr.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header))
+
+	resp, err := rt.RoundTrip(r)
+	if err != nil {
+		if t.FinishOnCancel {
+			f.fail(req.Context(), err)
+		} else {
+			f.finish(func() {
+				span.LogFields(log.Error(err))
+			})
+		}
+		return resp, err
+	}
+
+	ext.HTTPStatusCode.Set(span, uint16(resp.StatusCode))
+	// The bodies of the protocol switches are writable, they're kept as is.
+	if _, ok := resp.Body.(io.Writer); t.FinishOnCancel && resp.Body != nil && !ok {
+		resp.Body = &spanBody{ReadCloser: resp.Body, ctx: req.Context(), f: f}
+		return resp, nil
+	}
+	f.finish(nil)
+	return resp, nil
+}
+
+// spanBody finishes the span of its response once read or closed.
+type spanBody struct {
+	io.ReadCloser
+	ctx context.Context
+	f   *spanFinisher
+}
+
+func (b *spanBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.f.finish(nil)
+	} else if err != nil {
+		b.f.fail(b.ctx, err)
+	}
+	return n, err
+}
+
+func (b *spanBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.f.finish(nil)
+	return err
+}
@@ -0,0 +1,224 @@
+package nethttp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	"github.com/gchaincl/dd-go-opentracing/mocktracer"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddlewareAndTransport(t *testing.T) {
+	tr := ddtracer.NewTracer()
+
+	var server *ddtracer.Span
+	h := Middleware(tr, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server = ddtracer.SpanFromContext(r.Context()).(*ddtracer.Span)
+		w.WriteHeader(http.StatusTeapot)
+	}), ResourceNameFunc(func(r *http.Request) string {
+		return "GET /users/{id}"
+	}))
+
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	parent := tr.StartSpan("parent").(*ddtracer.Span)
+	ctx := ddtracer.ContextWithSpan(context.Background(), parent)
+
+	req, _ := http.NewRequest("GET", ts.URL+"/users/1", nil)
+	client := &http.Client{Transport: &Transport{Tracer: tr}}
+	resp, err := client.Do(req.WithContext(ctx))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.NotNil(t, server)
+	assert.Equal(t, parent.TraceID, server.TraceID)
+	assert.NotEqual(t, parent.SpanID, server.ParentID)
+	assert.Equal(t, "GET /users/{id}", server.Resource)
+	assert.Equal(t, "web", server.Type)
+	assert.Equal(t, "server", server.GetMeta("span.kind"))
+	assert.Equal(t, "GET", server.GetMeta("http.method"))
+	assert.Equal(t, "/users/1", server.GetMeta("http.url"))
+	assert.Equal(t, "418", server.GetMeta("http.status_code"))
+
+	assert.Empty(t, req.Header.Get("X-Datadog-Trace-Id"))
+}
+
+func TestMiddlewareFlush(t *testing.T) {
+	tr := ddtracer.NewTracer()
+	h := Middleware(tr, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("chunk"))
+		w.(http.Flusher).Flush()
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/stream", nil))
+	assert.True(t, w.Flushed)
+	assert.Equal(t, "chunk", w.Body.String())
+
+	sw := NewStatusWriter(w)
+	_, _, err := sw.Hijack()
+	assert.Error(t, err)
+	assert.Equal(t, http.ErrNotSupported, sw.Push("/style.css", nil))
+}
+
+func TestTransportStripsQuery(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	tr := mocktracer.New()
+	client := &http.Client{Transport: &Transport{Tracer: tr}}
+	resp, err := client.Get(strings.Replace(ts.URL, "://", "://user:pass@", 1) + "/users/42?token=secret")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	spans := tr.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, ts.URL+"/users/42", spans[0].Tags["http.url"])
+	assert.Equal(t, "GET /users/?", spans[0].Resource)
+}
+
+func TestTransportError(t *testing.T) {
+	tr := ddtracer.NewTracer()
+	client := &http.Client{Transport: &Transport{Tracer: tr}}
+
+	_, err := client.Get("http://127.0.0.1:0")
+	assert.Error(t, err)
+}
+
+func TestMiddlewareSkipPaths(t *testing.T) {
+	tr := ddtracer.NewTracer()
+
+	var span opentracing.Span
+	h := Middleware(tr, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span = ddtracer.SpanFromContext(r.Context())
+	}), SkipPaths("/healthz"))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+	assert.Nil(t, span)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users", nil))
+	assert.NotNil(t, span)
+}
+
+// finishedSpans is a SpanObserver sending the finished spans.
+type finishedSpans chan *ddtracer.Span
+
+func (finishedSpans) OnStart(*ddtracer.Span) {}
+
+func (c finishedSpans) OnFinish(span *ddtracer.Span) { c <- span }
+
+func (c finishedSpans) next(t *testing.T) *ddtracer.Span {
+	t.Helper()
+	select {
+	case span := <-c:
+		return span
+	case <-time.After(time.Second):
+		t.Fatal("no span finished")
+		return nil
+	}
+}
+
+func TestMiddlewareFinishOnCancel(t *testing.T) {
+	finished := make(finishedSpans, 10)
+	tr := ddtracer.NewTracerWithOptions(ddtracer.WithSpanObserver(finished))
+
+	release := make(chan struct{})
+	h := Middleware(tr, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}), FinishOnCancel())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan struct{})
+	go func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow", nil).WithContext(ctx))
+		close(served)
+	}()
+
+	// The span is finished while the handler is still stuck.
+	cancel()
+	span := finished.next(t)
+	assert.Equal(t, "true", span.GetMeta(TagCanceled))
+	assert.True(t, span.Duration > 0)
+
+	close(release)
+	<-served
+	assert.Empty(t, span.GetMeta("http.status_code"))
+	assert.Empty(t, finished)
+
+	t.Run("Served", func(t *testing.T) {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fast", nil))
+		span := finished.next(t)
+		assert.Empty(t, span.GetMeta(TagCanceled))
+		assert.Equal(t, "200", span.GetMeta("http.status_code"))
+	})
+}
+
+func TestTransportFinishOnCancel(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+			w.Write([]byte("body"))
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	finished := make(finishedSpans, 10)
+	tr := ddtracer.NewTracerWithOptions(ddtracer.WithSpanObserver(finished))
+	client := &http.Client{Transport: &Transport{Tracer: tr, FinishOnCancel: true}}
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req, _ := http.NewRequest("GET", ts.URL, nil)
+		resp, err := client.Do(req.WithContext(ctx))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		// The span lasts until the body is read.
+		assert.Empty(t, finished)
+		cancel()
+		span := finished.next(t)
+		assert.Equal(t, "true", span.GetMeta(TagCanceled))
+		assert.Equal(t, "200", span.GetMeta("http.status_code"))
+	})
+
+	t.Run("Read", func(t *testing.T) {
+		resp, err := client.Get(ts.URL)
+		require.NoError(t, err)
+		assert.Empty(t, finished)
+
+		release <- struct{}{}
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "body", string(body))
+		span := finished.next(t)
+		assert.Empty(t, span.GetMeta(TagCanceled))
+
+		resp.Body.Close()
+		assert.Empty(t, finished)
+	})
+
+	t.Run("Canceled request", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req, _ := http.NewRequest("GET", ts.URL, nil)
+		_, err := client.Do(req.WithContext(ctx))
+		require.Error(t, err)
+
+		span := finished.next(t)
+		assert.Equal(t, "true", span.GetMeta(TagCanceled))
+	})
+}
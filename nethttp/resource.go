@@ -0,0 +1,49 @@
+package nethttp
+
+import (
+	"net/http"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+)
+
+// NormalizePath replaces the segments of path that are likely IDs by "?",
+// see ddtracer.NormalizePath.
+func NormalizePath(path string) string {
+	return ddtracer.NormalizePath(path)
+}
+
+// StripQuery removes the credentials, the query string and the fragment of
+// rawurl, see ddtracer.SanitizeURL.
+func StripQuery(rawurl string) string {
+	return ddtracer.SanitizeURL(rawurl)
+}
+
+// Router returns the route pattern matching the requests,
+// i.e. "/users/{id}", used to name the resource of the server spans.
+type Router interface {
+	Route(r *http.Request) (pattern string, ok bool)
+}
+
+// RouterFunc adapts a function to a Router.
+type RouterFunc func(r *http.Request) (string, bool)
+
+// Route implements Router.
+func (f RouterFunc) Route(r *http.Request) (string, bool) {
+	return f(r)
+}
+
+// WithRouter names the resource of the server spans by the request method
+// followed by the route matched by rt, falling back to the normalized path.
+func WithRouter(rt Router) MWOption {
+	return ResourceNameFunc(func(r *http.Request) string {
+		if pattern, ok := rt.Route(r); ok {
+			return r.Method + " " + pattern
+		}
+		return defaultResourceName(r)
+	})
+}
+
+// defaultResourceName is the request method followed by its normalized path.
+func defaultResourceName(r *http.Request) string {
+	return r.Method + " " + NormalizePath(r.URL.Path)
+}
@@ -0,0 +1,44 @@
+package ddtracer
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strconv"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// enabledEnv disables the tracing when set to false, see NewTracer.
+const enabledEnv = "DD_TRACE_ENABLED"
+
+func tracingEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv(enabledEnv))
+	return err != nil || enabled
+}
+
+// NoopTracer is a ClosableTracer which doesn't record nor send anything,
+// its spans are opentracing.NoopSpan.
+type NoopTracer struct {
+	opentracing.NoopTracer
+}
+
+// Flush does nothing.
+func (NoopTracer) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Close does nothing.
+func (NoopTracer) Close() error {
+	return nil
+}
+
+// InjectHTTPHeader does nothing.
+func (NoopTracer) InjectHTTPHeader(sc opentracing.SpanContext, h http.Header) error {
+	return nil
+}
+
+// ExtractHTTPHeader returns opentracing.ErrSpanContextNotFound.
+func (NoopTracer) ExtractHTTPHeader(h http.Header) (opentracing.SpanContext, error) {
+	return nil, opentracing.ErrSpanContextNotFound
+}
@@ -0,0 +1,64 @@
+package ddtracer
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracingEnabled(t *testing.T) {
+	defer os.Unsetenv(enabledEnv)
+
+	for value, enabled := range map[string]bool{
+		"":      true,
+		"true":  true,
+		"1":     true,
+		"nope":  true,
+		"false": false,
+		"0":     false,
+	} {
+		os.Setenv(enabledEnv, value)
+		assert.Equal(t, enabled, tracingEnabled(), value)
+	}
+}
+
+func TestNewTracerDisabled(t *testing.T) {
+	os.Setenv(enabledEnv, "false")
+	defer os.Unsetenv(enabledEnv)
+
+	for _, tr := range []ClosableTracer{
+		NewTracer(),
+		NewTracerTransport(nil),
+		NewTracerWithOptions(WithServiceName("svc")),
+	} {
+		assert.IsType(t, NoopTracer{}, tr)
+	}
+
+	assert.IsType(t, &Tracer{}, NewTracerWithOptions(WithTracingEnabled(true)))
+}
+
+func TestWithTracingEnabled(t *testing.T) {
+	assert.IsType(t, NoopTracer{}, NewTracerWithOptions(WithTracingEnabled(false)))
+}
+
+func TestNoopTracer(t *testing.T) {
+	tr := NoopTracer{}
+
+	span := tr.StartSpan("op")
+	span.SetTag("key", "value")
+	span.Finish()
+
+	h := http.Header{}
+	assert.NoError(t, tr.InjectHTTPHeader(span.Context(), h))
+	assert.Empty(t, h)
+
+	_, err := tr.ExtractHTTPHeader(h)
+	assert.Equal(t, opentracing.ErrSpanContextNotFound, err)
+
+	assert.NoError(t, tr.Flush(context.Background()))
+	assert.NoError(t, tr.Close())
+}
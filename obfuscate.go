@@ -0,0 +1,86 @@
+package ddtracer
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// sqlLiterals matches the string and number literals, and the positional
+// placeholders ($1) so they're left untouched.
+var sqlLiterals = regexp.MustCompile(`'(?:[^']|'')*'|\$\d+|\b\d+(?:\.\d+)?\b`)
+
+// ObfuscateSQL replaces the string and number literals of query by "?".
+func ObfuscateSQL(query string) string {
+	return sqlLiterals.ReplaceAllStringFunc(query, func(lit string) string {
+		if lit[0] == '$' {
+			return lit
+		}
+		return "?"
+	})
+}
+
+// ObfuscateJSON replaces the values of query, a JSON document such as a
+// MongoDB command or an Elasticsearch query, by ?, keeping its keys, i.e.
+// {"filter":{"age":{"$gt":18}}} becomes {"filter":{"age":{"$gt":?}}}.
+// The newline delimited documents (i.e. bulk requests) are obfuscated one by
+// one. It returns "?" when query isn't valid JSON.
+func ObfuscateJSON(query string) string {
+	dec := json.NewDecoder(strings.NewReader(query))
+	dec.UseNumber()
+
+	var buf bytes.Buffer
+	for dec.More() {
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		if err := obfuscateJSONValue(dec, &buf); err != nil {
+			return "?"
+		}
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return "?"
+	}
+	return buf.String()
+}
+
+// obfuscateJSONValue writes the next value of dec to buf, the scalars
+// replaced by ?.
+func obfuscateJSONValue(dec *json.Decoder, buf *bytes.Buffer) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	d, ok := tok.(json.Delim)
+	if !ok {
+		buf.WriteByte('?')
+		return nil
+	}
+
+	buf.WriteRune(rune(d))
+	for i := 0; dec.More(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if d == '{' {
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			k, _ := json.Marshal(key)
+			buf.Write(k)
+			buf.WriteByte(':')
+		}
+		if err := obfuscateJSONValue(dec, buf); err != nil {
+			return err
+		}
+	}
+	end, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	buf.WriteRune(rune(end.(json.Delim)))
+	return nil
+}
@@ -0,0 +1,51 @@
+package ddtracer
+
+import (
+	"testing"
+
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateSQL(t *testing.T) {
+	for query, expected := range map[string]string{
+		"SELECT 1":                          "SELECT ?",
+		"SELECT * FROM t2 WHERE a = 1.5":    "SELECT * FROM t2 WHERE a = ?",
+		"SELECT * FROM t WHERE a = 'b'":     "SELECT * FROM t WHERE a = ?",
+		"SELECT * FROM t WHERE a = 'it''s'": "SELECT * FROM t WHERE a = ?",
+		"SELECT * FROM t WHERE a = $1":      "SELECT * FROM t WHERE a = $1",
+		"INSERT INTO t VALUES ('a', 'b')":   "INSERT INTO t VALUES (?, ?)",
+	} {
+		assert.Equal(t, expected, ObfuscateSQL(query), query)
+	}
+}
+
+func TestSQLObfuscation(t *testing.T) {
+	query := "SELECT * FROM users WHERE email = 'jane@example.com'"
+
+	span := NewTracer().StartSpan("query").(*Span)
+	ext.DBStatement.Set(span, query)
+	assert.Equal(t, query, span.Resource)
+
+	span = NewTracerWithOptions(WithSQLObfuscation()).StartSpan("query").(*Span)
+	ext.DBStatement.Set(span, query)
+	assert.Equal(t, "SELECT * FROM users WHERE email = ?", span.Resource)
+	assert.Equal(t, "SELECT * FROM users WHERE email = ?", span.GetMeta("sql.query"))
+}
+
+func TestObfuscateJSON(t *testing.T) {
+	for query, expected := range map[string]string{
+		`{"find":"users","filter":{"age":{"$gt":18},"name":"jane"}}`: `{"find":?,"filter":{"age":{"$gt":?},"name":?}}`,
+		`{"query":{"terms":{"tags":["a","b"]}},"size":10}`:           `{"query":{"terms":{"tags":[?,?]}},"size":?}`,
+		`{"index":{}}` + "\n" + `{"user":"jane","ok":true,"n":null}`: `{"index":{}}` + "\n" + `{"user":?,"ok":?,"n":?}`,
+		` [ ] `:       `[]`,
+		``:            ``,
+		`{"a":`:       `?`,
+		`{"a":1}}`:    `?`,
+		`not json`:    `?`,
+		`{"a b":"c"}`: `{"a b":?}`,
+		`{"é":[]}`:    `{"é":[]}`,
+	} {
+		assert.Equal(t, expected, ObfuscateJSON(query), query)
+	}
+}
@@ -0,0 +1,35 @@
+package ddtracer
+
+// SpanObserver is notified of the spans started and finished by a Tracer,
+// to build custom exporters, debug logging or to dual-write the spans to
+// another backend. See WithSpanObserver.
+//
+// The observers are called synchronously, so they must be fast and must not
+// keep the span after returning, as it's not safe to read it concurrently
+// with the Tracer.
+type SpanObserver interface {
+	// OnStart is called once the span is started, with its start options applied.
+	OnStart(span *Span)
+
+	// OnFinish is called once with the final span, before it's buffered
+	// to be sent to the agent.
+	OnFinish(span *Span)
+}
+
+// SpanObserverFuncs is a SpanObserver calling its non-nil funcs.
+type SpanObserverFuncs struct {
+	Start  func(span *Span)
+	Finish func(span *Span)
+}
+
+func (o SpanObserverFuncs) OnStart(span *Span) {
+	if o.Start != nil {
+		o.Start(span)
+	}
+}
+
+func (o SpanObserverFuncs) OnFinish(span *Span) {
+	if o.Finish != nil {
+		o.Finish(span)
+	}
+}
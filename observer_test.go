@@ -0,0 +1,49 @@
+package ddtracer
+
+import (
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingObserver struct {
+	started  []string
+	finished []string
+	duration time.Duration
+}
+
+func (o *recordingObserver) OnStart(span *Span) {
+	o.started = append(o.started, span.Name+":"+span.GetMeta("key"))
+}
+
+func (o *recordingObserver) OnFinish(span *Span) {
+	o.finished = append(o.finished, span.Name)
+	o.duration = time.Duration(span.Duration)
+}
+
+func TestSpanObserver(t *testing.T) {
+	o := &recordingObserver{}
+	var funcs []string
+	tr := NewTracerWithOptions(
+		WithSpanObserver(o),
+		WithSpanObserver(SpanObserverFuncs{
+			Finish: func(span *Span) { funcs = append(funcs, span.Name) },
+		}),
+	)
+
+	parent := tr.StartSpan("parent", opentracing.Tag{Key: "key", Value: "value"})
+	child := tr.StartSpan("child", opentracing.ChildOf(parent.Context()))
+	assert.Equal(t, []string{"parent:value", "child:"}, o.started)
+	assert.Empty(t, o.finished)
+
+	start := time.Now()
+	child.FinishWithOptions(opentracing.FinishOptions{FinishTime: start.Add(time.Second)})
+	child.Finish()
+	parent.Finish()
+
+	assert.Equal(t, []string{"child", "parent"}, o.finished)
+	assert.Equal(t, []string{"child", "parent"}, funcs)
+	assert.True(t, o.duration > 0)
+}
@@ -0,0 +1,727 @@
+package ddtracer
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+)
+
+// Option configures a Tracer created with NewTracerWithOptions.
+type Option func(*config)
+
+type config struct {
+	transport   tracer.Transport
+	agentHost   string
+	agentPort   string
+	agentSocket string
+	httpClient  *http.Client
+	headers     map[string]string
+	agentless   bool
+	apiKey      string
+	site        string
+	intakeURL   string
+	encoding    Encoding
+	compress    bool
+
+	service     string
+	resource    string
+	resourceOp  bool
+	mapper      ServiceMapper
+	sampleRate  float64
+	sampler     Sampler
+	propagators map[interface{}]Propagator
+	tags        map[string]string
+	debug       bool
+	enabled     bool
+	observers   []SpanObserver
+	logger      Logger
+	analytics   float64
+	implicit    bool
+	stackTraces bool
+	idGenerator IDGenerator
+	clock       Clock
+
+	// spanRules keep the spans of the dropped traces, see WithSamplingRules,
+	// spanRulesErr being the error loading them from the environment.
+	spanRules    []SpanSamplingRule
+	spanRulesErr error
+
+	// agentAddrErr is the error of the address given to WithAgentAddr.
+	agentAddrErr error
+
+	// routerTag and routes select the destination of the traces, see
+	// WithRouter.
+	routerTag string
+	routes    map[string]Destination
+
+	// durations enables the span duration histograms, see
+	// WithDurationHistograms.
+	durations bool
+
+	// abandonedTimeout enables the detection of the spans never finished,
+	// see WithAbandonedSpanDetection.
+	abandonedTimeout time.Duration
+
+	traceID128Bit bool
+	idEncoding    IDEncoding
+	partialFlush  int
+	limits        limits
+	sanitizer     TagSanitizer
+	obfuscateSQL  bool
+	spanFilters   []FilterFunc
+	spanLimit     float64
+	traceFilters  []FilterFunc
+	dogstatsdAddr string
+
+	// propagationInject and propagationExtract are the propagation styles,
+	// the default propagators being kept when both are empty and the
+	// Datadog style being used when only one of them is.
+	propagationInject  []string
+	propagationExtract []string
+
+	// queueing is set when the traces are queued by the Tracer rather than
+	// sent straight away by the DataDog's tracer.
+	queueing        bool
+	maxQueueSize    int
+	maxPayloadBytes int
+	flushInterval   time.Duration
+	dropPolicy      DropPolicy
+
+	// retrySpans, when positive, keeps the traces failed to be sent to
+	// retry them, see WithRetry.
+	retrySpans      int
+	retryBackoff    time.Duration
+	retryMaxBackoff time.Duration
+	breakerFailures int
+	breakerProbe    time.Duration
+}
+
+// WithServiceName sets the service name of the spans started by the Tracer.
+func WithServiceName(name string) Option {
+	return func(c *config) {
+		c.service = name
+	}
+}
+
+// WithResourceName sets the resource of the root spans started by the Tracer.
+func WithResourceName(name string) Option {
+	return func(c *config) {
+		c.resource = name
+	}
+}
+
+// WithResourceFromOperation sets whether the root spans without a resource
+// name take it from their operation name, which is the default, or from
+// DefaultResource.
+func WithResourceFromOperation(enabled bool) Option {
+	return func(c *config) {
+		c.resourceOp = enabled
+	}
+}
+
+// WithServiceMapper sets the ServiceMapper naming the spans after their
+// operation. The service.name and resource.name tags still take precedence.
+func WithServiceMapper(m ServiceMapper) Option {
+	return func(c *config) {
+		c.mapper = m
+	}
+}
+
+// WithAnalytics sets whether every span started by the Tracer is indexed by
+// trace search & analytics.
+func WithAnalytics(enabled bool) Option {
+	return func(c *config) {
+		c.analytics = 0
+		if enabled {
+			c.analytics = 1
+		}
+	}
+}
+
+// WithAnalyticsRate sets the rate at which the spans started by the Tracer
+// are indexed by trace search & analytics, see Span.SetAnalyticsRate.
+func WithAnalyticsRate(rate float64) Option {
+	return func(c *config) {
+		c.analytics = rate
+	}
+}
+
+// WithAgentAddr sets the host:port address of the DataDog agent. A malformed
+// address is logged and ignored.
+func WithAgentAddr(addr string) Option {
+	return func(c *config) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			c.agentAddrErr = fmt.Errorf("agent address: %v", err)
+			return
+		}
+		c.transport = nil
+		c.agentHost, c.agentPort = host, port
+		c.agentAddrErr = nil
+	}
+}
+
+// WithUDS connects to the DataDog agent through the Unix domain socket at path.
+func WithUDS(path string) Option {
+	return func(c *config) {
+		c.transport = nil
+		c.agentSocket = path
+	}
+}
+
+// WithHTTPClient sets the client used to send the traces to the agent,
+// i.e. to go through a proxy or use TLS. Its transport is replaced when
+// combined with WithUDS.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *config) {
+		c.transport = nil
+		c.httpClient = client
+	}
+}
+
+// WithAgentless sends the traces straight to the DataDog intake with apiKey,
+// or DD_API_KEY when empty, rather than to an agent, i.e. in serverless
+// environments. The intake of the site set by DD_SITE is used,
+// datadoghq.com by default.
+func WithAgentless(apiKey string) Option {
+	return func(c *config) {
+		c.transport = nil
+		c.agentless = true
+		if apiKey != "" {
+			c.apiKey = apiKey
+		}
+	}
+}
+
+// WithIntakeURL overrides the URL of the intake used by WithAgentless.
+func WithIntakeURL(url string) Option {
+	return func(c *config) {
+		c.intakeURL = url
+	}
+}
+
+// WithMaxQueueSize bounds the number of finished spans waiting to be sent,
+// see WithDropPolicy for what happens once the queue is full.
+func WithMaxQueueSize(spans int) Option {
+	return func(c *config) {
+		c.queueing = true
+		c.maxQueueSize = spans
+	}
+}
+
+// WithFlushInterval sets how often the finished traces are sent,
+// DefaultFlushInterval by default.
+func WithFlushInterval(d time.Duration) Option {
+	return func(c *config) {
+		c.queueing = true
+		c.flushInterval = d
+	}
+}
+
+// WithMaxPayloadBytes splits the traces in requests of up to n bytes. The
+// traces larger than that on their own are sent in several chunks of spans,
+// which the agent merges back, rather than being rejected by the agent.
+func WithMaxPayloadBytes(n int) Option {
+	return func(c *config) {
+		c.queueing = true
+		c.maxPayloadBytes = n
+	}
+}
+
+// WithEncoding sets the encoding of the payloads sent to the agent,
+// EncodingMsgpack by default, or EncodingJSON when sending them through
+// WithHTTPClient or WithUDS.
+func WithEncoding(enc Encoding) Option {
+	return func(c *config) {
+		c.transport = nil
+		c.encoding = enc
+	}
+}
+
+// WithCompression gzips the payloads sent to the agent when enabled, trading
+// CPU for bandwidth, i.e. when the agent is remote. The payloads sent to the
+// intake in agentless mode are always compressed.
+func WithCompression(enabled bool) Option {
+	return func(c *config) {
+		c.transport = nil
+		c.compress = enabled
+	}
+}
+
+// WithDropPolicy sets what happens to the finished traces once the queue
+// set by WithMaxQueueSize is full, DropOldest by default. The dropped spans
+// are counted in Stats.SpansDropped.
+func WithDropPolicy(p DropPolicy) Option {
+	return func(c *config) {
+		c.queueing = true
+		c.dropPolicy = p
+	}
+}
+
+// WithRetry keeps up to spans spans of the traces failed to be sent, i.e.
+// while the agent restarts, retrying them with an exponential backoff
+// rather than dropping them straight away. Beyond it the oldest traces are
+// dropped, and counted by Stats.SpansDropped.
+func WithRetry(spans int) Option {
+	return func(c *config) {
+		c.retrySpans = spans
+	}
+}
+
+// WithRetryBackoff sets the delay before the first retry, doubled after
+// every failure up to max, 1 and 30 seconds by default.
+func WithRetryBackoff(initial, max time.Duration) Option {
+	return func(c *config) {
+		c.retryBackoff, c.retryMaxBackoff = initial, max
+	}
+}
+
+// WithCircuitBreaker stops the retries once failures consecutive attempts
+// failed, the agent being then probed every probeInterval until it's back,
+// 5 failures and 1 minute by default. A negative failures disables it.
+func WithCircuitBreaker(failures int, probeInterval time.Duration) Option {
+	return func(c *config) {
+		c.breakerFailures, c.breakerProbe = failures, probeInterval
+	}
+}
+
+// WithPartialFlush flushes the finished spans as soon as there are n of them,
+// rather than waiting for the next flush. As the spans are sent as they're
+// finished, not when their trace is, it bounds the spans buffered from
+// long-running traces like stream processors.
+func WithPartialFlush(n int) Option {
+	return func(c *config) {
+		c.partialFlush = n
+	}
+}
+
+// WithMaxTags bounds the number of tags, meta and metrics, of every span.
+// The tags beyond it are dropped and counted by the _dd.dropped_tags metric.
+func WithMaxTags(n int) Option {
+	return func(c *config) {
+		c.limits.maxTags = n
+	}
+}
+
+// WithMaxTagValueLength truncates the meta values longer than n bytes,
+// ending them with "...(truncated)". It's 25000 by default, the limit of
+// the intake, zero disabling it. The truncations are counted by
+// Stats.Truncations.
+func WithMaxTagValueLength(n int) Option {
+	return func(c *config) {
+		c.limits.maxValueLength = n
+	}
+}
+
+// WithMaxResourceLength truncates the resources longer than n bytes when
+// the spans are finished, as WithMaxTagValueLength does. It's 5000 by
+// default, the limit of the intake, zero disabling it.
+func WithMaxResourceLength(n int) Option {
+	return func(c *config) {
+		c.limits.maxResourceLength = n
+	}
+}
+
+// WithMaxLogRecords bounds the number of log records kept by every span.
+// The records beyond it are dropped and counted by the _dd.dropped_logs
+// metric, their fields are still set as tags.
+func WithMaxLogRecords(n int) Option {
+	return func(c *config) {
+		c.limits.maxLogRecords = n
+	}
+}
+
+// WithTagSanitizer sets the TagSanitizer of the meta of the spans,
+// DefaultTagSanitizer by default. A nil sanitizer disables it.
+func WithTagSanitizer(f TagSanitizer) Option {
+	return func(c *config) {
+		c.sanitizer = f
+	}
+}
+
+// WithSQLObfuscation replaces the literals of the db.statement tags by "?"
+// before using them as resource and sql.query, see ObfuscateSQL.
+func WithSQLObfuscation() Option {
+	return func(c *config) {
+		c.obfuscateSQL = true
+	}
+}
+
+// WithImplicitParenting makes the span activated on the calling goroutine,
+// see Tracer.Activate, the parent of the spans started without references.
+func WithImplicitParenting(enabled bool) Option {
+	return func(c *config) {
+		c.implicit = enabled
+	}
+}
+
+// WithCreationStackTraces captures the stack trace of the creation of every
+// span, as WithStackTrace does. It's meant for debugging, as it slows down
+// StartSpan.
+func WithCreationStackTraces(enabled bool) Option {
+	return func(c *config) {
+		c.stackTraces = enabled
+	}
+}
+
+// WithIDGenerator sets the IDGenerator of the span and trace IDs, a
+// pseudo-random one owned by the Tracer by default. See CryptoIDGenerator.
+func WithIDGenerator(g IDGenerator) Option {
+	return func(c *config) {
+		c.idGenerator = g
+	}
+}
+
+// WithClock sets the Clock timing the spans, the system one by default.
+// It lets the tests assert the exact durations of the spans.
+func WithClock(clock Clock) Option {
+	return func(c *config) {
+		c.clock = clock
+	}
+}
+
+// WithDurationHistograms records, when enabled, the histograms of the
+// durations of the finished spans per operation name, reported by Stats and
+// DebugHandler, to profile the latencies locally.
+func WithDurationHistograms(enabled bool) Option {
+	return func(c *config) {
+		c.durations = enabled
+	}
+}
+
+// WithAbandonedSpanDetection logs the spans still unfinished timeout after
+// their start, along with their creation stack trace when captured (see
+// WithCreationStackTraces), and once more if they eventually finish. It
+// helps finding the instrumentations leaking spans, at the cost of tracking
+// every open span.
+func WithAbandonedSpanDetection(timeout time.Duration) Option {
+	return func(c *config) {
+		c.abandonedTimeout = timeout
+	}
+}
+
+// WithRouter sends the traces whose spans are tagged with tag to the
+// destination of its value, i.e. to the organization of every tenant with
+// WithRouter("tenant_id", map[string]Destination{"acme": {APIKey: key}}).
+// The other traces are sent as usual. The destinations are retried and
+// buffered independently of each other (see WithRetry), and sent to
+// concurrently, a flush lasting until the slowest one is done.
+// Tag the root spans at least, or see Span.SetTraceTag to tag them all.
+func WithRouter(tag string, destinations map[string]Destination) Option {
+	return func(c *config) {
+		c.routerTag = tag
+		c.routes = destinations
+	}
+}
+
+// WithSamplingRules keeps the spans matching rules even when their trace is
+// dropped by the sampler, the first rule matching a span deciding. They
+// replace the ones of DD_SPAN_SAMPLING_RULES, or DD_SPAN_SAMPLING_RULES_FILE.
+// See ParseSpanSamplingRules for the JSON ones.
+func WithSamplingRules(rules []SpanSamplingRule) Option {
+	return func(c *config) {
+		c.spanRules, c.spanRulesErr = rules, nil
+	}
+}
+
+// WithSpanRateLimit caps the spans started per operation name to perSecond,
+// allowing bursts of up to perSecond spans. The spans over the limit are not
+// sent: StartSpan returns a context-only span, whose children are attached to
+// its parent, and they are counted by their live parent in the
+// "_dd.limited_spans.<operation>" metric.
+func WithSpanRateLimit(perSecond float64) Option {
+	return func(c *config) {
+		c.spanLimit = perSecond
+	}
+}
+
+// WithSpanFilter adds f to the filters evaluated when every span finishes,
+// dropping it when any of them matches. The rest of its trace is kept.
+func WithSpanFilter(f FilterFunc) Option {
+	return func(c *config) {
+		c.spanFilters = append(c.spanFilters, f)
+	}
+}
+
+// WithTraceFilter adds f to the filters evaluated on the local root span of
+// every trace when flushed, the root span or the first span of the service
+// continuing a remote trace, dropping the whole trace when any of them
+// matches, i.e. MatchResource(regexp.MustCompile("^GET /health")).
+// The spans of long traces flushed before their local root finishes are kept.
+func WithTraceFilter(f FilterFunc) Option {
+	return func(c *config) {
+		c.traceFilters = append(c.traceFilters, f)
+	}
+}
+
+// WithHeaders sets headers on the requests sent to the agent,
+// i.e. to authenticate against a proxy in front of it.
+func WithHeaders(headers map[string]string) Option {
+	return func(c *config) {
+		for k, v := range headers {
+			c.headers[k] = v
+		}
+	}
+}
+
+// WithTransport sets the transport used to send the traces to the agent.
+func WithTransport(tr tracer.Transport) Option {
+	return func(c *config) {
+		c.transport = tr
+	}
+}
+
+// WithSampleRate sets the ratio of traces that will be sampled, between 0.0 and 1.0.
+func WithSampleRate(rate float64) Option {
+	return func(c *config) {
+		c.sampleRate = rate
+	}
+}
+
+// WithSampler sets the Sampler deciding which traces are kept, in place of
+// the sample rate. See Tracer.SetSampler to replace it at runtime.
+func WithSampler(s Sampler) Option {
+	return func(c *config) {
+		c.sampler = s
+	}
+}
+
+// WithPropagator registers p as the Propagator for format.
+func WithPropagator(format interface{}, p Propagator) Option {
+	return func(c *config) {
+		c.propagators[format] = p
+	}
+}
+
+// WithPropagationStyle sets the comma-separated propagation styles, among
+// "datadog", "b3", "tracecontext", "xray" and "jaeger", of the TextMap and
+// HTTPHeaders formats, i.e. WithPropagationStyle("datadog,jaeger").
+// Inject sets the headers of every style, and Extract reads the first one
+// found in the styles order.
+// The DD_TRACE_PROPAGATION_STYLE, DD_TRACE_PROPAGATION_STYLE_INJECT and
+// DD_TRACE_PROPAGATION_STYLE_EXTRACT environment variables set them too.
+func WithPropagationStyle(styles string) Option {
+	return func(c *config) {
+		c.propagationInject = splitStyles(styles)
+		c.propagationExtract = splitStyles(styles)
+	}
+}
+
+// WithPropagationStyleInject sets the propagation styles injected by the
+// TextMap and HTTPHeaders formats, i.e. []string{"datadog", "b3", "tracecontext"}
+// to migrate between header conventions without downtime. See
+// WithPropagationStyle.
+func WithPropagationStyleInject(styles []string) Option {
+	return func(c *config) {
+		c.propagationInject = styles
+	}
+}
+
+// WithPropagationStyleExtract sets the propagation styles extracted by the
+// TextMap and HTTPHeaders formats, in priority order. See
+// WithPropagationStyle.
+func WithPropagationStyleExtract(styles []string) Option {
+	return func(c *config) {
+		c.propagationExtract = styles
+	}
+}
+
+// splitStyles splits the comma-separated propagation styles.
+func splitStyles(styles string) []string {
+	var split []string
+	for _, style := range strings.Split(styles, ",") {
+		if style = strings.TrimSpace(style); style != "" {
+			split = append(split, style)
+		}
+	}
+	return split
+}
+
+// WithEnv sets the environment of every span started by the Tracer, it
+// overrides DD_ENV.
+func WithEnv(env string) Option {
+	return func(c *config) {
+		c.tags[string(EnvTag)] = env
+	}
+}
+
+// WithVersion sets the version of the service on every span started by the
+// Tracer, it overrides DD_VERSION.
+func WithVersion(version string) Option {
+	return func(c *config) {
+		c.tags[string(VersionTag)] = version
+	}
+}
+
+// WithGlobalTags sets tags that will be added to every span started by the Tracer.
+func WithGlobalTags(tags map[string]string) Option {
+	return func(c *config) {
+		for k, v := range tags {
+			c.tags[k] = v
+		}
+	}
+}
+
+// WithTracingEnabled overrides DD_TRACE_ENABLED, a NoopTracer is created
+// when disabled.
+func WithTracingEnabled(enabled bool) Option {
+	return func(c *config) {
+		c.enabled = enabled
+	}
+}
+
+// WithSpanObserver adds o to the observers notified of every span started
+// and finished by the Tracer, see SpanObserver.
+func WithSpanObserver(o SpanObserver) Option {
+	return func(c *config) {
+		c.observers = append(c.observers, o)
+	}
+}
+
+// WithDogstatsdAddr sets the host:port address of DogStatsD, usually the
+// port 8125 of the DataDog agent, the Tracer reporting its health metrics
+// (i.e. datadog.tracer.spans_dropped) to it. The metrics are discarded when
+// it's not set.
+func WithDogstatsdAddr(addr string) Option {
+	return func(c *config) {
+		c.dogstatsdAddr = addr
+	}
+}
+
+// WithSpanMetrics sends the hits, errors and duration of the top-level spans
+// to DogStatsD through client, tagged by service and resource. They are sent
+// for all the spans, sampled or not, so that they remain accurate whatever
+// the sample rate. A nil client sends them to the address of
+// WithDogstatsdAddr.
+func WithSpanMetrics(client StatsdClient) Option {
+	return WithSpanObserver(&spanMetrics{client: client})
+}
+
+// WithLogger sets the Logger reporting the errors of the Tracer, it logs
+// through the standard log package by default. Use NopLogger to disable it.
+func WithLogger(l Logger) Option {
+	return func(c *config) {
+		c.logger = l
+	}
+}
+
+// WithTraceID128Bit makes the root spans to have 128-bit trace IDs, the
+// upper 64 bits being propagated alongside the lower ones.
+func WithTraceID128Bit(enabled bool) Option {
+	return func(c *config) {
+		c.traceID128Bit = enabled
+	}
+}
+
+// WithPropagationIDEncoding sets the format of the IDs of the Datadog
+// headers injected, IDEncodingDecimal by default. Either is extracted
+// regardless, so that the services emitting hex IDs can be migrated one by
+// one: only the ambiguous IDs of 16 decimal digits are parsed in enc.
+func WithPropagationIDEncoding(enc IDEncoding) Option {
+	return func(c *config) {
+		c.idEncoding = enc
+	}
+}
+
+// WithDebug enables the debug logging of the traces sent to the agent, and
+// of every finished span through the Logger, on a single line with its IDs,
+// duration and tags. The latest traces are then kept for Tracer.DumpTrace.
+func WithDebug(enabled bool) Option {
+	return func(c *config) {
+		c.debug = enabled
+	}
+}
+
+// NewTracerWithOptions creates a new Tracer configured by the DD_* environment
+// variables and opts, which take precedence. It returns a NoopTracer when
+// disabled by DD_TRACE_ENABLED.
+func NewTracerWithOptions(opts ...Option) ClosableTracer {
+	c := &config{
+		sampleRate:  1,
+		resourceOp:  true,
+		propagators: make(map[interface{}]Propagator),
+		tags:        make(map[string]string),
+		headers:     make(map[string]string),
+		enabled:     tracingEnabled(),
+		logger:      stdLogger{},
+		sanitizer:   DefaultTagSanitizer,
+		limits:      defaultLimits,
+	}
+	c.loadEnv()
+	for _, opt := range opts {
+		opt(c)
+	}
+	if !c.enabled {
+		return NoopTracer{}
+	}
+
+	if c.transport == nil {
+		checkDuplicate(c.logger)
+	}
+	t := newTracer(c)
+	t.service = c.service
+	t.resource = c.resource
+	t.staticResource = !c.resourceOp
+	t.serviceMapper = c.mapper
+	t.sampler = c.sampler
+	t.AnalyticsRate = c.analytics
+	t.TraceID128Bit = c.traceID128Bit
+	t.idEncoding = c.idEncoding
+	t.implicitParenting = c.implicit
+	t.stackTraces = c.stackTraces
+	t.limits = c.limits
+	t.sanitizer = c.sanitizer
+	t.obfuscateSQL = c.obfuscateSQL
+	if c.partialFlush > 0 {
+		t.partialFlushSpans = uint64(c.partialFlush)
+	}
+	t.observers = c.observers
+	if c.debug {
+		t.debug = newDebugObserver(c.logger)
+		t.observers = append(t.observers, t.debug)
+	}
+	t.spanFilters = c.spanFilters
+	if c.spanLimit > 0 {
+		t.limiter = newSpanLimiter(c.spanLimit)
+	}
+	if c.durations {
+		t.durations = newDurationHistograms()
+		t.observers = append(t.observers, t.durations)
+	}
+	if c.agentAddrErr != nil {
+		c.logger.Printf("%v", c.agentAddrErr)
+	}
+	if c.spanRulesErr != nil {
+		c.logger.Printf("%v", c.spanRulesErr)
+	}
+	if len(c.spanRules) > 0 {
+		t.spanSampler = t.newSpanSampler(c.spanRules)
+	}
+	if c.abandonedTimeout > 0 {
+		t.abandoned = t.startAbandonedSpans(c.abandonedTimeout)
+		t.observers = append(t.observers, t.abandoned)
+	}
+	t.DebugLoggingEnabled = c.debug
+	t.SetSampleRate(c.sampleRate)
+	if inject, extract := c.propagationInject, c.propagationExtract; len(inject) > 0 || len(extract) > 0 {
+		if len(inject) == 0 {
+			inject = []string{PropagationStyleDatadog}
+		}
+		if len(extract) == 0 {
+			extract = []string{PropagationStyleDatadog}
+		}
+		t.setPropagationStyles(inject, extract)
+	}
+	for format, p := range c.propagators {
+		t.RegisterPropagator(format, p)
+	}
+	for k, v := range c.tags {
+		t.SetMeta(k, v)
+	}
+
+	return t
+}
@@ -0,0 +1,105 @@
+package ddtracer
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopPropagator struct{}
+
+func (nopPropagator) Inject(sc *SpanContext, carrier interface{}) error { return nil }
+
+func (nopPropagator) Extract(carrier interface{}) (opentracing.SpanContext, error) {
+	return nil, opentracing.ErrSpanContextNotFound
+}
+
+func TestNewTracerWithOptions(t *testing.T) {
+	env := newEnv(t)
+	defer env.close()
+	u, _ := url.Parse(env.ts.URL)
+
+	tr := NewTracerWithOptions(
+		WithServiceName("my-service"),
+		WithAgentAddr(u.Host),
+		WithGlobalTags(map[string]string{"env": "test"}),
+		WithPropagator("custom", nopPropagator{}),
+		WithDebug(true),
+	).(*Tracer)
+	assert.True(t, tr.DebugLoggingEnabled)
+
+	span := tr.StartSpan("test").(*Span)
+	assert.Equal(t, "my-service", span.Service)
+	assert.Equal(t, "test", span.GetMeta("env"))
+	span.Finish()
+
+	_, err := tr.Extract("custom", nil)
+	assert.Equal(t, opentracing.ErrSpanContextNotFound, err)
+
+	require.NoError(t, tr.FlushTraces())
+	require.Len(t, env.reqs, 1)
+
+	var spans [][]*tracer.Span
+	require.NoError(t, json.NewDecoder(env.reqs[0].Body).Decode(&spans))
+	assert.Equal(t, "my-service", spans[0][0].Service)
+
+	t.Run("Malformed agent address", func(t *testing.T) {
+		l := &recordingLogger{}
+		NewTracerWithOptions(WithAgentAddr("localhost"), WithLogger(l))
+		require.Len(t, l.lines, 1)
+		assert.Contains(t, l.lines[0], "agent address: address localhost: missing port in address")
+	})
+
+	t.Run("Defaults", func(t *testing.T) {
+		span := NewTracerWithOptions().StartSpan("test").(*Span)
+		assert.Equal(t, DefaultService, span.Service)
+		assert.Equal(t, "test", span.Resource)
+	})
+
+	t.Run("Static resource", func(t *testing.T) {
+		span := NewTracerWithOptions(WithResourceFromOperation(false)).StartSpan("test").(*Span)
+		assert.Equal(t, DefaultResource, span.Resource)
+	})
+
+	t.Run("Per tracer defaults", func(t *testing.T) {
+		a := NewTracerWithOptions(WithServiceName("a"), WithResourceName("/a"))
+		b := NewTracerWithOptions(WithServiceName("b"), WithResourceName("/b"))
+
+		spanA := a.StartSpan("test").(*Span)
+		spanB := b.StartSpan("test").(*Span)
+		assert.Equal(t, "a", spanA.Service)
+		assert.Equal(t, "/a", spanA.Resource)
+		assert.Equal(t, "b", spanB.Service)
+		assert.Equal(t, "/b", spanB.Resource)
+	})
+
+	t.Run("Unified service tagging", func(t *testing.T) {
+		defer setenv(map[string]string{envEnv: "staging", versionEnv: "1.0.0"})()
+
+		tr := NewTracerWithOptions(WithEnv("prod"), WithGlobalTags(map[string]string{"team": "core"}))
+
+		carrier := opentracing.TextMapCarrier{}
+		remote := NewTracerWithOptions().StartSpan("remote")
+		require.NoError(t, tr.Inject(remote.Context(), opentracing.TextMap, carrier))
+		sc, err := tr.Extract(opentracing.TextMap, carrier)
+		require.NoError(t, err)
+
+		root := tr.StartSpan("root").(*Span)
+		child := tr.StartSpan("child", opentracing.ChildOf(root.Context())).(*Span)
+		extracted := tr.StartSpan("extracted", opentracing.ChildOf(sc)).(*Span)
+		for _, span := range []*Span{root, child, extracted} {
+			assert.Equal(t, "prod", span.GetMeta("env"), span.Name)
+			assert.Equal(t, "1.0.0", span.GetMeta("version"), span.Name)
+			assert.Equal(t, "core", span.GetMeta("team"), span.Name)
+		}
+
+		span := NewTracerWithOptions(WithVersion("2.0.0")).StartSpan("test").(*Span)
+		assert.Equal(t, "2.0.0", span.GetMeta("version"))
+		assert.Equal(t, "staging", span.GetMeta("env"))
+	})
+}
@@ -0,0 +1,260 @@
+// Package otelbridge exposes a ddtracer Tracer through the OpenTelemetry
+// tracing API, so that OpenTelemetry and OpenTracing instrumentation can run
+// side by side during a migration while sending their spans to DataDog.
+//
+// The spans started by either API are found in the context by the other one,
+// as long as the OpenTracing spans are put in it by ddtracer.ContextWithSpan
+// or by ContextWithSpan.
+package otelbridge
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+)
+
+// libraryNameTag is the tag set to the name of the OpenTelemetry Tracer
+// starting the spans.
+const libraryNameTag = "otel.library.name"
+
+// TracerProvider is an OpenTelemetry TracerProvider starting the spans with
+// an OpenTracing Tracer, usually a ddtracer one.
+type TracerProvider struct {
+	embedded.TracerProvider
+
+	tracer opentracing.Tracer
+}
+
+// NewTracerProvider returns a TracerProvider starting the spans with tr,
+// i.e. otel.SetTracerProvider(otelbridge.NewTracerProvider(tracer)).
+func NewTracerProvider(tr opentracing.Tracer) *TracerProvider {
+	return &TracerProvider{tracer: tr}
+}
+
+// Tracer returns a Tracer tagging its spans with the instrumentation name.
+func (p *TracerProvider) Tracer(name string, opts ...trace.TracerOption) trace.Tracer {
+	return &Tracer{provider: p, name: name}
+}
+
+// Tracer is an OpenTelemetry Tracer, see TracerProvider.
+type Tracer struct {
+	embedded.Tracer
+
+	provider *TracerProvider
+	name     string
+}
+
+// Start starts a span, child of the OpenTelemetry or OpenTracing span of ctx
+// if any, and returns it along with a context holding it for both APIs.
+// The links are kept as span links.
+func (t *Tracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+
+	var sso []opentracing.StartSpanOption
+	if !cfg.NewRoot() {
+		if parent := parentContext(ctx); parent != nil {
+			sso = append(sso, opentracing.ChildOf(parent))
+		}
+	}
+	var links []opentracing.SpanContext
+	for _, link := range cfg.Links() {
+		if link.SpanContext.IsValid() {
+			links = append(links, spanContext(link.SpanContext))
+		}
+	}
+	if len(links) > 0 {
+		sso = append(sso, ddtracer.WithSpanLinks(links...))
+	}
+	if ts := cfg.Timestamp(); !ts.IsZero() {
+		sso = append(sso, opentracing.StartTime(ts))
+	}
+	if kind := spanKind(cfg.SpanKind()); kind != "" {
+		sso = append(sso, opentracing.Tag{Key: string(ext.SpanKind), Value: kind})
+	}
+	if t.name != "" {
+		sso = append(sso, opentracing.Tag{Key: libraryNameTag, Value: t.name})
+	}
+	for _, kv := range cfg.Attributes() {
+		sso = append(sso, opentracing.Tag{Key: string(kv.Key), Value: kv.Value.AsInterface()})
+	}
+
+	s := &span{ot: t.provider.tracer.StartSpan(name, sso...), provider: t.provider}
+	return contextWithSpan(ctx, s), s
+}
+
+// ContextWithSpan returns a copy of ctx holding the OpenTracing span for both
+// APIs, so the OpenTelemetry instrumentation uses it as parent.
+func ContextWithSpan(ctx context.Context, s opentracing.Span) context.Context {
+	return contextWithSpan(ctx, &span{ot: s})
+}
+
+func contextWithSpan(ctx context.Context, s *span) context.Context {
+	return trace.ContextWithSpan(ddtracer.ContextWithSpan(ctx, s.ot), s)
+}
+
+// parentContext returns the context of the latest span of ctx, which might
+// have been started by either API, or nil if there's none.
+func parentContext(ctx context.Context) opentracing.SpanContext {
+	otSpan := ddtracer.SpanFromContext(ctx)
+	otelSpan := trace.SpanFromContext(ctx)
+
+	if s, ok := otelSpan.(*span); ok {
+		if otSpan == nil || otSpan == s.ot || startedBefore(otSpan, s.ot) {
+			return s.ot.Context()
+		}
+	}
+	if otSpan != nil {
+		return otSpan.Context()
+	}
+	if sc := otelSpan.SpanContext(); sc.IsValid() {
+		return spanContext(sc)
+	}
+	return nil
+}
+
+// startedBefore reports whether a started before b, false if unknown.
+func startedBefore(a, b opentracing.Span) bool {
+	da, ok := a.(*ddtracer.Span)
+	if !ok {
+		return false
+	}
+	db, ok := b.(*ddtracer.Span)
+	return ok && da.Start < db.Start
+}
+
+// spanContext converts an OpenTelemetry span context, which is split into
+// the upper and lower 64 bits of the trace ID.
+func spanContext(sc trace.SpanContext) *ddtracer.SpanContext {
+	traceID, spanID := sc.TraceID(), sc.SpanID()
+	return ddtracer.NewRemoteSpanContext(
+		binary.BigEndian.Uint64(traceID[:8]),
+		binary.BigEndian.Uint64(traceID[8:]),
+		binary.BigEndian.Uint64(spanID[:]),
+		sc.IsSampled(),
+	)
+}
+
+func spanKind(kind trace.SpanKind) string {
+	switch kind {
+	case trace.SpanKindServer:
+		return string(ext.SpanKindRPCServerEnum)
+	case trace.SpanKindClient:
+		return string(ext.SpanKindRPCClientEnum)
+	case trace.SpanKindProducer:
+		return string(ext.SpanKindProducerEnum)
+	case trace.SpanKindConsumer:
+		return string(ext.SpanKindConsumerEnum)
+	}
+	return ""
+}
+
+// span is an OpenTelemetry span wrapping an OpenTracing one.
+type span struct {
+	embedded.Span
+	ot opentracing.Span
+
+	provider *TracerProvider
+	ended    int32
+}
+
+func (s *span) End(opts ...trace.SpanEndOption) {
+	if !atomic.CompareAndSwapInt32(&s.ended, 0, 1) {
+		return
+	}
+	cfg := trace.NewSpanEndConfig(opts...)
+	s.ot.FinishWithOptions(opentracing.FinishOptions{FinishTime: cfg.Timestamp()})
+}
+
+// AddEvent logs the event with its attributes, at the current time as
+// OpenTracing spans don't log at a given time.
+func (s *span) AddEvent(name string, opts ...trace.EventOption) {
+	cfg := trace.NewEventConfig(opts...)
+	s.ot.LogFields(eventFields(name, cfg.Attributes())...)
+}
+
+// AddLink is a no-op, the links are only kept when starting the span.
+func (s *span) AddLink(link trace.Link) {}
+
+func (s *span) IsRecording() bool {
+	return atomic.LoadInt32(&s.ended) == 0
+}
+
+// RecordError logs err as an OpenTelemetry exception event. Like in
+// OpenTelemetry, the span is only flagged as an error by SetStatus.
+func (s *span) RecordError(err error, opts ...trace.EventOption) {
+	if err == nil {
+		return
+	}
+	cfg := trace.NewEventConfig(opts...)
+	s.ot.LogFields(eventFields("exception", append([]attribute.KeyValue{
+		attribute.String("exception.type", fmt.Sprintf("%T", err)),
+		attribute.String("exception.message", err.Error()),
+	}, cfg.Attributes()...))...)
+}
+
+func (s *span) SpanContext() trace.SpanContext {
+	sc, ok := s.ot.Context().(*ddtracer.SpanContext)
+	if !ok {
+		return trace.SpanContext{}
+	}
+
+	var traceID trace.TraceID
+	var spanID trace.SpanID
+	binary.BigEndian.PutUint64(traceID[:8], sc.TraceIDHigh())
+	binary.BigEndian.PutUint64(traceID[8:], sc.TraceID())
+	binary.BigEndian.PutUint64(spanID[:], sc.SpanID())
+	var flags trace.TraceFlags
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: flags.WithSampled(sc.Sampled()),
+	})
+}
+
+func (s *span) SetStatus(code codes.Code, description string) {
+	switch code {
+	case codes.Error:
+		ext.Error.Set(s.ot, true)
+		if description != "" {
+			s.ot.SetTag("error.msg", description)
+		}
+	case codes.Ok:
+		ext.Error.Set(s.ot, false)
+	}
+}
+
+func (s *span) SetName(name string) {
+	s.ot.SetOperationName(name)
+}
+
+func (s *span) SetAttributes(kv ...attribute.KeyValue) {
+	for _, attr := range kv {
+		s.ot.SetTag(string(attr.Key), attr.Value.AsInterface())
+	}
+}
+
+func (s *span) TracerProvider() trace.TracerProvider {
+	if s.provider == nil {
+		return NewTracerProvider(s.ot.Tracer())
+	}
+	return s.provider
+}
+
+func eventFields(name string, attrs []attribute.KeyValue) []log.Field {
+	fields := make([]log.Field, 0, len(attrs)+1)
+	fields = append(fields, log.String("event", name))
+	for _, attr := range attrs {
+		fields = append(fields, log.Object(string(attr.Key), attr.Value.AsInterface()))
+	}
+	return fields
+}
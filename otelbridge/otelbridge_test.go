@@ -0,0 +1,119 @@
+package otelbridge
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracer(t *testing.T) {
+	tr := ddtracer.NewTracer()
+	otel := NewTracerProvider(tr).Tracer("github.com/acme/app")
+
+	start := time.Now().Add(-time.Second)
+	ctx, otelSpan := otel.Start(context.Background(), "http.request",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithTimestamp(start),
+		trace.WithAttributes(attribute.String("http.method", "GET"), attribute.Int("retries", 2)),
+	)
+	root := ddtracer.SpanFromContext(ctx).(*ddtracer.Span)
+	assert.Equal(t, "http.request", root.Name)
+	assert.Equal(t, start.UnixNano(), root.Start)
+	assert.Equal(t, "server", root.GetMeta("span.kind"))
+	assert.Equal(t, "GET", root.GetMeta("http.method"))
+	assert.Equal(t, float64(2), root.Metrics["retries"])
+	assert.Equal(t, "github.com/acme/app", root.GetMeta(libraryNameTag))
+
+	sc := otelSpan.SpanContext()
+	assert.True(t, sc.IsValid())
+	assert.True(t, sc.IsSampled())
+	assert.Equal(t, root.SpanID, spanContext(sc).SpanID())
+	assert.Equal(t, root.TraceID, spanContext(sc).TraceID())
+
+	// OpenTracing child of an OpenTelemetry span.
+	child := tr.StartSpan("db.query", ddtracer.ChildOfContext(ctx))
+	cctx := ddtracer.ContextWithSpan(ctx, child)
+	assert.Equal(t, root.SpanID, child.(*ddtracer.Span).ParentID)
+
+	// OpenTelemetry child of an OpenTracing span.
+	_, grandchild := otel.Start(cctx, "cache.get")
+	assert.Equal(t, child.(*ddtracer.Span).SpanID, grandchild.(*span).ot.(*ddtracer.Span).ParentID)
+	assert.Equal(t, root.TraceID, grandchild.(*span).ot.(*ddtracer.Span).TraceID)
+
+	otelSpan.SetName("GET /users")
+	otelSpan.SetStatus(codes.Error, "boom")
+	otelSpan.RecordError(errors.New("boom"))
+	otelSpan.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", 1)))
+	assert.EqualValues(t, 1, root.Error)
+	assert.Equal(t, "boom", root.GetMeta("error.msg"))
+	assert.Equal(t, "GET /users", root.Name)
+
+	assert.True(t, otelSpan.IsRecording())
+	otelSpan.End()
+	otelSpan.End()
+	assert.False(t, otelSpan.IsRecording())
+	assert.NotZero(t, root.Duration)
+}
+
+func TestTracerRemoteParent(t *testing.T) {
+	otel := NewTracerProvider(ddtracer.NewTracer()).Tracer("")
+
+	remote := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2},
+		SpanID:     trace.SpanID{0, 0, 0, 0, 0, 0, 0, 3},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	link := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{15: 4},
+		SpanID:  trace.SpanID{7: 5},
+	})
+
+	_, s := otel.Start(trace.ContextWithRemoteSpanContext(context.Background(), remote), "consume",
+		trace.WithLinks(trace.Link{SpanContext: link}))
+	consumer := s.(*span).ot.(*ddtracer.Span)
+	assert.Equal(t, uint64(2), consumer.TraceID)
+	assert.Equal(t, uint64(3), consumer.ParentID)
+	assert.Contains(t, consumer.GetMeta("_dd.span_links"), `"trace_id":4,"span_id":5`)
+
+	sc := s.SpanContext()
+	assert.Equal(t, remote.TraceID(), sc.TraceID())
+
+	_, root := otel.Start(trace.ContextWithRemoteSpanContext(context.Background(), remote), "root", trace.WithNewRoot())
+	assert.Zero(t, root.(*span).ot.(*ddtracer.Span).ParentID)
+
+	t.Run("Links of new roots", func(t *testing.T) {
+		for _, ctx := range []context.Context{
+			trace.ContextWithRemoteSpanContext(context.Background(), remote),
+			context.Background(),
+		} {
+			_, s := otel.Start(ctx, "batch", trace.WithNewRoot(), trace.WithLinks(trace.Link{SpanContext: link}))
+			root := s.(*span).ot.(*ddtracer.Span)
+			assert.Zero(t, root.ParentID)
+			assert.Equal(t, root.SpanID, root.TraceID)
+			assert.Contains(t, root.GetMeta("_dd.span_links"), `"trace_id":4,"span_id":5`)
+		}
+
+		_, s := otel.Start(context.Background(), "batch", trace.WithLinks(trace.Link{SpanContext: link}))
+		assert.Zero(t, s.(*span).ot.(*ddtracer.Span).ParentID)
+	})
+}
+
+func TestContextWithSpan(t *testing.T) {
+	tr := ddtracer.NewTracer()
+	parent := tr.StartSpan("parent").(*ddtracer.Span)
+
+	ctx := ContextWithSpan(context.Background(), parent)
+	sc := trace.SpanContextFromContext(ctx)
+	require.True(t, sc.IsValid())
+	assert.Equal(t, parent.SpanID, spanContext(sc).SpanID())
+	assert.Equal(t, parent, ddtracer.SpanFromContext(ctx))
+}
@@ -0,0 +1,156 @@
+package ddtracer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/ugorji/go/codec"
+)
+
+// Encoding is the format of the payloads sent to the agent, see WithEncoding.
+type Encoding string
+
+const (
+	// EncodingJSON encodes the payloads in JSON, as understood by any agent.
+	EncodingJSON Encoding = "application/json"
+	// EncodingMsgpack encodes the payloads in MessagePack, more compact and
+	// cheaper to encode than JSON, as the DataDog's transport does.
+	EncodingMsgpack Encoding = "application/msgpack"
+)
+
+var msgpackHandle codec.MsgpackHandle
+
+// marshal encodes v.
+func (e Encoding) marshal(v interface{}) ([]byte, error) {
+	if e == EncodingMsgpack {
+		var b []byte
+		err := codec.NewEncoderBytes(&b, &msgpackHandle).Encode(v)
+		return b, err
+	}
+	return json.Marshal(v)
+}
+
+// arraySize returns the size of an array of n elements, of size bytes in all.
+func (e Encoding) arraySize(n, size int) int {
+	if e == EncodingMsgpack {
+		switch {
+		case n < 16:
+			return size + 1
+		case n <= 0xffff:
+			return size + 3
+		}
+		return size + 5
+	}
+	if n == 0 {
+		return 2
+	}
+	return size + n + 1
+}
+
+// array encodes the array of the encoded elems.
+func (e Encoding) array(elems [][]byte) []byte {
+	var b bytes.Buffer
+	if e == EncodingMsgpack {
+		n := len(elems)
+		switch {
+		case n < 16:
+			b.WriteByte(0x90 | byte(n))
+		case n <= 0xffff:
+			b.WriteByte(0xdc)
+			binary.Write(&b, binary.BigEndian, uint16(n))
+		default:
+			b.WriteByte(0xdd)
+			binary.Write(&b, binary.BigEndian, uint32(n))
+		}
+		for _, elem := range elems {
+			b.Write(elem)
+		}
+		return b.Bytes()
+	}
+
+	b.WriteByte('[')
+	for i, elem := range elems {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.Write(elem)
+	}
+	b.WriteByte(']')
+	return b.Bytes()
+}
+
+// payload is an encoded batch of traces.
+type payload struct {
+	body   []byte
+	traces int
+}
+
+// encodePayloads encodes traces in payloads of up to max bytes, unlimited
+// when not positive. The traces too large to fit in a payload on their own
+// are chunked, their spans being split in several traces sharing their
+// trace ID, which the agent merges back. Only the spans too large on their
+// own are dropped, and reported by the error.
+func encodePayloads(enc Encoding, traces [][]*tracer.Span, max int) ([]payload, error) {
+	var (
+		payloads []payload
+		errs     []error
+		elems    [][]byte
+		size     int
+	)
+	flush := func() {
+		if len(elems) > 0 {
+			payloads = append(payloads, payload{body: enc.array(elems), traces: len(elems)})
+			elems, size = nil, 0
+		}
+	}
+
+	var add func(trace []*tracer.Span)
+	add = func(trace []*tracer.Span) {
+		b, err := enc.marshal(trace)
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		if max > 0 && enc.arraySize(1, len(b)) > max {
+			if len(trace) == 1 {
+				errs = append(errs, fmt.Errorf("dropping span %q: %d bytes exceed the payload limit", trace[0].Name, len(b)))
+				return
+			}
+			add(trace[:len(trace)/2])
+			add(trace[len(trace)/2:])
+			return
+		}
+		if max > 0 && len(elems) > 0 && enc.arraySize(len(elems)+1, size+len(b)) > max {
+			flush()
+		}
+		elems = append(elems, b)
+		size += len(b)
+	}
+
+	for _, trace := range traces {
+		add(trace)
+	}
+	flush()
+
+	if len(errs) > 0 {
+		return payloads, fmt.Errorf("encoding traces: %v", errs)
+	}
+	return payloads, nil
+}
+
+// gzipped returns body compressed with gzip.
+func gzipped(body []byte) ([]byte, error) {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	if _, err := zw.Write(body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,102 @@
+package ddtracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugorji/go/codec"
+)
+
+// decodePayload decodes the traces of a payload encoded with enc.
+func decodePayload(t *testing.T, enc Encoding, body []byte) [][]*tracer.Span {
+	var traces [][]*tracer.Span
+	if enc == EncodingMsgpack {
+		require.NoError(t, codec.NewDecoderBytes(body, &msgpackHandle).Decode(&traces))
+	} else {
+		require.NoError(t, json.Unmarshal(body, &traces))
+	}
+	return traces
+}
+
+func TestEncodePayloads(t *testing.T) {
+	for _, enc := range []Encoding{EncodingMsgpack, EncodingJSON} {
+		t.Run(string(enc), func(t *testing.T) {
+			t.Run("Unlimited", func(t *testing.T) {
+				var traces [][]*tracer.Span
+				for i := 0; i < 20; i++ {
+					traces = append(traces, trace(fmt.Sprint("t", i)))
+				}
+				payloads, err := encodePayloads(enc, traces, 0)
+				require.NoError(t, err)
+				require.Len(t, payloads, 1)
+				assert.Equal(t, 20, payloads[0].traces)
+				assert.Equal(t, names(traces), names(decodePayload(t, enc, payloads[0].body)))
+			})
+
+			t.Run("Batches", func(t *testing.T) {
+				b, _ := enc.marshal(trace("a"))
+				max := enc.arraySize(2, 2*len(b))
+
+				payloads, err := encodePayloads(enc, [][]*tracer.Span{trace("a"), trace("b"), trace("c")}, max)
+				require.NoError(t, err)
+				require.Len(t, payloads, 2)
+				assert.Equal(t, []string{"a", "b"}, names(decodePayload(t, enc, payloads[0].body)))
+				assert.Equal(t, []string{"c"}, names(decodePayload(t, enc, payloads[1].body)))
+				for _, p := range payloads {
+					assert.True(t, len(p.body) <= max)
+				}
+			})
+
+			t.Run("Chunks", func(t *testing.T) {
+				large := spans("span", 10)
+				for _, s := range large {
+					s.TraceID = 42
+					s.Meta = map[string]string{"value": strings.Repeat("x", 100)}
+				}
+
+				payloads, err := encodePayloads(enc, [][]*tracer.Span{large}, 500)
+				require.NoError(t, err)
+				assert.True(t, len(payloads) > 1)
+
+				var got []*tracer.Span
+				for _, p := range payloads {
+					assert.True(t, len(p.body) <= 500)
+					for _, trace := range decodePayload(t, enc, p.body) {
+						got = append(got, trace...)
+					}
+				}
+				require.Len(t, got, 10)
+				for _, s := range got {
+					assert.Equal(t, uint64(42), s.TraceID)
+				}
+			})
+
+			t.Run("Oversized span", func(t *testing.T) {
+				payloads, err := encodePayloads(enc, [][]*tracer.Span{trace("a"), trace(strings.Repeat("x", 1000))}, 500)
+				assert.Error(t, err)
+				require.Len(t, payloads, 1)
+				assert.Equal(t, []string{"a"}, names(decodePayload(t, enc, payloads[0].body)))
+			})
+		})
+	}
+}
+
+func TestMsgpackArrayHeader(t *testing.T) {
+	for _, n := range []int{0, 15, 16, 0x10000} {
+		elems := make([][]byte, n)
+		for i := range elems {
+			elems[i] = []byte{0x01}
+		}
+		body := EncodingMsgpack.array(elems)
+		assert.Equal(t, EncodingMsgpack.arraySize(n, n), len(body))
+
+		var decoded []int
+		require.NoError(t, codec.NewDecoderBytes(body, &msgpackHandle).Decode(&decoded))
+		assert.Len(t, decoded, n)
+	}
+}
@@ -1,6 +1,12 @@
 package ddtracer
 
 import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"strconv"
 	"strings"
 
@@ -9,28 +15,246 @@ import (
 )
 
 const (
+	// Datadog's standard headers, IDs are decimal encoded unless configured
+	// otherwise by WithPropagationIDEncoding.
+	fieldDatadogTraceID          = "x-datadog-trace-id"
+	fieldDatadogParentID         = "x-datadog-parent-id"
+	fieldDatadogSamplingPriority = "x-datadog-sampling-priority"
+	// fieldDatadogTags holds the propagated tags as comma separated key=value
+	// pairs, i.e. _dd.p.tid for 128-bit trace IDs.
+	fieldDatadogTags = "x-datadog-tags"
+
+	// Legacy headers, IDs are hex encoded.
 	tracePrefix = "dd-trace-"
 
 	fieldSpanID   = tracePrefix + "spanid"
 	fieldTraceID  = tracePrefix + "traceid"
 	fieldParentID = tracePrefix + "parentid"
-	//fieldSampled = tracePrefix + "sampled"
+
+	baggagePrefix = "ot-baggage-"
 )
 
+// IDEncoding is the format of the IDs of the Datadog headers, see
+// WithPropagationIDEncoding.
+type IDEncoding int
+
+const (
+	// IDEncodingDecimal is the format of the DataDog tracing libraries, the
+	// default one.
+	IDEncodingDecimal IDEncoding = iota
+	// IDEncodingHex formats the IDs as 16 hexadecimal digits, as the
+	// services instrumented with an old version of this package do.
+	IDEncodingHex
+)
+
+// format formats id in the encoding.
+func (e IDEncoding) format(id uint64) string {
+	if e == IDEncodingHex {
+		s := strconv.FormatUint(id, 16)
+		return "0000000000000000"[len(s):] + s
+	}
+	return strconv.FormatUint(id, 10)
+}
+
+// parse parses the id of a Datadog header in either encoding: it's hex when
+// it looks like so, i.e. with a 0x prefix, a-f digits or zero padded to 16
+// digits, decimal otherwise. The IDs of 16 decimal digits are hex when it's
+// the configured encoding, as format pads them, but never the other ones.
+func (e IDEncoding) parse(id string) (uint64, error) {
+	if len(id) > 2 && id[0] == '0' && (id[1] == 'x' || id[1] == 'X') {
+		return strconv.ParseUint(id[2:], 16, 64)
+	}
+	if isHexID(id) || (e == IDEncodingHex && len(id) == 16) {
+		return strconv.ParseUint(id, 16, 64)
+	}
+	return strconv.ParseUint(id, 10, 64)
+}
+
+// isHexID reports whether id can only be hex encoded.
+func isHexID(id string) bool {
+	if len(id) == 16 && id[0] == '0' {
+		return true
+	}
+	for i := 0; i < len(id); i++ {
+		if c := id[i] | 0x20; c >= 'a' && c <= 'f' {
+			return true
+		}
+	}
+	return false
+}
+
+// Propagator injects and extracts spans into/from carriers of a given format.
+type Propagator interface {
+	Inject(sc *SpanContext, carrier interface{}) error
+	Extract(carrier interface{}) (opentracing.SpanContext, error)
+}
+
+// RegisterPropagator sets p as the Propagator used by Inject and Extract for
+// format, replacing the existing one.
+// It's not safe to call it concurrently with Inject or Extract.
+func (t *Tracer) RegisterPropagator(format interface{}, p Propagator) {
+	t.propagators[format] = p
+}
+
+// EnableB3 sets the B3 propagator alongside the Datadog one for the
+// TextMap and HTTPHeaders formats.
+// When inject is set both headers are injected, when extract is set B3
+// headers are extracted if there's no Datadog ones.
+func (t *Tracer) EnableB3(inject, extract bool) {
+	t.chainPropagator(&b3Propagator{}, inject, extract)
+}
+
+// EnableTraceContext sets the W3C Trace Context propagator alongside the
+// Datadog one for the TextMap and HTTPHeaders formats, see EnableB3.
+func (t *Tracer) EnableTraceContext(inject, extract bool) {
+	t.chainPropagator(&traceContextPropagator{}, inject, extract)
+}
+
+// EnableXRay sets the AWS X-Ray propagator alongside the Datadog one for
+// the TextMap and HTTPHeaders formats, see EnableB3. It keeps the traces
+// going through ALBs or Lambda functions connected.
+func (t *Tracer) EnableXRay(inject, extract bool) {
+	t.chainPropagator(&xrayPropagator{}, inject, extract)
+}
+
+// Propagation styles, the header formats used by the TextMap and HTTPHeaders
+// propagators, see WithPropagationStyle.
+const (
+	PropagationStyleDatadog      = "datadog"
+	PropagationStyleB3           = "b3"
+	PropagationStyleTraceContext = "tracecontext"
+	PropagationStyleXRay         = "xray"
+	PropagationStyleJaeger       = "jaeger"
+)
+
+// stylePropagator returns the Propagator of style for the TextMap format, or
+// for the HTTPHeaders one when httpHeaders is set.
+func (t *Tracer) stylePropagator(style string, httpHeaders bool) (Propagator, bool) {
+	switch strings.ToLower(strings.TrimSpace(style)) {
+	case PropagationStyleDatadog:
+		return &textMapPropagator{t: t, httpHeaders: httpHeaders}, true
+	case PropagationStyleB3:
+		return &b3Propagator{}, true
+	case PropagationStyleTraceContext:
+		return &traceContextPropagator{}, true
+	case PropagationStyleXRay:
+		return &xrayPropagator{}, true
+	case PropagationStyleJaeger:
+		return &jaegerPropagator{httpHeaders: httpHeaders}, true
+	}
+	return nil, false
+}
+
+// setPropagationStyles registers the propagators of the TextMap and
+// HTTPHeaders formats injecting all the inject styles, and extracting from
+// the first extract style finding a trace. The unknown styles are ignored.
+func (t *Tracer) setPropagationStyles(inject, extract []string) {
+	for _, format := range []interface{}{opentracing.TextMap, opentracing.HTTPHeaders} {
+		httpHeaders := format == opentracing.HTTPHeaders
+		t.RegisterPropagator(format, &propagatorChain{
+			injectors:  t.stylePropagators(inject, httpHeaders),
+			extractors: t.stylePropagators(extract, httpHeaders),
+		})
+	}
+}
+
+func (t *Tracer) stylePropagators(styles []string, httpHeaders bool) []Propagator {
+	var propagators []Propagator
+	for _, style := range styles {
+		p, ok := t.stylePropagator(style, httpHeaders)
+		if !ok {
+			t.logger.Printf("unknown propagation style %q", style)
+			continue
+		}
+		propagators = append(propagators, p)
+	}
+	return propagators
+}
+
+func (t *Tracer) chainPropagator(p Propagator, inject, extract bool) {
+	for _, format := range []interface{}{opentracing.TextMap, opentracing.HTTPHeaders} {
+		chain, ok := t.propagators[format].(*propagatorChain)
+		if !ok {
+			dd := t.propagators[format]
+			chain = &propagatorChain{
+				injectors:  []Propagator{dd},
+				extractors: []Propagator{dd},
+			}
+		}
+
+		if inject {
+			chain.injectors = append(chain.injectors, p)
+		}
+		if extract {
+			chain.extractors = append(chain.extractors, p)
+		}
+		t.RegisterPropagator(format, chain)
+	}
+}
+
+// propagatorChain injects using all of its injectors, and extracts from the
+// first extractor that finds a trace.
+type propagatorChain struct {
+	injectors  []Propagator
+	extractors []Propagator
+}
+
+func (c *propagatorChain) Inject(sc *SpanContext, carrier interface{}) error {
+	for _, p := range c.injectors {
+		if err := p.Inject(sc, carrier); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *propagatorChain) Extract(carrier interface{}) (opentracing.SpanContext, error) {
+	var sc opentracing.SpanContext
+	var err error
+	for _, p := range c.extractors {
+		sc, err = p.Extract(carrier)
+		if err != nil && err != opentracing.ErrSpanContextNotFound {
+			return nil, err
+		}
+
+		if ctx, ok := sc.(*SpanContext); ok && ctx.traceID != 0 {
+			return sc, nil
+		}
+	}
+	return sc, err
+}
+
 type textMapPropagator struct {
 	t *Tracer
+
+	// httpHeaders makes the keys case-insensitive on Extract, as
+	// HTTP headers might get canonicalized on the way.
+	httpHeaders bool
 }
 
-func (p *textMapPropagator) Inject(span *tracer.Span, carrier interface{}) error {
+func (p *textMapPropagator) Inject(sc *SpanContext, carrier interface{}) error {
 	tm, ok := carrier.(opentracing.TextMapWriter)
 	if !ok {
 		return opentracing.ErrInvalidCarrier
 	}
 
-	tm.Set(fieldSpanID, strconv.FormatUint(span.SpanID, 16))
-	tm.Set(fieldTraceID, strconv.FormatUint(span.TraceID, 16))
-	if span.ParentID > 0 {
-		tm.Set(fieldParentID, strconv.FormatUint(span.ParentID, 16))
+	tm.Set(fieldDatadogTraceID, p.t.idEncoding.format(sc.traceID))
+	tm.Set(fieldDatadogParentID, p.t.idEncoding.format(sc.spanID))
+	tm.Set(fieldDatadogSamplingPriority, strconv.Itoa(sc.samplingPriority()))
+	if sc.traceIDHigh != 0 {
+		tm.Set(fieldDatadogTags, traceIDHighKey+"="+fmt.Sprintf("%016x", sc.traceIDHigh))
+	}
+
+	if p.t.LegacyHeaders {
+		tm.Set(fieldSpanID, strconv.FormatUint(sc.spanID, 16))
+		tm.Set(fieldTraceID, strconv.FormatUint(sc.traceID, 16))
+		if sc.parentID > 0 {
+			tm.Set(fieldParentID, strconv.FormatUint(sc.parentID, 16))
+		}
+	}
+
+	for k, v := range sc.baggage {
+		tm.Set(baggagePrefix+k, v)
 	}
 
 	return nil
@@ -42,35 +266,232 @@ func (p *textMapPropagator) Extract(carrier interface{}) (opentracing.SpanContex
 		return nil, opentracing.ErrInvalidCarrier
 	}
 
+	h := &textMapHeaders{httpHeaders: p.httpHeaders, idEncoding: p.t.idEncoding, priority: PriorityAutoKeep}
+	if err := tm.ForeachKey(h.set); err != nil {
+		return nil, err
+	}
+
+	traceID, spanID, parentID, traceIDHigh := h.traceID, h.spanID, h.parentID, h.traceIDHigh
+	// Datadog's headers take precedence over the legacy ones.
+	if h.ddTraceID != 0 {
+		traceID, spanID, parentID = h.ddTraceID, h.ddSpanID, 0
+	} else {
+		traceIDHigh = 0
+	}
+	// The parent ID is optional, but not the trace and span ones: a context
+	// without them would corrupt the traces of the spans using it as parent.
+	if traceID == 0 || spanID == 0 {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+
+	span := &Span{
+		Span: &tracer.Span{
+			SpanID:   spanID,
+			ParentID: parentID,
+			TraceID:  traceID,
+			Sampled:  h.priority > 0,
+		},
+		baggage:     h.baggage,
+		traceIDHigh: traceIDHigh,
+	}
+	if h.hasPriority {
+		span.Span.SetMetric(samplingPriorityKey, float64(h.priority))
+	}
+
+	return span.Context(), nil
+}
+
+// textMapHeaders collects the headers extracted by the textMapPropagator.
+// They're gathered in a single struct as the ForeachKey handler would make
+// each of them escape to the heap otherwise.
+type textMapHeaders struct {
+	httpHeaders bool
+	idEncoding  IDEncoding
+
+	spanID, traceID, parentID        uint64
+	ddSpanID, ddTraceID, traceIDHigh uint64
+	priority                         int64
+	hasPriority                      bool
+	baggage                          map[string]string
+}
+
+func (h *textMapHeaders) set(k, v string) error {
+	key := k
+	if h.httpHeaders {
+		key = lowerKey(k)
+	}
+
 	var err error
-	var spanID, traceID, parentID uint64
-	err = tm.ForeachKey(func(k, v string) error {
-		switch strings.ToLower(k) {
-		case fieldSpanID:
-			spanID, err = strconv.ParseUint(v, 16, 64)
-			if err != nil {
-				return opentracing.ErrSpanContextCorrupted
-			}
-		case fieldTraceID:
-			traceID, err = strconv.ParseUint(v, 16, 64)
-			if err != nil {
-				return opentracing.ErrSpanContextCorrupted
-			}
-		case fieldParentID:
-			parentID, err = strconv.ParseUint(v, 16, 64)
-			if err != nil {
-				return opentracing.ErrSpanContextCorrupted
+	switch key {
+	case fieldDatadogTraceID:
+		h.ddTraceID, err = h.idEncoding.parse(v)
+	case fieldDatadogParentID:
+		h.ddSpanID, err = h.idEncoding.parse(v)
+	case fieldDatadogSamplingPriority:
+		h.priority, err = strconv.ParseInt(v, 10, 64)
+		h.hasPriority = true
+	case fieldDatadogTags:
+		h.traceIDHigh = parseTraceIDHigh(v)
+	case fieldSpanID:
+		h.spanID, err = strconv.ParseUint(v, 16, 64)
+	case fieldTraceID:
+		h.traceID, err = strconv.ParseUint(v, 16, 64)
+	case fieldParentID:
+		h.parentID, err = strconv.ParseUint(v, 16, 64)
+	default:
+		if strings.HasPrefix(key, baggagePrefix) {
+			if h.baggage == nil {
+				h.baggage = make(map[string]string)
 			}
+			h.baggage[strings.TrimPrefix(key, baggagePrefix)] = v
 		}
+	}
+	if err != nil {
+		return opentracing.ErrSpanContextCorrupted
+	}
+	return nil
+}
 
-		return nil
-	})
+// binaryPropagator encodes the context as a sequence of varints:
+// trace id, span id, parent id, sampling priority, baggage length followed
+// by each length-prefixed baggage key and value, and the upper 64 bits of
+// the trace id. The latter is optional, to decode the older payloads.
+type binaryPropagator struct {
+	t *Tracer
+}
+
+func (p *binaryPropagator) Inject(sc *SpanContext, carrier interface{}) error {
+	w, ok := carrier.(io.Writer)
+	if !ok {
+		return opentracing.ErrInvalidCarrier
+	}
 
-	span := Span{&tracer.Span{
-		SpanID:   spanID,
-		ParentID: parentID,
-		TraceID:  traceID,
-	}}
+	priority := int64(sc.samplingPriority())
+
+	buf := make([]byte, 0, 64)
+	buf = appendUvarint(buf, sc.traceID)
+	buf = appendUvarint(buf, sc.spanID)
+	buf = appendUvarint(buf, sc.parentID)
+	buf = appendVarint(buf, priority)
+	buf = appendUvarint(buf, uint64(len(sc.baggage)))
+	for k, v := range sc.baggage {
+		buf = appendUvarint(buf, uint64(len(k)))
+		buf = append(buf, k...)
+		buf = appendUvarint(buf, uint64(len(v)))
+		buf = append(buf, v...)
+	}
+	buf = appendUvarint(buf, sc.traceIDHigh)
+
+	_, err := w.Write(buf)
+	return err
+}
+
+func (p *binaryPropagator) Extract(carrier interface{}) (opentracing.SpanContext, error) {
+	r, ok := carrier.(io.Reader)
+	if !ok {
+		return nil, opentracing.ErrInvalidCarrier
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+
+	buf := bytes.NewReader(data)
+	var ids [3]uint64
+	for i := range ids {
+		if ids[i], err = binary.ReadUvarint(buf); err != nil {
+			return nil, opentracing.ErrSpanContextCorrupted
+		}
+	}
+	if ids[0] == 0 || ids[1] == 0 {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+
+	priority, err := binary.ReadVarint(buf)
+	if err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+
+	n, err := binary.ReadUvarint(buf)
+	if err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+
+	baggage := make(map[string]string)
+	for i := uint64(0); i < n; i++ {
+		k, err := readString(buf)
+		if err != nil {
+			return nil, opentracing.ErrSpanContextCorrupted
+		}
+		v, err := readString(buf)
+		if err != nil {
+			return nil, opentracing.ErrSpanContextCorrupted
+		}
+		baggage[k] = v
+	}
+
+	var traceIDHigh uint64
+	if buf.Len() > 0 {
+		if traceIDHigh, err = binary.ReadUvarint(buf); err != nil {
+			return nil, opentracing.ErrSpanContextCorrupted
+		}
+	}
+
+	span := &Span{
+		Span: &tracer.Span{
+			TraceID:  ids[0],
+			SpanID:   ids[1],
+			ParentID: ids[2],
+			Sampled:  priority > 0,
+		},
+		baggage:     baggage,
+		traceIDHigh: traceIDHigh,
+	}
+	span.Span.SetMetric(samplingPriorityKey, float64(priority))
+
+	return span.Context(), nil
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	return append(buf, tmp[:n]...)
+}
+
+func appendVarint(buf []byte, v int64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(tmp[:], v)
+	return append(buf, tmp[:n]...)
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	if n > uint64(r.Len()) {
+		return "", io.ErrUnexpectedEOF
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// InjectHTTPHeader injects sc into h, it's a shortcut for
+// Inject(sc, opentracing.HTTPHeaders, HeaderCarrier(h)).
+func (t *Tracer) InjectHTTPHeader(sc opentracing.SpanContext, h http.Header) error {
+	return t.Inject(sc, opentracing.HTTPHeaders, HeaderCarrier(h))
+}
 
-	return span.Context(), err
+// ExtractHTTPHeader extracts a SpanContext from h, it's a shortcut for
+// Extract(opentracing.HTTPHeaders, HeaderCarrier(h)).
+func (t *Tracer) ExtractHTTPHeader(h http.Header) (opentracing.SpanContext, error) {
+	return t.Extract(opentracing.HTTPHeaders, HeaderCarrier(h))
 }
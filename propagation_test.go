@@ -1,17 +1,22 @@
 package ddtracer
 
 import (
+	"bytes"
+	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/DataDog/dd-trace-go/tracer"
 	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestPropagationInject(t *testing.T) {
 	tr := NewTracer()
+	tr.(*Tracer).LegacyHeaders = true
 	span := tr.StartSpan("span").(*Span)
 	span.SpanID = 0xaa
 	span.TraceID = 0xbb
@@ -20,9 +25,20 @@ func TestPropagationInject(t *testing.T) {
 	err := tr.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header))
 	require.NoError(t, err)
 
+	assert.Equal(t, "187", req.Header.Get("X-Datadog-Trace-Id"))
+	assert.Equal(t, "170", req.Header.Get("X-Datadog-Parent-Id"))
+	assert.Equal(t, "1", req.Header.Get("X-Datadog-Sampling-Priority"))
+
 	assert.Equal(t, "aa", req.Header.Get("Dd-Trace-Spanid"))
 	assert.Equal(t, "bb", req.Header.Get("Dd-Trace-Traceid"))
 
+	t.Run("Without LegacyHeaders", func(t *testing.T) {
+		h := http.Header{}
+		require.NoError(t, NewTracer().Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(h)))
+		assert.Equal(t, "187", h.Get("X-Datadog-Trace-Id"))
+		assert.Empty(t, h.Get("Dd-Trace-Traceid"))
+	})
+
 	t.Run("Parent doesn't set ParentID", func(t *testing.T) {
 		assert.Empty(t, req.Header.Get("Dd-Trace-Parentid"))
 	})
@@ -47,13 +63,30 @@ func TestPropagationExtract(t *testing.T) {
 	require.NoError(t, err)
 
 	ctx := spanContext.(*SpanContext)
-	span, ok := tracer.SpanFromContext(ctx.ctx)
+	span, ok := ctx.ddSpan()
 	require.True(t, ok)
 
 	assert.Equal(t, uint64(0xaa), span.SpanID)
 	assert.Equal(t, uint64(0xbb), span.TraceID)
 	assert.Equal(t, uint64(0xcc), span.ParentID)
 
+	t.Run("Datadog headers", func(t *testing.T) {
+		h := http.Header{}
+		h.Set("X-Datadog-Trace-Id", "187")
+		h.Set("X-Datadog-Parent-Id", "170")
+		h.Set("X-Datadog-Sampling-Priority", "0")
+		h.Set("Dd-Trace-Traceid", "ff")
+
+		sc, err := tr.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(h))
+		require.NoError(t, err)
+
+		span, ok := sc.(*SpanContext).ddSpan()
+		require.True(t, ok)
+		assert.Equal(t, uint64(0xbb), span.TraceID)
+		assert.Equal(t, uint64(0xaa), span.SpanID)
+		assert.False(t, span.Sampled)
+	})
+
 	t.Run("CorruptedContext", func(t *testing.T) {
 		for _, key := range []string{"Spanid", "Parentid", "Traceid"} {
 			_, err := tr.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(http.Header{
@@ -61,6 +94,396 @@ func TestPropagationExtract(t *testing.T) {
 			}))
 			assert.Equal(t, opentracing.ErrSpanContextCorrupted, err)
 		}
+
+		for _, key := range []string{"Trace-Id", "Parent-Id", "Sampling-Priority"} {
+			_, err := tr.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(http.Header{
+				"X-Datadog-" + key: []string{"NaN"},
+			}))
+			assert.Equal(t, opentracing.ErrSpanContextCorrupted, err)
+		}
 	})
 
 }
+
+func TestPropagationIDEncoding(t *testing.T) {
+	extract := func(tr opentracing.Tracer, traceID, parentID string) (*SpanContext, error) {
+		sc, err := tr.Extract(opentracing.TextMap, opentracing.TextMapCarrier{
+			fieldDatadogTraceID:  traceID,
+			fieldDatadogParentID: parentID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return sc.(*SpanContext), nil
+	}
+
+	dec := NewTracerWithOptions()
+	hex := NewTracerWithOptions(WithPropagationIDEncoding(IDEncodingHex))
+
+	t.Run("Inject", func(t *testing.T) {
+		span := hex.StartSpan("op", WithTraceID(0xabc), WithSpanID(42))
+		carrier := opentracing.TextMapCarrier{}
+		require.NoError(t, hex.Inject(span.Context(), opentracing.TextMap, carrier))
+		assert.Equal(t, "0000000000000abc", carrier[fieldDatadogTraceID])
+		assert.Equal(t, "000000000000002a", carrier[fieldDatadogParentID])
+
+		// Both read it back.
+		for _, tr := range []opentracing.Tracer{dec, hex} {
+			sc, err := extract(tr, carrier[fieldDatadogTraceID], carrier[fieldDatadogParentID])
+			require.NoError(t, err)
+			assert.Equal(t, uint64(0xabc), sc.traceID)
+			assert.Equal(t, uint64(42), sc.spanID)
+		}
+	})
+
+	t.Run("Extract", func(t *testing.T) {
+		for _, tc := range []struct {
+			tr       opentracing.Tracer
+			id       string
+			expected uint64
+		}{
+			{dec, "187", 187},
+			{dec, "bb", 0xbb},
+			{dec, "0xBB", 0xbb},
+			{dec, "0000000000000187", 0x187},
+			{dec, "18446744073709551615", 1<<64 - 1},
+			{dec, "1234567890123456", 1234567890123456},
+			{hex, "0000000000000187", 0x187},
+			{hex, "1234567890123456", 0x1234567890123456},
+			{hex, "0x187", 0x187},
+			{hex, "ffffffffffffffff", 1<<64 - 1},
+			// The decimal IDs of the DataDog libraries, not padded.
+			{hex, "187", 187},
+			{hex, "1234567890123", 1234567890123},
+			{hex, "18446744073709551615", 1<<64 - 1},
+		} {
+			sc, err := extract(tc.tr, tc.id, "1")
+			require.NoError(t, err, tc.id)
+			assert.Equal(t, tc.expected, sc.traceID, tc.id)
+		}
+
+		for _, id := range []string{"0x", "xyz", "10000000000000000000000"} {
+			_, err := extract(dec, id, "1")
+			assert.Equal(t, opentracing.ErrSpanContextCorrupted, err, id)
+		}
+	})
+}
+
+func TestPropagationHTTPHeader(t *testing.T) {
+	tr := NewTracer().(*Tracer)
+	span := tr.StartSpan("client").(*Span)
+
+	var got *tracer.Span
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		sc, err := tr.ExtractHTTPHeader(req.Header)
+		require.NoError(t, err)
+
+		got, _ = sc.(*SpanContext).ddSpan()
+	}))
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+	require.NoError(t, tr.InjectHTTPHeader(span.Context(), req.Header))
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.NotNil(t, got)
+	assert.Equal(t, span.SpanID, got.SpanID)
+	assert.Equal(t, span.TraceID, got.TraceID)
+}
+
+func TestPropagationInjectContexts(t *testing.T) {
+	tr := NewTracer()
+
+	t.Run("Live span", func(t *testing.T) {
+		span := tr.StartSpan("span").(*Span)
+		h := http.Header{}
+		require.NoError(t, tr.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(h)))
+		assert.NotEmpty(t, h.Get("X-Datadog-Trace-Id"))
+	})
+
+	t.Run("Extracted context", func(t *testing.T) {
+		in := http.Header{}
+		in.Set("Dd-Trace-Spanid", "aa")
+		in.Set("Dd-Trace-Traceid", "bb")
+		sc, err := tr.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(in))
+		require.NoError(t, err)
+
+		out := http.Header{}
+		require.NoError(t, tr.Inject(sc, opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(out)))
+		assert.Equal(t, "170", out.Get("X-Datadog-Parent-Id"))
+		assert.Equal(t, "187", out.Get("X-Datadog-Trace-Id"))
+	})
+
+	t.Run("Context without live span", func(t *testing.T) {
+		sc := &SpanContext{traceID: 0xbb, spanID: 0xaa}
+
+		h := http.Header{}
+		require.NoError(t, tr.Inject(sc, opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(h)))
+		assert.Equal(t, "170", h.Get("X-Datadog-Parent-Id"))
+		assert.Equal(t, "187", h.Get("X-Datadog-Trace-Id"))
+
+		err := tr.Inject(&SpanContext{}, opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(h))
+		assert.Equal(t, opentracing.ErrInvalidSpanContext, err)
+	})
+
+	t.Run("Foreign context", func(t *testing.T) {
+		span := mocktracer.New().StartSpan("span")
+		err := tr.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(http.Header{}))
+		assert.Equal(t, opentracing.ErrInvalidSpanContext, err)
+	})
+}
+
+func TestPropagationBaggage(t *testing.T) {
+	tr := NewTracer()
+	span := tr.StartSpan("span")
+	span.SetBaggageItem("user", "42")
+
+	h := http.Header{}
+	require.NoError(t, tr.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(h)))
+	assert.Equal(t, "42", h.Get("Ot-Baggage-User"))
+
+	sc, err := tr.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(h))
+	require.NoError(t, err)
+
+	child := tr.StartSpan("child", opentracing.ChildOf(sc))
+	assert.Equal(t, "42", child.BaggageItem("user"))
+}
+
+func TestPropagationBinary(t *testing.T) {
+	tr := NewTracer()
+	span := tr.StartSpan("span").(*Span)
+	span.ParentID = 0xcc
+	span.SetBaggageItem("user", "42")
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, tr.Inject(span.Context(), opentracing.Binary, buf))
+
+	sc, err := tr.Extract(opentracing.Binary, buf)
+	require.NoError(t, err)
+
+	ctx := sc.(*SpanContext)
+	assert.Equal(t, span.TraceID, ctx.traceID)
+	assert.Equal(t, span.SpanID, ctx.spanID)
+	assert.Equal(t, uint64(0xcc), ctx.parentID)
+	assert.Equal(t, map[string]string{"user": "42"}, ctx.baggage)
+
+	t.Run("Empty carrier", func(t *testing.T) {
+		_, err := tr.Extract(opentracing.Binary, &bytes.Buffer{})
+		assert.Equal(t, opentracing.ErrSpanContextNotFound, err)
+	})
+
+	t.Run("Corrupted carrier", func(t *testing.T) {
+		_, err := tr.Extract(opentracing.Binary, bytes.NewBuffer([]byte{0xff}))
+		assert.Equal(t, opentracing.ErrSpanContextCorrupted, err)
+	})
+
+	t.Run("Zero IDs", func(t *testing.T) {
+		for _, ids := range [][]byte{{0, 1}, {1, 0}} {
+			data := append(ids, 0, 0, 0, 0)
+			_, err := tr.Extract(opentracing.Binary, bytes.NewBuffer(data))
+			assert.Equal(t, opentracing.ErrSpanContextNotFound, err)
+		}
+	})
+
+	t.Run("Invalid carrier", func(t *testing.T) {
+		err := tr.Inject(span.Context(), opentracing.Binary, "foo")
+		assert.Equal(t, opentracing.ErrInvalidCarrier, err)
+
+		_, err = tr.Extract(opentracing.Binary, "foo")
+		assert.Equal(t, opentracing.ErrInvalidCarrier, err)
+	})
+}
+
+func TestPropagationTextMap(t *testing.T) {
+	tr := NewTracer()
+	span := tr.StartSpan("span").(*Span)
+	span.SetBaggageItem("UserID", "42")
+
+	carrier := opentracing.TextMapCarrier{}
+	require.NoError(t, tr.Inject(span.Context(), opentracing.TextMap, carrier))
+	assert.Equal(t, "42", carrier["ot-baggage-UserID"])
+
+	sc, err := tr.Extract(opentracing.TextMap, carrier)
+	require.NoError(t, err)
+
+	ctx := sc.(*SpanContext)
+	assert.Equal(t, span.TraceID, ctx.traceID)
+	assert.Equal(t, span.SpanID, ctx.spanID)
+	assert.Equal(t, map[string]string{"UserID": "42"}, ctx.baggage)
+}
+
+func TestPropagationExtractedChild(t *testing.T) {
+	env := newEnv(t)
+	defer env.close()
+	tr := env.tr.(*Tracer)
+
+	h := http.Header{}
+	h.Set("X-Datadog-Trace-Id", "187")
+	h.Set("X-Datadog-Parent-Id", "170")
+
+	sc, err := tr.ExtractHTTPHeader(h)
+	require.NoError(t, err)
+
+	span := tr.StartSpan("server", opentracing.ChildOf(sc)).(*Span)
+	assert.Equal(t, uint64(0xbb), span.TraceID)
+	assert.Equal(t, uint64(0xaa), span.ParentID)
+	assert.Equal(t, DefaultService, span.Service)
+
+	child := tr.StartSpan("child", opentracing.ChildOf(span.Context())).(*Span)
+	child.Finish()
+	span.Finish()
+
+	require.NoError(t, tr.FlushTraces())
+	require.Len(t, env.reqs, 1)
+
+	var spans [][]*tracer.Span
+	require.NoError(t, json.NewDecoder(env.reqs[0].Body).Decode(&spans))
+	require.Len(t, spans, 1)
+	require.Len(t, spans[0], 2)
+	for _, s := range spans[0] {
+		assert.Equal(t, uint64(0xbb), s.TraceID)
+	}
+
+	t.Run("Empty context creates a root span", func(t *testing.T) {
+		sc, err := tr.ExtractHTTPHeader(http.Header{})
+		assert.Equal(t, opentracing.ErrSpanContextNotFound, err)
+
+		span := tr.StartSpan("server", opentracing.ChildOf(sc)).(*Span)
+		assert.Equal(t, span.SpanID, span.TraceID)
+		assert.Zero(t, span.ParentID)
+	})
+}
+
+func TestPropagationExtractPartialHeaders(t *testing.T) {
+	tr := NewTracer().(*Tracer)
+
+	for name, h := range map[string]http.Header{
+		"Empty":              {},
+		"Baggage only":       {"Ot-Baggage-User": {"jane"}},
+		"Priority only":      {"X-Datadog-Sampling-Priority": {"1"}},
+		"Missing span ID":    {"X-Datadog-Trace-Id": {"1"}},
+		"Missing trace ID":   {"X-Datadog-Parent-Id": {"2"}},
+		"Legacy, no span ID": {"Dd-Trace-Traceid": {"bb"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			sc, err := tr.ExtractHTTPHeader(h)
+			assert.Equal(t, opentracing.ErrSpanContextNotFound, err)
+			assert.Nil(t, sc)
+		})
+	}
+
+	t.Run("Missing parent ID", func(t *testing.T) {
+		sc, err := tr.ExtractHTTPHeader(http.Header{
+			"Dd-Trace-Traceid": {"bb"},
+			"Dd-Trace-Spanid":  {"aa"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, uint64(0xbb), sc.(*SpanContext).traceID)
+		assert.Zero(t, sc.(*SpanContext).parentID)
+	})
+
+	t.Run("Corrupted", func(t *testing.T) {
+		sc, err := tr.ExtractHTTPHeader(http.Header{
+			"X-Datadog-Trace-Id":  {"1"},
+			"X-Datadog-Parent-Id": {"z"},
+		})
+		assert.Equal(t, opentracing.ErrSpanContextCorrupted, err)
+		assert.Nil(t, sc)
+	})
+}
+
+func TestPropagationStyleInjectExtract(t *testing.T) {
+	tr := NewTracerWithOptions(
+		WithPropagationStyleInject([]string{"datadog", "b3", "tracecontext"}),
+		WithPropagationStyleExtract([]string{"tracecontext", "datadog"}),
+	).(*Tracer)
+
+	span := tr.StartSpan("root").(*Span)
+	h := http.Header{}
+	require.NoError(t, tr.InjectHTTPHeader(span.Context(), h))
+	assert.NotEmpty(t, h.Get("X-Datadog-Trace-Id"))
+	assert.NotEmpty(t, h.Get("X-B3-Traceid"))
+	assert.NotEmpty(t, h.Get("Traceparent"))
+
+	t.Run("Priority", func(t *testing.T) {
+		sc, err := tr.ExtractHTTPHeader(http.Header{
+			"X-Datadog-Trace-Id":  []string{"1"},
+			"X-Datadog-Parent-Id": []string{"2"},
+			"Traceparent":         []string{"00-000000000000000000000000000000bb-00000000000000cc-01"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, uint64(0xbb), sc.(*SpanContext).traceID)
+	})
+
+	t.Run("Fallback", func(t *testing.T) {
+		sc, err := tr.ExtractHTTPHeader(http.Header{
+			"X-Datadog-Trace-Id":  []string{"1"},
+			"X-Datadog-Parent-Id": []string{"2"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), sc.(*SpanContext).traceID)
+	})
+
+	t.Run("Not configured", func(t *testing.T) {
+		_, err := tr.ExtractHTTPHeader(http.Header{
+			"X-B3-Traceid": []string{"bb"},
+			"X-B3-Spanid":  []string{"cc"},
+		})
+		assert.Equal(t, opentracing.ErrSpanContextNotFound, err)
+	})
+
+	t.Run("Default extract", func(t *testing.T) {
+		tr := NewTracerWithOptions(WithPropagationStyleInject([]string{"b3"})).(*Tracer)
+
+		h := http.Header{}
+		require.NoError(t, tr.InjectHTTPHeader(tr.StartSpan("root").Context(), h))
+		assert.Empty(t, h.Get("X-Datadog-Trace-Id"))
+		assert.NotEmpty(t, h.Get("X-B3-Traceid"))
+
+		sc, err := tr.ExtractHTTPHeader(http.Header{
+			"X-Datadog-Trace-Id":  []string{"1"},
+			"X-Datadog-Parent-Id": []string{"2"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), sc.(*SpanContext).traceID)
+	})
+}
+
+func BenchmarkInjectTextMap(b *testing.B) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}))
+	sc := tr.StartSpan("op").Context()
+	carrier := opentracing.TextMapCarrier{}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tr.Inject(sc, opentracing.TextMap, carrier)
+	}
+}
+
+func BenchmarkInjectHTTPHeaders(b *testing.B) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}))
+	sc := tr.StartSpan("op").Context()
+	carrier := opentracing.HTTPHeadersCarrier(http.Header{})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tr.Inject(sc, opentracing.HTTPHeaders, carrier)
+	}
+}
+
+func BenchmarkExtractTextMap(b *testing.B) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}))
+	carrier := opentracing.TextMapCarrier{}
+	require.NoError(b, tr.Inject(tr.StartSpan("op").Context(), opentracing.TextMap, carrier))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tr.Extract(opentracing.TextMap, carrier)
+	}
+}
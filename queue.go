@@ -0,0 +1,190 @@
+package ddtracer
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+)
+
+// driverFlushInterval is the interval at which the DataDog's tracer hands
+// over the finished traces to its transport.
+const driverFlushInterval = 2 * time.Second
+
+// DefaultFlushInterval is the flush interval of the Tracers configured with
+// WithMaxQueueSize, WithMaxPayloadBytes or WithDropPolicy alone.
+const DefaultFlushInterval = driverFlushInterval
+
+// DropPolicy decides what happens to the finished traces once the queue
+// set by WithMaxQueueSize is full.
+type DropPolicy int
+
+const (
+	// DropOldest drops the oldest queued traces to make room for the new ones.
+	DropOldest DropPolicy = iota
+	// Block holds the new traces until the queue is flushed. Meanwhile the
+	// finished spans are kept by the DataDog's tracer, which replaces them at
+	// random once its own buffer is full.
+	Block
+)
+
+// queueTransport queues the traces handed over by the DataDog's tracer,
+// sending them in batches every flush interval.
+type queueTransport struct {
+	tracer.Transport
+	stats *stats
+
+	maxSpans   int
+	maxPayload int
+	policy     DropPolicy
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	traces [][]*tracer.Span
+	spans  int
+	closed bool
+
+	sendMu sync.Mutex
+	exit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// newQueueTransport returns a queue in front of tr, holding up to maxSpans
+// spans and sending batches of up to maxPayload bytes, unlimited when zero.
+func newQueueTransport(tr tracer.Transport, stats *stats, maxSpans, maxPayload int, policy DropPolicy) *queueTransport {
+	q := &queueTransport{
+		Transport:  tr,
+		stats:      stats,
+		maxSpans:   maxSpans,
+		maxPayload: maxPayload,
+		policy:     policy,
+		exit:       make(chan struct{}),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// start flushes the queue every interval. As the DataDog's tracer hands over
+// the traces every driverFlushInterval, shorter intervals take them with feed.
+func (q *queueTransport) start(interval time.Duration, feed func() error) {
+	if interval <= 0 {
+		interval = DefaultFlushInterval
+	}
+
+	q.wg.Add(1)
+	go q.every(interval, func() { q.flush() })
+	if interval < driverFlushInterval {
+		q.wg.Add(1)
+		go q.every(interval, func() { feed() })
+	}
+}
+
+func (q *queueTransport) every(interval time.Duration, f func()) {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			f()
+		case <-q.exit:
+			return
+		}
+	}
+}
+
+// SendTraces queues the traces, it never fails.
+func (q *queueTransport) SendTraces(traces [][]*tracer.Span) (*http.Response, error) {
+	q.mu.Lock()
+	for _, trace := range traces {
+		if q.maxSpans > 0 && len(trace) > q.maxSpans {
+			atomic.AddUint64(&q.stats.spansDropped, uint64(len(trace)))
+			continue
+		}
+		for q.maxSpans > 0 && q.spans+len(trace) > q.maxSpans {
+			if q.policy == Block && !q.closed {
+				q.cond.Wait()
+				continue
+			}
+			oldest := q.traces[0]
+			q.traces = q.traces[1:]
+			q.spans -= len(oldest)
+			atomic.AddUint64(&q.stats.spansDropped, uint64(len(oldest)))
+		}
+		q.traces = append(q.traces, trace)
+		q.spans += len(trace)
+	}
+	q.mu.Unlock()
+
+	return &http.Response{StatusCode: http.StatusOK}, nil
+}
+
+// queued returns the number of queued spans.
+func (q *queueTransport) queued() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.spans
+}
+
+// flush sends the queued traces, returning the last error.
+func (q *queueTransport) flush() error {
+	q.mu.Lock()
+	traces := q.traces
+	q.traces, q.spans = nil, 0
+	q.cond.Broadcast()
+	q.mu.Unlock()
+
+	q.sendMu.Lock()
+	defer q.sendMu.Unlock()
+
+	var err error
+	for _, batch := range q.batches(traces) {
+		if _, e := q.Transport.SendTraces(batch); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// batches splits traces in batches of up to maxPayload bytes, estimated by
+// their JSON encoding.
+func (q *queueTransport) batches(traces [][]*tracer.Span) [][][]*tracer.Span {
+	if len(traces) == 0 {
+		return nil
+	}
+	if q.maxPayload <= 0 {
+		return [][][]*tracer.Span{traces}
+	}
+
+	var (
+		batches [][][]*tracer.Span
+		batch   [][]*tracer.Span
+		size    int
+	)
+	for _, trace := range traces {
+		b, _ := json.Marshal(trace)
+		if len(batch) > 0 && size+len(b) > q.maxPayload {
+			batches = append(batches, batch)
+			batch, size = nil, 0
+		}
+		batch = append(batch, trace)
+		size += len(b)
+	}
+	return append(batches, batch)
+}
+
+// close stops flushing the queue and sends the remaining traces.
+func (q *queueTransport) close() error {
+	q.mu.Lock()
+	q.closed = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+
+	close(q.exit)
+	q.wg.Wait()
+	return q.flush()
+}
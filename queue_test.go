@@ -0,0 +1,133 @@
+package ddtracer
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingTransport keeps the traces it's sent.
+type recordingTransport struct {
+	discardTransport
+
+	mu      sync.Mutex
+	batches [][][]*tracer.Span
+}
+
+func (t *recordingTransport) SendTraces(traces [][]*tracer.Span) (*http.Response, error) {
+	t.mu.Lock()
+	t.batches = append(t.batches, traces)
+	t.mu.Unlock()
+	return &http.Response{StatusCode: http.StatusOK}, nil
+}
+
+func (t *recordingTransport) traces() [][]*tracer.Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var traces [][]*tracer.Span
+	for _, b := range t.batches {
+		traces = append(traces, b...)
+	}
+	return traces
+}
+
+func spans(name string, n int) []*tracer.Span {
+	trace := make([]*tracer.Span, n)
+	for i := range trace {
+		trace[i] = &tracer.Span{Name: name}
+	}
+	return trace
+}
+
+func TestQueueTransport(t *testing.T) {
+	t.Run("Drop oldest", func(t *testing.T) {
+		rec, st := &recordingTransport{}, &stats{}
+		q := newQueueTransport(rec, st, 3, 0, DropOldest)
+
+		q.SendTraces([][]*tracer.Span{spans("a", 2), spans("b", 2), spans("c", 4)})
+		require.NoError(t, q.flush())
+
+		traces := rec.traces()
+		require.Len(t, traces, 1)
+		assert.Equal(t, "b", traces[0][0].Name)
+		assert.Equal(t, uint64(6), st.spansDropped)
+	})
+
+	t.Run("Block", func(t *testing.T) {
+		rec := &recordingTransport{}
+		q := newQueueTransport(rec, &stats{}, 2, 0, Block)
+		q.SendTraces([][]*tracer.Span{spans("a", 2)})
+
+		done := make(chan struct{})
+		go func() {
+			q.SendTraces([][]*tracer.Span{spans("b", 2)})
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("SendTraces didn't block")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		require.NoError(t, q.flush())
+		<-done
+		require.NoError(t, q.close())
+		assert.Len(t, rec.traces(), 2)
+	})
+
+	t.Run("Max payload", func(t *testing.T) {
+		b, _ := json.Marshal(spans("a", 1))
+		rec := &recordingTransport{}
+		q := newQueueTransport(rec, &stats{}, 0, 2*len(b), DropOldest)
+
+		q.SendTraces([][]*tracer.Span{spans("a", 1), spans("b", 1), spans("c", 1)})
+		require.NoError(t, q.flush())
+		require.Len(t, rec.batches, 2)
+		assert.Len(t, rec.batches[0], 2)
+		assert.Len(t, rec.batches[1], 1)
+	})
+}
+
+func TestQueueOptions(t *testing.T) {
+	t.Run("Flush interval", func(t *testing.T) {
+		rec := &recordingTransport{}
+		tr := NewTracerWithOptions(WithTransport(rec), WithFlushInterval(10*time.Millisecond))
+		defer tr.Close()
+
+		tr.StartSpan("test").Finish()
+		for i := 0; i < 100 && len(rec.traces()) == 0; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		require.Len(t, rec.traces(), 1)
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		rec := &recordingTransport{}
+		tr := NewTracerWithOptions(WithTransport(rec), WithFlushInterval(time.Hour), WithMaxQueueSize(10))
+
+		tr.StartSpan("test").Finish()
+		require.NoError(t, tr.Close())
+		require.Len(t, rec.traces(), 1)
+	})
+
+	t.Run("Dropped spans", func(t *testing.T) {
+		rec := &recordingTransport{}
+		tr := NewTracerWithOptions(WithTransport(rec), WithFlushInterval(time.Hour), WithMaxQueueSize(1)).(*Tracer)
+		defer tr.Close()
+
+		for i := 0; i < 3; i++ {
+			tr.StartSpan("test").Finish()
+		}
+		require.NoError(t, tr.FlushTraces())
+		assert.Len(t, rec.traces(), 1)
+		assert.Equal(t, uint64(2), tr.Stats().SpansDropped)
+	})
+}
@@ -0,0 +1,93 @@
+package ddtracer
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// limitedSpansKey prefixes the metrics counting, on the parent spans, the
+// children of each operation not started because of WithSpanRateLimit.
+const limitedSpansKey = "_dd.limited_spans."
+
+// spanLimiter caps the spans started per operation name and second.
+type spanLimiter struct {
+	perSecond float64
+
+	mu         sync.Mutex
+	operations map[string]*rateLimitedSampler
+}
+
+func newSpanLimiter(perSecond float64) *spanLimiter {
+	return &spanLimiter{
+		perSecond:  perSecond,
+		operations: make(map[string]*rateLimitedSampler),
+	}
+}
+
+// allow reports whether a span of op can be started.
+func (l *spanLimiter) allow(op string) bool {
+	l.mu.Lock()
+	s, ok := l.operations[op]
+	if !ok {
+		s = RateLimitedSampler(l.perSecond).(*rateLimitedSampler)
+		l.operations[op] = s
+	}
+	l.mu.Unlock()
+
+	return s.allow()
+}
+
+// limitedSpan returns the span standing for a span of op not started by the
+// spanLimiter. It's counted by its parent, when it's a live span, and it's
+// context-only: its children are attached to the parent and it's not sent
+// to the agent.
+func (t *Tracer) limitedSpan(op string, opts *opentracing.StartSpanOptions) *Span {
+	atomic.AddUint64(&t.stats.spansLimited, 1)
+
+	for _, ref := range opts.References {
+		p, ok := ref.ReferencedContext.(*SpanContext)
+		if !ok {
+			continue
+		}
+		if p.span != nil {
+			p.span.countLimited(op)
+		}
+
+		span := &Span{
+			Span: &tracer.Span{
+				Name:     op,
+				TraceID:  p.traceID,
+				SpanID:   p.spanID,
+				ParentID: p.parentID,
+				Sampled:  p.sampled,
+			},
+			tr:          t,
+			traceState:  p.traceState,
+			traceIDHigh: p.traceIDHigh,
+		}
+		// The baggage is copied, the context of the parent being immutable.
+		for k, v := range p.baggage {
+			span.SetBaggageItem(k, v)
+		}
+		if p.hasPriority {
+			span.Span.SetMetric(samplingPriorityKey, float64(p.priority))
+		}
+		return span
+	}
+
+	return &Span{Span: &tracer.Span{Name: op}, tr: t}
+}
+
+// countLimited counts a child of op not started because of the rate limit.
+func (s *Span) countLimited(op string) {
+	if !s.lock() {
+		return
+	}
+	defer s.mu.Unlock()
+
+	key := limitedSpansKey + op
+	s.setMetric(key, s.Metrics[key]+1)
+}
@@ -0,0 +1,84 @@
+package ddtracer
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpanRateLimit(t *testing.T) {
+	rec := &recordingTransport{}
+	tr := NewTracerWithOptions(WithTransport(rec), WithSpanRateLimit(2)).(*Tracer)
+
+	parent := tr.StartSpan("http.request").(*Span)
+	for i := 0; i < 5; i++ {
+		child := tr.StartSpan("cache.get", opentracing.ChildOf(parent.Context()))
+		grandchild := tr.StartSpan("cache.decode", opentracing.ChildOf(child.Context())).(*Span)
+		assert.Equal(t, parent.TraceID, grandchild.TraceID)
+		if i >= 2 {
+			// Both are limited, standing for the parent.
+			assert.Equal(t, parent.SpanID, grandchild.SpanID)
+		}
+		grandchild.Finish()
+		child.Finish()
+	}
+	parent.Finish()
+	require.NoError(t, tr.FlushTraces())
+
+	assert.Equal(t, float64(3), parent.Metrics[limitedSpansKey+"cache.get"])
+	assert.NotContains(t, parent.Metrics, limitedSpansKey+"cache.decode")
+	assert.EqualValues(t, 6, tr.Stats().SpansLimited)
+
+	var spans int
+	for _, trace := range rec.traces() {
+		spans += len(trace)
+	}
+	assert.Equal(t, 5, spans)
+}
+
+func TestSpanRateLimitRoot(t *testing.T) {
+	tr := NewTracerWithOptions(WithTransport(&recordingTransport{}), WithSpanRateLimit(1)).(*Tracer)
+
+	assert.False(t, tr.StartSpan("op").(*Span).contextOnly())
+	limited := tr.StartSpan("op").(*Span)
+	assert.True(t, limited.contextOnly())
+	assert.Equal(t, tr, limited.Tracer())
+
+	child := tr.StartSpan("child", opentracing.ChildOf(limited.Context())).(*Span)
+	assert.Zero(t, child.ParentID)
+	assert.NotZero(t, child.TraceID)
+}
+
+func TestSpanRateLimitBaggage(t *testing.T) {
+	tr := NewTracerWithOptions(WithTransport(&recordingTransport{}), WithSpanRateLimit(1)).(*Tracer)
+
+	parent := tr.StartSpan("parent")
+	parent.SetBaggageItem("user", "alice")
+	ctx := parent.Context()
+	tr.StartSpan("child", opentracing.ChildOf(ctx)).Finish()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			limited := tr.StartSpan("child", opentracing.ChildOf(ctx)).(*Span)
+			assert.True(t, limited.contextOnly())
+			assert.Equal(t, "alice", limited.BaggageItem("user"))
+			limited.SetBaggageItem("user", "bob")
+			limited.SetBaggageItem(fmt.Sprint("key", i), "value")
+		}(i)
+	}
+	wg.Wait()
+
+	baggage := make(map[string]string)
+	ctx.ForeachBaggageItem(func(k, v string) bool {
+		baggage[k] = v
+		return true
+	})
+	assert.Equal(t, map[string]string{"user": "alice"}, baggage)
+}
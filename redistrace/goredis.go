@@ -0,0 +1,61 @@
+package redistrace
+
+import (
+	"context"
+	"strings"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	"github.com/go-redis/redis/v8"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// NewHook returns a go-redis hook tracing the commands and pipelines,
+// i.e. client.AddHook(redistrace.NewHook(tracer)).
+func NewHook(tr opentracing.Tracer, opts ...Option) redis.Hook {
+	return &hook{newTracer(tr, opts)}
+}
+
+type hook struct {
+	t *tracer
+}
+
+func (h *hook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	span := h.t.start(ctx, commandOperation, cmd.Name())
+	span.SetTag(TagArgsLength, len(cmd.Args()))
+	return ddtracer.ContextWithSpan(ctx, span), nil
+}
+
+func (h *hook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		finish(span, cmd.Err(), redis.Nil)
+	}
+	return nil
+}
+
+func (h *hook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	names := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		names[i] = cmd.Name()
+	}
+
+	span := h.t.start(ctx, pipelineOperation, strings.Join(names, " "))
+	span.SetTag(TagPipelineLength, len(cmds))
+	return ddtracer.ContextWithSpan(ctx, span), nil
+}
+
+func (h *hook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return nil
+	}
+
+	var err error
+	for _, cmd := range cmds {
+		if cmd.Err() != nil && cmd.Err() != redis.Nil {
+			err = cmd.Err()
+			break
+		}
+	}
+	finish(span, err, redis.Nil)
+	return nil
+}
@@ -0,0 +1,58 @@
+package redistrace
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHook(t *testing.T) {
+	tr := newRecordingTracer()
+	parent := tr.StartSpan("parent").(*ddtracer.Span)
+	ctx := ddtracer.ContextWithSpan(context.Background(), parent)
+
+	h := NewHook(tr)
+	get := redis.NewCmd(ctx, "get", "key")
+	cctx, err := h.BeforeProcess(ctx, get)
+	require.NoError(t, err)
+	get.SetErr(redis.Nil)
+	require.NoError(t, h.AfterProcess(cctx, get))
+
+	require.Len(t, tr.spans, 2)
+	span := tr.spans[1]
+	assert.Equal(t, "redis.command", span.Name)
+	assert.Equal(t, "GET", span.Resource)
+	assert.Equal(t, "redis", span.Service)
+	assert.Equal(t, "cache", span.Type)
+	assert.Equal(t, float64(2), span.Metrics[TagArgsLength])
+	assert.Equal(t, parent.SpanID, span.ParentID)
+	assert.Equal(t, int32(0), span.Error)
+}
+
+func TestHookPipeline(t *testing.T) {
+	tr := newRecordingTracer()
+	ctx := context.Background()
+
+	h := NewHook(tr, ServiceName("sessions"))
+	cmds := []redis.Cmder{
+		redis.NewCmd(ctx, "set", "key", "value"),
+		redis.NewCmd(ctx, "expire", "key", 10),
+	}
+	cctx, err := h.BeforeProcessPipeline(ctx, cmds)
+	require.NoError(t, err)
+	cmds[1].SetErr(errors.New("boom"))
+	require.NoError(t, h.AfterProcessPipeline(cctx, cmds))
+
+	require.Len(t, tr.spans, 1)
+	span := tr.spans[0]
+	assert.Equal(t, "redis.pipeline", span.Name)
+	assert.Equal(t, "SET EXPIRE", span.Resource)
+	assert.Equal(t, "sessions", span.Service)
+	assert.Equal(t, float64(2), span.Metrics[TagPipelineLength])
+	assert.Equal(t, int32(1), span.Error)
+}
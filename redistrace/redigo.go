@@ -0,0 +1,46 @@
+package redistrace
+
+import (
+	"context"
+
+	"github.com/gomodule/redigo/redis"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// Conn is a redigo connection tracing the commands sent with Do.
+type Conn struct {
+	redis.Conn
+	t   *tracer
+	ctx context.Context
+}
+
+// WrapConn returns c tracing its commands, use WithContext to set their parent.
+func WrapConn(c redis.Conn, tr opentracing.Tracer, opts ...Option) *Conn {
+	return &Conn{
+		Conn: c,
+		t:    newTracer(tr, opts),
+		ctx:  context.Background(),
+	}
+}
+
+// WithContext returns a copy of c whose spans are children of the span in ctx.
+func (c *Conn) WithContext(ctx context.Context) *Conn {
+	cc := *c
+	cc.ctx = ctx
+	return &cc
+}
+
+// Do traces the command unless commandName is empty, which only flushes the
+// pending commands and receives their replies.
+func (c *Conn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	if commandName == "" {
+		return c.Conn.Do(commandName, args...)
+	}
+
+	span := c.t.start(c.ctx, commandOperation, commandName)
+	span.SetTag(TagArgsLength, len(args))
+
+	reply, err := c.Conn.Do(commandName, args...)
+	finish(span, err, redis.ErrNil)
+	return reply, err
+}
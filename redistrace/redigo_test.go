@@ -0,0 +1,47 @@
+package redistrace
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeConn struct {
+	redis.Conn
+}
+
+func (fakeConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	if commandName == "FAIL" {
+		return nil, errors.New("boom")
+	}
+	return "OK", nil
+}
+
+func TestConn(t *testing.T) {
+	tr := newRecordingTracer()
+	parent := tr.StartSpan("parent").(*ddtracer.Span)
+	ctx := ddtracer.ContextWithSpan(context.Background(), parent)
+
+	c := WrapConn(fakeConn{}, tr).WithContext(ctx)
+	reply, err := c.Do("set", "key", "value")
+	require.NoError(t, err)
+	assert.Equal(t, "OK", reply)
+	_, err = c.Do("FAIL")
+	assert.Error(t, err)
+	c.Do("")
+
+	require.Len(t, tr.spans, 3)
+	set, fail := tr.spans[1], tr.spans[2]
+	assert.Equal(t, "SET", set.Resource)
+	assert.Equal(t, "cache", set.Type)
+	assert.Equal(t, float64(2), set.Metrics[TagArgsLength])
+	assert.Equal(t, parent.SpanID, set.ParentID)
+	assert.Equal(t, int32(0), set.Error)
+	assert.Equal(t, "FAIL", fail.Resource)
+	assert.Equal(t, int32(1), fail.Error)
+}
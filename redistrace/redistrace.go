@@ -0,0 +1,65 @@
+// Package redistrace traces the commands of the go-redis and redigo clients
+// with cache spans, child of the span in the context.
+package redistrace
+
+import (
+	"context"
+	"strings"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+const (
+	defaultService = "redis"
+
+	commandOperation  = "redis.command"
+	pipelineOperation = "redis.pipeline"
+
+	// TagArgsLength is the number of arguments of the command.
+	TagArgsLength = "redis.args_length"
+	// TagPipelineLength is the number of commands of the pipeline.
+	TagPipelineLength = "redis.pipeline_length"
+)
+
+// Option configures the instrumentation.
+type Option func(*tracer)
+
+// ServiceName sets the service of the spans, "redis" by default.
+func ServiceName(name string) Option {
+	return func(t *tracer) {
+		t.service = name
+	}
+}
+
+type tracer struct {
+	tr      opentracing.Tracer
+	service string
+}
+
+func newTracer(tr opentracing.Tracer, opts []Option) *tracer {
+	t := &tracer{tr: tr, service: defaultService}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// start starts a span child of the span in ctx, with resource as resource.
+func (t *tracer) start(ctx context.Context, op, resource string) opentracing.Span {
+	span := t.tr.StartSpan(op, ddtracer.ChildOfContext(ctx), ext.SpanKindRPCClient)
+	ext.DBType.Set(span, "redis")
+	ext.PeerService.Set(span, t.service)
+	ext.Component.Set(span, strings.ToUpper(resource))
+	return span
+}
+
+// finish finishes span, marking it as an error unless err is nil or a miss.
+func finish(span opentracing.Span, err error, miss error) {
+	if err != nil && err != miss {
+		span.LogFields(log.Error(err))
+	}
+	span.Finish()
+}
@@ -0,0 +1,22 @@
+package redistrace
+
+import (
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// recordingTracer keeps the spans it starts.
+type recordingTracer struct {
+	opentracing.Tracer
+	spans []*ddtracer.Span
+}
+
+func newRecordingTracer() *recordingTracer {
+	return &recordingTracer{Tracer: ddtracer.NewTracer()}
+}
+
+func (t *recordingTracer) StartSpan(op string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	span := t.Tracer.StartSpan(op, opts...)
+	t.spans = append(t.spans, span.(*ddtracer.Span))
+	return span
+}
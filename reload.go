@@ -0,0 +1,123 @@
+package ddtracer
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// SetSampleRate sets the ratio of traces sampled, between 0.0 and 1.0, the
+// invalid rates being ignored. Unlike the one of the DataDog's tracer, it's
+// safe to call it at runtime, i.e. to raise the sampling during an incident.
+func (t *Tracer) SetSampleRate(rate float64) {
+	if rate < 0 || rate > 1 || math.IsNaN(rate) {
+		t.logger.Printf("sample rate %v: must be between 0 and 1", rate)
+		return
+	}
+	atomic.StoreUint64(&t.sampleRate, math.Float64bits(rate))
+}
+
+// SampleRate returns the ratio of traces sampled, see SetSampleRate.
+func (t *Tracer) SampleRate() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&t.sampleRate))
+}
+
+// SetSampler replaces the Sampler deciding which traces are kept, it's safe
+// to call it at runtime. A nil sampler falls back to the sample rate.
+func (t *Tracer) SetSampler(s Sampler) {
+	t.samplerMu.Lock()
+	t.sampler = s
+	t.samplerMu.Unlock()
+}
+
+// SetSamplerRules replaces the Sampler by a ServiceSampler sampling the
+// traces at the rates of their root operation or service, the other ones
+// being kept.
+func (t *Tracer) SetSamplerRules(services, operations map[string]float64) {
+	t.SetSampler(ServiceSampler(services, operations, nil))
+}
+
+func (t *Tracer) currentSampler() Sampler {
+	t.samplerMu.RLock()
+	defer t.samplerMu.RUnlock()
+	return t.sampler
+}
+
+// sample decides whether the root span s is kept, by the Sampler of the
+// Tracer if any, otherwise by its sample rate.
+func (t *Tracer) sample(s *Span) bool {
+	if sampler := t.currentSampler(); sampler != nil {
+		return sampler.Sample(s)
+	}
+	if rate := t.SampleRate(); rate < 1 {
+		return RateSampler(rate).Sample(s)
+	}
+	return true
+}
+
+// SamplingConfig are the sampling settings applied by WatchSamplingConfig,
+// the zero ones being left untouched.
+type SamplingConfig struct {
+	SampleRate *float64           `json:"sample_rate,omitempty"`
+	Services   map[string]float64 `json:"services,omitempty"`
+	Operations map[string]float64 `json:"operations,omitempty"`
+}
+
+// ApplySamplingConfig sets the sample rate and the sampler rules of c.
+func (t *Tracer) ApplySamplingConfig(c SamplingConfig) {
+	if c.SampleRate != nil {
+		t.SetSampleRate(*c.SampleRate)
+	}
+	if c.Services != nil || c.Operations != nil {
+		t.SetSamplerRules(c.Services, c.Operations)
+	}
+}
+
+// WatchSamplingConfig applies the SamplingConfig JSON encoded at path, i.e.
+// {"sample_rate": 0.5, "services": {"db": 0.1}}, then again every time it's
+// modified, checking it every interval until ctx is done. The errors are
+// reported to the Logger, the previous config being kept.
+func (t *Tracer) WatchSamplingConfig(ctx context.Context, path string, interval time.Duration) {
+	var modTime time.Time
+	load := func() {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.logger.Printf("sampling config %s: %v", path, err)
+			return
+		}
+		if info.ModTime().Equal(modTime) {
+			return
+		}
+		modTime = info.ModTime()
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.logger.Printf("sampling config %s: %v", path, err)
+			return
+		}
+		var c SamplingConfig
+		if err := json.Unmarshal(data, &c); err != nil {
+			t.logger.Printf("sampling config %s: %v", path, err)
+			return
+		}
+		t.ApplySamplingConfig(c)
+	}
+
+	load()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				load()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,76 @@
+package ddtracer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampledRatio(tr *Tracer, op string) float64 {
+	var kept int
+	for i := 0; i < 1000; i++ {
+		if tr.StartSpan(op).(*Span).Sampled {
+			kept++
+		}
+	}
+	return float64(kept) / 1000
+}
+
+func TestSetSampleRate(t *testing.T) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}), WithLogger(NopLogger), WithSampleRate(0)).(*Tracer)
+	assert.Equal(t, float64(0), tr.SampleRate())
+	assert.Equal(t, float64(0), sampledRatio(tr, "op"))
+
+	tr.SetSampleRate(0.5)
+	assert.InDelta(t, 0.5, sampledRatio(tr, "op"), 0.1)
+	span := tr.StartSpan("op").(*Span)
+	assert.Equal(t, 0.5, span.Metrics[sampleRateKey])
+
+	tr.SetSampleRate(2)
+	assert.Equal(t, 0.5, tr.SampleRate())
+
+	tr.SetSampleRate(1)
+	assert.Equal(t, float64(1), sampledRatio(tr, "op"))
+	assert.NotContains(t, tr.StartSpan("op").(*Span).Metrics, sampleRateKey)
+}
+
+func TestSetSamplerRules(t *testing.T) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}), WithServiceName("svc")).(*Tracer)
+	tr.SetSamplerRules(map[string]float64{"svc": 0}, map[string]float64{"important": 1})
+	assert.Equal(t, float64(0), sampledRatio(tr, "op"))
+	assert.Equal(t, float64(1), sampledRatio(tr, "important"))
+
+	tr.SetSampler(nil)
+	assert.Equal(t, float64(1), sampledRatio(tr, "op"))
+}
+
+func TestWatchSamplingConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ddtracer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sampling.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"sample_rate": 0}`), 0644))
+
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}), WithServiceName("svc"), WithLogger(NopLogger)).(*Tracer)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tr.WatchSamplingConfig(ctx, path, 10*time.Millisecond)
+	assert.Equal(t, float64(0), tr.SampleRate())
+
+	// Make sure the modification time changes, whatever its resolution.
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"sample_rate": 1, "services": {"svc": 0}}`), 0644))
+	later := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(path, later, later))
+
+	for deadline := time.Now().Add(time.Second); tr.currentSampler() == nil && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Equal(t, float64(1), tr.SampleRate())
+	assert.Equal(t, float64(0), sampledRatio(tr, "op"))
+}
@@ -0,0 +1,196 @@
+package ddtracer
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+)
+
+// The retry defaults of the Tracers configured with WithRetry alone.
+const (
+	defaultRetryInitialBackoff = time.Second
+	defaultRetryMaxBackoff     = 30 * time.Second
+	defaultBreakerFailures     = 5
+	defaultBreakerProbe        = time.Minute
+)
+
+// retryTransport keeps the traces failed to be sent, retrying them with an
+// exponential backoff. After breakerFailures consecutive failures the circuit
+// opens, the agent being then probed every probeInterval only.
+type retryTransport struct {
+	tracer.Transport
+	stats  *stats
+	logger Logger
+
+	maxSpans        int
+	initialBackoff  time.Duration
+	maxBackoff      time.Duration
+	breakerFailures int
+	probeInterval   time.Duration
+
+	mu       sync.Mutex
+	traces   [][]*tracer.Span
+	spans    int
+	failures int
+	retryAt  time.Time
+
+	sendMu sync.Mutex
+	exit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newRetryTransport(tr tracer.Transport, stats *stats, logger Logger, c *config) *retryTransport {
+	r := &retryTransport{
+		Transport:       tr,
+		stats:           stats,
+		logger:          logger,
+		maxSpans:        c.retrySpans,
+		initialBackoff:  c.retryBackoff,
+		maxBackoff:      c.retryMaxBackoff,
+		breakerFailures: c.breakerFailures,
+		probeInterval:   c.breakerProbe,
+		exit:            make(chan struct{}),
+	}
+	if r.initialBackoff <= 0 {
+		r.initialBackoff = defaultRetryInitialBackoff
+	}
+	if r.maxBackoff < r.initialBackoff {
+		r.maxBackoff = defaultRetryMaxBackoff
+		if r.maxBackoff < r.initialBackoff {
+			r.maxBackoff = r.initialBackoff
+		}
+	}
+	if r.breakerFailures == 0 {
+		r.breakerFailures = defaultBreakerFailures
+	}
+	if r.probeInterval <= 0 {
+		r.probeInterval = defaultBreakerProbe
+	}
+	return r
+}
+
+// start retries the traces every initialBackoff, as the DataDog's tracer
+// only hands over new ones.
+func (r *retryTransport) start() {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.initialBackoff)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.send(false)
+			case <-r.exit:
+				return
+			}
+		}
+	}()
+}
+
+// SendTraces sends traces along with the ones to retry, unless the backoff
+// or the circuit breaker hold them. It never fails, the traces failed to be
+// sent being kept.
+func (r *retryTransport) SendTraces(traces [][]*tracer.Span) (*http.Response, error) {
+	r.mu.Lock()
+	r.keep(traces, false)
+	r.mu.Unlock()
+
+	r.send(false)
+	return &http.Response{StatusCode: http.StatusOK}, nil
+}
+
+// kept returns the number of spans kept to be retried.
+func (r *retryTransport) kept() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.spans
+}
+
+// send sends the kept traces if they're due, or anyway when forced.
+func (r *retryTransport) send(force bool) error {
+	r.sendMu.Lock()
+	defer r.sendMu.Unlock()
+
+	r.mu.Lock()
+	if len(r.traces) == 0 || (!force && time.Now().Before(r.retryAt)) {
+		r.mu.Unlock()
+		return nil
+	}
+	traces := r.traces
+	r.traces, r.spans = nil, 0
+	r.mu.Unlock()
+
+	_, err := r.Transport.SendTraces(traces)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil {
+		if r.open() {
+			r.logger.Printf("agent reachable again, closing the circuit")
+		}
+		r.failures, r.retryAt = 0, time.Time{}
+		return nil
+	}
+
+	r.failures++
+	r.retryAt = time.Now().Add(r.backoff())
+	if r.failures == r.breakerFailures {
+		r.logger.Printf("%d consecutive flushes failed, probing the agent every %s", r.failures, r.probeInterval)
+	}
+	r.keep(traces, true)
+	return err
+}
+
+// open reports whether the circuit breaker is open, it must be called with
+// mu held.
+func (r *retryTransport) open() bool {
+	return r.breakerFailures > 0 && r.failures >= r.breakerFailures
+}
+
+// backoff is the delay before the next retry, once failed r.failures times.
+func (r *retryTransport) backoff() time.Duration {
+	if r.open() {
+		return r.probeInterval
+	}
+	d := r.initialBackoff
+	for i := 1; i < r.failures && d < r.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > r.maxBackoff {
+		d = r.maxBackoff
+	}
+	return d
+}
+
+// keep adds traces to the ones to retry, before them when they're older,
+// dropping the oldest ones beyond maxSpans. It must be called with mu held.
+func (r *retryTransport) keep(traces [][]*tracer.Span, older bool) {
+	if older {
+		traces = append(traces, r.traces...)
+	} else {
+		traces = append(r.traces, traces...)
+	}
+
+	r.spans = 0
+	for _, trace := range traces {
+		r.spans += len(trace)
+	}
+	for r.maxSpans > 0 && r.spans > r.maxSpans {
+		r.spans -= len(traces[0])
+		atomic.AddUint64(&r.stats.spansDropped, uint64(len(traces[0])))
+		traces = traces[1:]
+	}
+	r.traces = traces
+}
+
+// close stops the retries and makes a last attempt, whatever the backoff.
+func (r *retryTransport) close() error {
+	close(r.exit)
+	r.wg.Wait()
+	return r.send(true)
+}
@@ -0,0 +1,101 @@
+package ddtracer
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyTransport fails while down is set, recording the traces otherwise.
+type flakyTransport struct {
+	recordingTransport
+	down     int32
+	attempts int32
+}
+
+func (t *flakyTransport) SendTraces(traces [][]*tracer.Span) (*http.Response, error) {
+	atomic.AddInt32(&t.attempts, 1)
+	if atomic.LoadInt32(&t.down) == 1 {
+		return &http.Response{}, errors.New("connection refused")
+	}
+	return t.recordingTransport.SendTraces(traces)
+}
+
+func names(traces [][]*tracer.Span) []string {
+	var names []string
+	for _, trace := range traces {
+		names = append(names, trace[0].Name)
+	}
+	return names
+}
+
+func TestRetryTransport(t *testing.T) {
+	flaky, st := &flakyTransport{down: 1}, &stats{}
+	r := newRetryTransport(flaky, st, NopLogger, &config{
+		retrySpans:      4,
+		retryBackoff:    time.Hour,
+		breakerFailures: -1,
+	})
+
+	_, err := r.SendTraces([][]*tracer.Span{spans("a", 2)})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), flaky.attempts)
+
+	// The backoff holds the new traces, the oldest being dropped once full.
+	r.SendTraces([][]*tracer.Span{spans("b", 1), spans("c", 2)})
+	assert.Equal(t, int32(1), flaky.attempts)
+	assert.Equal(t, uint64(2), st.spansDropped)
+
+	atomic.StoreInt32(&flaky.down, 0)
+	require.NoError(t, r.close())
+	assert.Equal(t, []string{"b", "c"}, names(flaky.traces()))
+}
+
+func TestRetryTransportBackoff(t *testing.T) {
+	r := newRetryTransport(discardTransport{}, &stats{}, NopLogger, &config{
+		retryBackoff:    time.Second,
+		retryMaxBackoff: 5 * time.Second,
+		breakerFailures: 5,
+		breakerProbe:    time.Minute,
+	})
+
+	var backoffs []time.Duration
+	for r.failures = 1; r.failures <= 6; r.failures++ {
+		backoffs = append(backoffs, r.backoff())
+	}
+	assert.Equal(t, []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second,
+		time.Minute, time.Minute,
+	}, backoffs)
+}
+
+func TestWithRetry(t *testing.T) {
+	flaky := &flakyTransport{down: 1}
+	tr := NewTracerWithOptions(
+		WithTransport(flaky),
+		WithLogger(NopLogger),
+		WithRetry(100),
+		WithRetryBackoff(10*time.Millisecond, 20*time.Millisecond),
+		WithCircuitBreaker(2, 10*time.Millisecond),
+	).(*Tracer)
+	defer tr.Close()
+
+	tr.StartSpan("op").Finish()
+	assert.Error(t, tr.FlushTraces())
+	assert.Error(t, tr.FlushTraces())
+	assert.Empty(t, flaky.traces())
+
+	// The agent is back, the background retries send the kept trace.
+	atomic.StoreInt32(&flaky.down, 0)
+	for deadline := time.Now().Add(time.Second); len(flaky.traces()) == 0 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Len(t, flaky.traces(), 1)
+	assert.Equal(t, uint64(0), tr.Stats().SpansDropped)
+}
@@ -0,0 +1,125 @@
+package ddtracer
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+)
+
+// Destination is where the traces routed to it are sent, see WithRouter.
+type Destination struct {
+	// APIKey authenticates the traces sent straight to the intake of Site,
+	// the site of the Tracer when empty, i.e. the one of another organization.
+	APIKey string
+	Site   string
+
+	// Transport, when not nil, sends the traces instead, i.e. to another agent.
+	Transport tracer.Transport
+}
+
+// transport returns the transport of the destination, with the headers of c.
+func (d Destination) transport(c *config) tracer.Transport {
+	tr := d.Transport
+	if tr == nil {
+		site := d.Site
+		if site == "" {
+			site = c.site
+		}
+		url := intakeURL(site)
+		if c.intakeURL != "" && d.Site == "" {
+			url = c.intakeURL
+		}
+		tr = newIntakeTransport(url, d.APIKey, c.httpClient)
+	}
+	for k, v := range c.headers {
+		tr.SetHeader(k, v)
+	}
+	return tr
+}
+
+// routerTransport sends every trace to the destination selected by the value
+// of its tag, the traces without a destination going to the default
+// transport. Every destination has a transport of its own, retrying and
+// buffering its traces independently of the others (see WithRetry), and
+// they're sent to concurrently so that a slow destination doesn't delay the
+// sending to the others. A flush still lasts until the slowest one is done.
+type routerTransport struct {
+	tag      string
+	routes   map[string]tracer.Transport
+	fallback tracer.Transport
+
+	// retries are the retry transports of the destinations, see WithRetry.
+	retries []*retryTransport
+}
+
+// destination returns the transport of trace, according to the value of the
+// tag of its first span holding it.
+func (r *routerTransport) destination(trace []*tracer.Span) tracer.Transport {
+	for _, span := range trace {
+		if v, ok := span.Meta[r.tag]; ok {
+			if tr, ok := r.routes[v]; ok {
+				return tr
+			}
+			break
+		}
+	}
+	return r.fallback
+}
+
+// SendTraces sends the traces of every destination in a request of its own,
+// concurrently, returning the error of the last destination failing.
+func (r *routerTransport) SendTraces(traces [][]*tracer.Span) (*http.Response, error) {
+	batches := make(map[tracer.Transport][][]*tracer.Span)
+	var order []tracer.Transport
+	for _, trace := range traces {
+		tr := r.destination(trace)
+		if _, ok := batches[tr]; !ok {
+			order = append(order, tr)
+		}
+		batches[tr] = append(batches[tr], trace)
+	}
+
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+	results := make([]result, len(order))
+	var wg sync.WaitGroup
+	for i, tr := range order {
+		wg.Add(1)
+		go func(i int, tr tracer.Transport) {
+			defer wg.Done()
+			results[i].resp, results[i].err = tr.SendTraces(batches[tr])
+		}(i, tr)
+	}
+	wg.Wait()
+
+	var err error
+	resp := &http.Response{StatusCode: http.StatusOK}
+	for _, rs := range results {
+		if rs.err != nil {
+			resp, err = rs.resp, rs.err
+		}
+	}
+	return resp, err
+}
+
+// SendServices sends the services to every destination.
+func (r *routerTransport) SendServices(services map[string]tracer.Service) (*http.Response, error) {
+	resp, err := r.fallback.SendServices(services)
+	for _, tr := range r.routes {
+		if rs, e := tr.SendServices(services); e != nil {
+			resp, err = rs, e
+		}
+	}
+	return resp, err
+}
+
+// SetHeader sets the header on the transports of every destination.
+func (r *routerTransport) SetHeader(key, value string) {
+	r.fallback.SetHeader(key, value)
+	for _, tr := range r.routes {
+		tr.SetHeader(key, value)
+	}
+}
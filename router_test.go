@@ -0,0 +1,139 @@
+package ddtracer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter(t *testing.T) {
+	acme, globex := &recordingTransport{}, &recordingTransport{}
+	fallback := &recordingTransport{}
+	tr := NewTracerWithOptions(
+		WithTransport(fallback),
+		WithRouter("tenant_id", map[string]Destination{
+			"acme":   {Transport: acme},
+			"globex": {Transport: globex},
+		}),
+	).(*Tracer)
+
+	root := tr.StartSpan("acme").(*Span)
+	root.SetTraceTag("tenant_id", "acme")
+	tr.StartSpan("acme.child", opentracing.ChildOf(root.Context())).Finish()
+	root.Finish()
+	tr.StartSpan("globex", opentracing.Tag{Key: "tenant_id", Value: "globex"}).Finish()
+	tr.StartSpan("unknown", opentracing.Tag{Key: "tenant_id", Value: "initech"}).Finish()
+	tr.StartSpan("untagged").Finish()
+	require.NoError(t, tr.FlushTraces())
+
+	require.Len(t, acme.traces(), 1)
+	assert.Len(t, acme.traces()[0], 2)
+	assert.Equal(t, []string{"globex"}, names(globex.traces()))
+	others := names(fallback.traces())
+	sort.Strings(others)
+	assert.Equal(t, []string{"unknown", "untagged"}, others)
+	assert.Equal(t, uint64(3), tr.Stats().Flushes)
+}
+
+func TestRouterAPIKey(t *testing.T) {
+	in := &intake{}
+	ts := httptest.NewServer(in)
+	defer ts.Close()
+
+	tr := NewTracerWithOptions(
+		WithAgentless("default"),
+		WithIntakeURL(ts.URL),
+		WithRouter("tenant_id", map[string]Destination{"acme": {APIKey: "acme-key"}}),
+	).(*Tracer)
+	tr.StartSpan("acme", opentracing.Tag{Key: "tenant_id", Value: "acme"}).Finish()
+	require.NoError(t, tr.FlushTraces())
+	tr.StartSpan("other").Finish()
+	require.NoError(t, tr.FlushTraces())
+
+	assert.Equal(t, []string{"acme-key", "default"}, in.apiKeys)
+	assert.Equal(t, []string{"acme", "other"}, names(in.traces))
+}
+
+func TestRouterRetries(t *testing.T) {
+	acme, fallback := &flakyTransport{down: 1}, &flakyTransport{}
+	tr := NewTracerWithOptions(
+		WithTransport(fallback),
+		WithRetry(100),
+		WithRouter("tenant_id", map[string]Destination{"acme": {Transport: acme}}),
+	).(*Tracer)
+	defer tr.Close()
+
+	tr.StartSpan("acme", opentracing.Tag{Key: "tenant_id", Value: "acme"}).Finish()
+	tr.StartSpan("other").Finish()
+	tr.FlushTraces()
+
+	// The traces of the destination down are kept, the others are sent once.
+	assert.Equal(t, []string{"other"}, names(fallback.traces()))
+	assert.Empty(t, acme.traces())
+	assert.Equal(t, 1, tr.router.retries[0].kept())
+
+	acme.down = 0
+	require.NoError(t, tr.FlushTraces())
+	assert.Equal(t, []string{"acme"}, names(acme.traces()))
+	assert.Equal(t, []string{"other"}, names(fallback.traces()))
+}
+
+func TestRouterTransport(t *testing.T) {
+	a, fallback := &recordingTransport{}, &recordingTransport{}
+	r := &routerTransport{tag: "tenant", routes: map[string]tracer.Transport{"a": a}, fallback: fallback}
+
+	tagged := spans("tagged", 2)
+	tagged[1].Meta = map[string]string{"tenant": "a"}
+	_, err := r.SendTraces([][]*tracer.Span{spans("untagged", 1), tagged})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"tagged"}, names(a.traces()))
+	assert.Equal(t, []string{"untagged"}, names(fallback.traces()))
+}
+
+// blockingTransport blocks the sending of the traces until released.
+type blockingTransport struct {
+	recordingTransport
+	release chan struct{}
+}
+
+func (t *blockingTransport) SendTraces(traces [][]*tracer.Span) (*http.Response, error) {
+	<-t.release
+	return t.recordingTransport.SendTraces(traces)
+}
+
+func TestRouterTransportConcurrent(t *testing.T) {
+	slow := &blockingTransport{release: make(chan struct{})}
+	a, fallback := &recordingTransport{}, &recordingTransport{}
+	r := &routerTransport{tag: "tenant", routes: map[string]tracer.Transport{"slow": slow, "a": a}, fallback: fallback}
+
+	tagged := func(name, tenant string) []*tracer.Span {
+		trace := spans(name, 1)
+		trace[0].Meta = map[string]string{"tenant": tenant}
+		return trace
+	}
+	done := make(chan error)
+	go func() {
+		_, err := r.SendTraces([][]*tracer.Span{tagged("slow", "slow"), tagged("a", "a"), spans("untagged", 1)})
+		done <- err
+	}()
+
+	// The other destinations are sent to while the slow one is blocked.
+	deadline := time.Now().Add(time.Second)
+	for (len(a.traces()) == 0 || len(fallback.traces()) == 0) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, []string{"a"}, names(a.traces()))
+	assert.Equal(t, []string{"untagged"}, names(fallback.traces()))
+
+	close(slow.release)
+	require.NoError(t, <-done)
+	assert.Equal(t, []string{"slow"}, names(slow.traces()))
+}
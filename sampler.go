@@ -0,0 +1,168 @@
+package ddtracer
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sampleRateKey is the metric read by DataDog to upscale the sampled traces stats.
+const sampleRateKey = "_sample_rate"
+
+// knuthFactor spreads the trace IDs so the sampling decision is
+// deterministic across services.
+const knuthFactor = uint64(1111111111111111111)
+
+// Sampler decides whether a trace is kept, it's asked once per trace
+// with the root span.
+type Sampler interface {
+	Sample(span *Span) bool
+}
+
+// AllSampler keeps every trace.
+func AllSampler() Sampler {
+	return allSampler{}
+}
+
+type allSampler struct{}
+
+func (allSampler) Sample(span *Span) bool {
+	return true
+}
+
+// RateSampler keeps the given ratio of traces, rate has to be between 0.0 and 1.0.
+func RateSampler(rate float64) Sampler {
+	if rate < 0 {
+		rate = 0
+	} else if rate > 1 {
+		rate = 1
+	}
+	return rateSampler(rate)
+}
+
+type rateSampler float64
+
+func (r rateSampler) Sample(span *Span) bool {
+	span.SetMetric(sampleRateKey, float64(r))
+	return sampleByRate(span.TraceID, float64(r))
+}
+
+func sampleByRate(traceID uint64, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	return traceID*knuthFactor < uint64(rate*math.MaxUint64)
+}
+
+// ServiceSampler samples the traces at the rate configured for the root span's
+// operation name, or its service, falling back to fallback when none matches.
+func ServiceSampler(services, operations map[string]float64, fallback Sampler) Sampler {
+	if fallback == nil {
+		fallback = AllSampler()
+	}
+	return &serviceSampler{services: services, operations: operations, fallback: fallback}
+}
+
+type serviceSampler struct {
+	services   map[string]float64
+	operations map[string]float64
+	fallback   Sampler
+}
+
+func (s *serviceSampler) Sample(span *Span) bool {
+	if rate, ok := s.operations[span.Name]; ok {
+		return RateSampler(rate).Sample(span)
+	}
+	if rate, ok := s.services[span.Service]; ok {
+		return RateSampler(rate).Sample(span)
+	}
+	return s.fallback.Sample(span)
+}
+
+// RateLimitedSampler keeps at most perSecond traces per second, allowing
+// bursts of up to perSecond traces.
+func RateLimitedSampler(perSecond float64) Sampler {
+	return &rateLimitedSampler{
+		perSecond: perSecond,
+		tokens:    perSecond,
+		now:       time.Now,
+	}
+}
+
+type rateLimitedSampler struct {
+	perSecond float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func (s *rateLimitedSampler) Sample(span *Span) bool {
+	return s.allow()
+}
+
+// allow takes a token, if there's any left.
+func (s *rateLimitedSampler) allow() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if !s.last.IsZero() {
+		s.tokens += now.Sub(s.last).Seconds() * s.perSecond
+		if s.tokens > s.perSecond {
+			s.tokens = s.perSecond
+		}
+	}
+	s.last = now
+
+	if s.tokens < 1 {
+		return false
+	}
+	s.tokens--
+	return true
+}
+
+// AdaptiveSampler aims to keep target traces per second, adjusting its
+// sample rate every window according to the throughput observed in the
+// previous one.
+func AdaptiveSampler(target float64, window time.Duration) Sampler {
+	return &adaptiveSampler{
+		target: target,
+		window: window,
+		rate:   1,
+		now:    time.Now,
+	}
+}
+
+type adaptiveSampler struct {
+	target float64
+	window time.Duration
+
+	mu    sync.Mutex
+	rate  float64
+	seen  int
+	start time.Time
+	now   func() time.Time
+}
+
+func (s *adaptiveSampler) Sample(span *Span) bool {
+	s.mu.Lock()
+	now := s.now()
+	if s.start.IsZero() {
+		s.start = now
+	}
+	if elapsed := now.Sub(s.start); elapsed >= s.window {
+		throughput := float64(s.seen) / elapsed.Seconds()
+		s.rate = 1
+		if throughput > s.target {
+			s.rate = s.target / throughput
+		}
+		s.seen, s.start = 0, now
+	}
+	s.seen++
+	rate := s.rate
+	s.mu.Unlock()
+
+	return RateSampler(rate).Sample(span)
+}
@@ -0,0 +1,108 @@
+package ddtracer
+
+import (
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateSampler(t *testing.T) {
+	tr := NewTracer()
+	span := tr.StartSpan("test").(*Span)
+
+	assert.True(t, AllSampler().Sample(span))
+	assert.True(t, RateSampler(1).Sample(span))
+	assert.False(t, RateSampler(0).Sample(span))
+	assert.Equal(t, float64(0), span.Metrics["_sample_rate"])
+
+	var kept int
+	for i := 0; i < 1000; i++ {
+		if RateSampler(0.5).Sample(tr.StartSpan("test").(*Span)) {
+			kept++
+		}
+	}
+	assert.InDelta(t, 500, kept, 100)
+}
+
+func TestServiceSampler(t *testing.T) {
+	s := ServiceSampler(
+		map[string]float64{"db": 0},
+		map[string]float64{"health": 0, "important": 1},
+		RateSampler(0),
+	)
+	tr := NewTracerWithOptions(WithSampler(s), WithServiceName("db"))
+
+	assert.False(t, tr.StartSpan("health").(*Span).Sampled)
+	assert.True(t, tr.StartSpan("important").(*Span).Sampled)
+	assert.False(t, tr.StartSpan("query").(*Span).Sampled)
+	assert.False(t, tr.StartSpan("other", opentracing.Tag{Key: string(ext.PeerService), Value: "web"}).(*Span).Sampled)
+
+	t.Run("Children follow the root decision", func(t *testing.T) {
+		root := tr.StartSpan("important")
+		child := tr.StartSpan("health", opentracing.ChildOf(root.Context())).(*Span)
+		assert.True(t, child.Sampled)
+	})
+
+	t.Run("Explicit priority wins", func(t *testing.T) {
+		span := tr.StartSpan("health", opentracing.Tag{Key: string(ext.SamplingPriority), Value: PriorityUserKeep}).(*Span)
+		assert.True(t, span.Sampled)
+	})
+}
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) add(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestRateLimitedSampler(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	s := RateLimitedSampler(2).(*rateLimitedSampler)
+	s.now = clock.now
+
+	span := NewTracer().StartSpan("test").(*Span)
+	assert.True(t, s.Sample(span))
+	assert.True(t, s.Sample(span))
+	assert.False(t, s.Sample(span))
+
+	clock.add(500 * time.Millisecond)
+	assert.True(t, s.Sample(span))
+	assert.False(t, s.Sample(span))
+
+	clock.add(10 * time.Second)
+	assert.True(t, s.Sample(span))
+	assert.True(t, s.Sample(span))
+	assert.False(t, s.Sample(span))
+}
+
+func TestAdaptiveSampler(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	s := AdaptiveSampler(10, time.Second).(*adaptiveSampler)
+	s.now = clock.now
+
+	tr := NewTracer()
+	for i := 0; i < 100; i++ {
+		assert.True(t, s.Sample(tr.StartSpan("test").(*Span)))
+	}
+
+	clock.add(time.Second)
+	var kept int
+	for i := 0; i < 1000; i++ {
+		if s.Sample(tr.StartSpan("test").(*Span)) {
+			kept++
+		}
+	}
+	assert.Equal(t, 0.1, s.rate)
+	assert.InDelta(t, 100, kept, 50)
+
+	t.Run("Back to full rate when the traffic goes down", func(t *testing.T) {
+		clock.add(100 * time.Second)
+		s.Sample(tr.StartSpan("test").(*Span))
+		assert.Equal(t, float64(1), s.rate)
+	})
+}
@@ -0,0 +1,85 @@
+package ddtracer
+
+import (
+	"strconv"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+)
+
+// samplingPriorityKey is the metric read by DataDog to keep or drop a trace.
+const samplingPriorityKey = "_sampling_priority_v1"
+
+// Sampling priorities, see Span.SetSamplingPriority.
+const (
+	// PriorityUserReject drops the trace, it has been decided by the user.
+	PriorityUserReject = -1
+	// PriorityAutoReject drops the trace, it has been decided by the sampler.
+	PriorityAutoReject = 0
+	// PriorityAutoKeep keeps the trace, it has been decided by the sampler.
+	PriorityAutoKeep = 1
+	// PriorityUserKeep keeps the trace, it has been decided by the user.
+	PriorityUserKeep = 2
+)
+
+// SetSamplingPriority sets the sampling priority of the span, which is propagated
+// downstream and inherited by its children. A priority greater than zero keeps the trace.
+// It's the same as ext.SamplingPriority.Set(span, priority).
+func (s *Span) SetSamplingPriority(priority int) {
+	if !s.lock() {
+		return
+	}
+	defer s.mu.Unlock()
+
+	s.setSamplingPriority(priority)
+}
+
+// setSamplingPriority implements SetSamplingPriority, the span must be locked.
+func (s *Span) setSamplingPriority(priority int) {
+	s.Span.SetMetric(samplingPriorityKey, float64(priority))
+	s.Sampled = priority > 0
+}
+
+// SamplingPriority returns the sampling priority of the span, and whether it's been set.
+func (s *Span) SamplingPriority() (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return samplingPriority(s.Span)
+}
+
+func samplingPriority(span *tracer.Span) (int, bool) {
+	if span == nil {
+		return 0, false
+	}
+
+	v, ok := span.Metrics[samplingPriorityKey]
+	return int(v), ok
+}
+
+// samplingPriority returns the priority to propagate, derived from the
+// sampling decision when it hasn't been set.
+func (sc *SpanContext) samplingPriority() int {
+	if sc.hasPriority {
+		return sc.priority
+	}
+	if sc.sampled {
+		return PriorityAutoKeep
+	}
+	return PriorityAutoReject
+}
+
+// inheritSamplingPriority makes span follow the decision taken upstream by p.
+func inheritSamplingPriority(span *tracer.Span, p *SpanContext) {
+	if !p.hasPriority {
+		return
+	}
+
+	span.SetMetric(samplingPriorityKey, float64(p.priority))
+	span.Sampled = p.priority > 0
+}
+
+func mapSamplingPriority(span *Span, value interface{}) {
+	if priority, err := strconv.Atoi(tagString(value)); err == nil {
+		span.setSamplingPriority(priority)
+	}
+}
@@ -0,0 +1,63 @@
+package ddtracer
+
+import (
+	"net/http"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSamplingPriority(t *testing.T) {
+	tr := NewTracer().(*Tracer)
+	span := tr.StartSpan("test").(*Span)
+
+	_, ok := span.SamplingPriority()
+	assert.False(t, ok)
+
+	ext.SamplingPriority.Set(span, PriorityUserKeep)
+	priority, ok := span.SamplingPriority()
+	assert.True(t, ok)
+	assert.Equal(t, PriorityUserKeep, priority)
+	assert.Equal(t, float64(2), span.Metrics["_sampling_priority_v1"])
+
+	t.Run("Children inherit", func(t *testing.T) {
+		child := tr.StartSpan("child", opentracing.ChildOf(span.Context())).(*Span)
+		priority, ok := child.SamplingPriority()
+		assert.True(t, ok)
+		assert.Equal(t, PriorityUserKeep, priority)
+	})
+
+	t.Run("Propagation", func(t *testing.T) {
+		span.SetSamplingPriority(PriorityUserReject)
+		assert.False(t, span.Sampled)
+
+		h := http.Header{}
+		require.NoError(t, tr.InjectHTTPHeader(span.Context(), h))
+		assert.Equal(t, "-1", h.Get("X-Datadog-Sampling-Priority"))
+
+		sc, err := tr.ExtractHTTPHeader(h)
+		require.NoError(t, err)
+
+		child := tr.StartSpan("child", opentracing.ChildOf(sc)).(*Span)
+		priority, ok := child.SamplingPriority()
+		assert.True(t, ok)
+		assert.Equal(t, PriorityUserReject, priority)
+		assert.False(t, child.Sampled)
+	})
+
+	t.Run("Without priority", func(t *testing.T) {
+		sc, err := tr.ExtractHTTPHeader(http.Header{
+			"X-Datadog-Trace-Id":  []string{"1"},
+			"X-Datadog-Parent-Id": []string{"2"},
+		})
+		require.NoError(t, err)
+
+		child := tr.StartSpan("child", opentracing.ChildOf(sc)).(*Span)
+		_, ok := child.SamplingPriority()
+		assert.False(t, ok)
+		assert.True(t, child.Sampled)
+	})
+}
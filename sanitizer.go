@@ -0,0 +1,37 @@
+package ddtracer
+
+import (
+	"strings"
+)
+
+// Redacted replaces the values of the sensitive tags in DefaultTagSanitizer.
+const Redacted = "<redacted>"
+
+// TagSanitizer is called with every meta set on the spans, including the
+// fields of their log records, before they leave the process. It returns the
+// value to set, which might be redacted, or false to drop the tag.
+type TagSanitizer func(key, value string) (string, bool)
+
+// sensitiveKeys are the substrings of the keys redacted by DefaultTagSanitizer.
+var sensitiveKeys = []string{
+	"authorization",
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"api_key",
+	"apikey",
+	"cookie",
+}
+
+// DefaultTagSanitizer redacts the values of the tags whose key mentions
+// credentials, i.e. "http.request.headers.authorization" or "db.password".
+func DefaultTagSanitizer(key, value string) (string, bool) {
+	key = strings.ToLower(key)
+	for _, k := range sensitiveKeys {
+		if strings.Contains(key, k) {
+			return Redacted, true
+		}
+	}
+	return value, true
+}
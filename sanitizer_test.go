@@ -0,0 +1,61 @@
+package ddtracer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultTagSanitizer(t *testing.T) {
+	for key, expected := range map[string]string{
+		"http.request.headers.Authorization": Redacted,
+		"db.password":                        Redacted,
+		"oauth.token":                        Redacted,
+		"http.url":                           "value",
+		"user":                               "value",
+	} {
+		v, ok := DefaultTagSanitizer(key, "value")
+		assert.True(t, ok)
+		assert.Equal(t, expected, v, key)
+	}
+}
+
+func TestTagSanitizer(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		span := NewTracer().StartSpan("test").(*Span)
+		span.SetTag("db.password", "hunter2")
+		span.LogFields(log.String("api_token", "hunter2"), log.String("event", "login"))
+
+		assert.Equal(t, Redacted, span.GetMeta("db.password"))
+		assert.Equal(t, Redacted, span.GetMeta("api_token"))
+		assert.NotContains(t, span.GetMeta("log.0"), "hunter2")
+		assert.Contains(t, span.GetMeta("log.0"), "login")
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		tr := NewTracerWithOptions(WithTagSanitizer(func(key, value string) (string, bool) {
+			if key == "user.email" {
+				return "", false
+			}
+			return strings.ToUpper(value), true
+		}))
+
+		span := tr.StartSpan("test").(*Span)
+		span.SetTag("user.email", "jane@example.com")
+		span.SetTag("db.password", "hunter2")
+		span.LogKV("user.email", "jane@example.com")
+
+		_, ok := span.Meta["user.email"]
+		assert.False(t, ok)
+		assert.Equal(t, "HUNTER2", span.GetMeta("db.password"))
+		assert.NotContains(t, span.GetMeta("log.0"), "jane")
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		span := NewTracerWithOptions(WithTagSanitizer(nil)).StartSpan("test").(*Span)
+		span.SetTag("db.password", "hunter2")
+		assert.Equal(t, "hunter2", span.GetMeta("db.password"))
+	})
+}
@@ -0,0 +1,119 @@
+package ddtracer
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// Scope is the activation of a span on a goroutine, see Tracer.Activate.
+type Scope struct {
+	scopes *scopes
+	span   opentracing.Span
+	goid   uint64
+	prev   *Scope
+
+	// closed is guarded by scopes.mu.
+	closed bool
+}
+
+// Span returns the activated span.
+func (s *Scope) Span() opentracing.Span {
+	return s.span
+}
+
+// Close deactivates the span, the previously active one of the goroutine
+// being active again. It doesn't finish the span, and it's safe to call it
+// more than once or out of order.
+func (s *Scope) Close() {
+	s.scopes.close(s)
+}
+
+// scopes holds the active scope of every goroutine.
+type scopes struct {
+	mu     sync.Mutex
+	active map[uint64]*Scope
+}
+
+func (ss *scopes) activate(span opentracing.Span) *Scope {
+	goid := goroutineID()
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if ss.active == nil {
+		ss.active = make(map[uint64]*Scope)
+	}
+	s := &Scope{scopes: ss, span: span, goid: goid, prev: ss.active[goid]}
+	ss.active[goid] = s
+	return s
+}
+
+func (ss *scopes) close(s *Scope) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	s.closed = true
+	if ss.active[s.goid] != s {
+		return
+	}
+
+	// Skips the scopes closed out of order.
+	prev := s.prev
+	for prev != nil && prev.closed {
+		prev = prev.prev
+	}
+	if prev == nil {
+		delete(ss.active, s.goid)
+	} else {
+		ss.active[s.goid] = prev
+	}
+}
+
+func (ss *scopes) activeSpan() opentracing.Span {
+	goid := goroutineID()
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if s := ss.active[goid]; s != nil {
+		return s.span
+	}
+	return nil
+}
+
+// Activate makes span the active span of the calling goroutine until the
+// returned Scope is closed, i.e.
+//
+//	scope := tracer.Activate(span)
+//	defer scope.Close()
+//
+// The active span is the parent of the spans started without references
+// when the Tracer is configured WithImplicitParenting.
+// Being goroutine-local, it's not propagated to the goroutines started
+// meanwhile, for which context.Context remains the way to go.
+func (t *Tracer) Activate(span opentracing.Span) *Scope {
+	return t.scopes.activate(span)
+}
+
+// ActiveSpan returns the active span of the calling goroutine, nil if none.
+func (t *Tracer) ActiveSpan() opentracing.Span {
+	return t.scopes.activeSpan()
+}
+
+var goroutinePrefix = []byte("goroutine ")
+
+// goroutineID returns the ID of the calling goroutine, parsed from the
+// header of its stack trace, i.e. "goroutine 18 [running]:".
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], goroutinePrefix)
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
@@ -0,0 +1,75 @@
+package ddtracer
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScope(t *testing.T) {
+	tr := NewTracer().(*Tracer)
+	assert.Nil(t, tr.ActiveSpan())
+
+	root := tr.StartSpan("root")
+	outer := tr.Activate(root)
+	assert.Equal(t, root, outer.Span())
+	assert.Equal(t, root, tr.ActiveSpan())
+
+	child := tr.StartSpan("child")
+	inner := tr.Activate(child)
+	assert.Equal(t, child, tr.ActiveSpan())
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.Nil(t, tr.ActiveSpan())
+	}()
+	wg.Wait()
+
+	inner.Close()
+	assert.Equal(t, root, tr.ActiveSpan())
+	inner.Close()
+	assert.Equal(t, root, tr.ActiveSpan())
+	outer.Close()
+	assert.Nil(t, tr.ActiveSpan())
+
+	t.Run("Out of order", func(t *testing.T) {
+		first := tr.Activate(root)
+		second := tr.Activate(child)
+		first.Close()
+		assert.Equal(t, child, tr.ActiveSpan())
+		second.Close()
+		assert.Nil(t, tr.ActiveSpan())
+	})
+}
+
+func TestImplicitParenting(t *testing.T) {
+	tr := NewTracerWithOptions(WithImplicitParenting(true)).(*Tracer)
+
+	root := tr.StartSpan("root").(*Span)
+	scope := tr.Activate(root)
+	defer scope.Close()
+
+	child := tr.StartSpan("child").(*Span)
+	assert.Equal(t, root.TraceID, child.TraceID)
+	assert.Equal(t, root.SpanID, child.ParentID)
+
+	t.Run("Disabled", func(t *testing.T) {
+		tr := NewTracer().(*Tracer)
+		root := tr.StartSpan("root")
+		defer tr.Activate(root).Close()
+		assert.Zero(t, tr.StartSpan("child").(*Span).ParentID)
+	})
+}
+
+func BenchmarkActiveSpan(b *testing.B) {
+	tr := NewTracer().(*Tracer)
+	defer tr.Activate(tr.StartSpan("root")).Close()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		tr.ActiveSpan()
+	}
+}
@@ -0,0 +1,63 @@
+package ddtracer
+
+import (
+	"bytes"
+	"encoding/base64"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler, encoding the context as
+// the Binary format does, to persist it (i.e. in a job queue or a database)
+// and resume the trace later with a FollowsFrom reference.
+func (ctx *SpanContext) MarshalBinary() ([]byte, error) {
+	sc, err := ctx.refresh()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := (&binaryPropagator{}).Inject(sc, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, decoding a context
+// encoded by MarshalBinary. The decoded context is not attached to any span.
+func (ctx *SpanContext) UnmarshalBinary(data []byte) error {
+	sc, err := (&binaryPropagator{}).Extract(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	*ctx = *sc.(*SpanContext)
+	return nil
+}
+
+// SpanContextToString encodes sc as an URL safe string, i.e. for the
+// metadata of cron jobs, see SpanContextFromString.
+func SpanContextToString(sc opentracing.SpanContext) (string, error) {
+	ctx, ok := sc.(*SpanContext)
+	if !ok || ctx == nil {
+		return "", opentracing.ErrInvalidSpanContext
+	}
+	data, err := ctx.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// SpanContextFromString decodes a context encoded by SpanContextToString,
+// to be referenced by the spans resuming the trace.
+func SpanContextFromString(s string) (opentracing.SpanContext, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	ctx := &SpanContext{}
+	if err := ctx.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
@@ -0,0 +1,57 @@
+package ddtracer
+
+import (
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpanContextString(t *testing.T) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}), WithTraceID128Bit(true))
+
+	parent := tr.StartSpan("enqueue").(*Span)
+	parent.SetBaggageItem("user", "42")
+	s, err := SpanContextToString(parent.Context())
+	require.NoError(t, err)
+	parent.Finish()
+
+	sc, err := SpanContextFromString(s)
+	require.NoError(t, err)
+	ctx := sc.(*SpanContext)
+	assert.Equal(t, parent.TraceID, ctx.TraceID())
+	assert.Equal(t, parent.traceIDHigh, ctx.TraceIDHigh())
+	assert.Equal(t, parent.SpanID, ctx.SpanID())
+
+	job := tr.StartSpan("job", opentracing.FollowsFrom(sc)).(*Span)
+	assert.Equal(t, parent.TraceID, job.TraceID)
+	assert.Equal(t, parent.SpanID, job.ParentID)
+	assert.Equal(t, "42", job.BaggageItem("user"))
+	assert.Equal(t, followsFromRefType, job.GetMeta(refTypeTag))
+}
+
+func TestSpanContextStringErrors(t *testing.T) {
+	_, err := SpanContextToString(nil)
+	assert.Equal(t, opentracing.ErrInvalidSpanContext, err)
+
+	_, err = SpanContextToString(&SpanContext{})
+	assert.Equal(t, opentracing.ErrInvalidSpanContext, err)
+
+	for _, s := range []string{"", "!", "AQ"} {
+		_, err = SpanContextFromString(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestSpanContextBinaryMarshaler(t *testing.T) {
+	sc := NewRemoteSpanContext(0, 1, 2, true)
+	data, err := sc.MarshalBinary()
+	require.NoError(t, err)
+
+	var decoded SpanContext
+	require.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Equal(t, uint64(1), decoded.TraceID())
+	assert.Equal(t, uint64(2), decoded.SpanID())
+	assert.True(t, decoded.Sampled())
+}
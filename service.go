@@ -0,0 +1,27 @@
+package ddtracer
+
+import (
+	"github.com/DataDog/dd-trace-go/tracer"
+)
+
+// ServiceMapper derives the service and resource names of a span from its
+// operation name, letting generic OpenTracing instrumentation be named after
+// the organization's conventions. An empty service or resource keeps the one
+// the span would get otherwise.
+type ServiceMapper func(operation string) (service, resource string)
+
+// applyServiceMapper sets the service and resource returned by the Tracer's
+// ServiceMapper, if any, on span.
+func (t *Tracer) applyServiceMapper(span *tracer.Span, op string) {
+	if t.serviceMapper == nil {
+		return
+	}
+
+	service, resource := t.serviceMapper(op)
+	if service != "" {
+		span.Service = service
+	}
+	if resource != "" {
+		span.Resource = resource
+	}
+}
@@ -0,0 +1,43 @@
+package ddtracer
+
+import (
+	"strings"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceMapper(t *testing.T) {
+	tr := NewTracerWithOptions(WithServiceName("app"), WithServiceMapper(func(op string) (string, string) {
+		if strings.HasPrefix(op, "redis.") {
+			return "redis", strings.TrimPrefix(op, "redis.")
+		}
+		return "", ""
+	}))
+
+	t.Run("Mapped", func(t *testing.T) {
+		span := tr.StartSpan("redis.GET").(*Span)
+		assert.Equal(t, "redis", span.Service)
+		assert.Equal(t, "GET", span.Resource)
+	})
+
+	t.Run("Unmapped", func(t *testing.T) {
+		span := tr.StartSpan("http.request").(*Span)
+		assert.Equal(t, "app", span.Service)
+		assert.Equal(t, "http.request", span.Resource)
+	})
+
+	t.Run("Child", func(t *testing.T) {
+		parent := tr.StartSpan("http.request")
+		span := tr.StartSpan("redis.SET", opentracing.ChildOf(parent.Context())).(*Span)
+		assert.Equal(t, "redis", span.Service)
+		assert.Equal(t, "SET", span.Resource)
+	})
+
+	t.Run("Tags take precedence", func(t *testing.T) {
+		span := tr.StartSpan("redis.GET", ServiceName("cache")).(*Span)
+		assert.Equal(t, "cache", span.Service)
+		assert.Equal(t, "GET", span.Resource)
+	})
+}
@@ -0,0 +1,135 @@
+package ddtracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// The metrics marking the spans kept by a SpanSamplingRule, read by the agent
+// to keep them even though their trace is dropped.
+const (
+	spanSamplingMechanismKey    = "_dd.span_sampling.mechanism"
+	spanSamplingRuleRateKey     = "_dd.span_sampling.rule_rate"
+	spanSamplingMaxPerSecondKey = "_dd.span_sampling.max_per_second"
+
+	// spanSamplingMechanism is the sampling mechanism of the single spans.
+	spanSamplingMechanism = 8
+)
+
+// SpanSamplingRule keeps the spans it matches even when their trace is
+// dropped, i.e. to keep the spans of a key operation with a low trace sample
+// rate. Its JSON encoding is the one of DD_SPAN_SAMPLING_RULES:
+//
+//	[{"service": "db-*", "name": "postgres.query", "sample_rate": 0.5, "max_per_second": 100}]
+type SpanSamplingRule struct {
+	// Service and Name are the glob patterns, with the * and ? wildcards,
+	// matching the service and the operation name of the spans, any when empty.
+	Service string `json:"service"`
+	Name    string `json:"name"`
+
+	// SampleRate is the ratio of the spans matched kept, 1 by default.
+	SampleRate float64 `json:"sample_rate"`
+	// MaxPerSecond, when positive, caps the spans kept per second.
+	MaxPerSecond float64 `json:"max_per_second"`
+}
+
+// UnmarshalJSON defaults the sample rate to 1.
+func (r *SpanSamplingRule) UnmarshalJSON(b []byte) error {
+	type rule SpanSamplingRule
+	v := rule{SampleRate: 1}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*r = SpanSamplingRule(v)
+	return nil
+}
+
+// ParseSpanSamplingRules parses the rules JSON encoded, as DD_SPAN_SAMPLING_RULES.
+func ParseSpanSamplingRules(data []byte) ([]SpanSamplingRule, error) {
+	var rules []SpanSamplingRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("span sampling rules: %v", err)
+	}
+	for _, r := range rules {
+		if r.SampleRate < 0 || r.SampleRate > 1 {
+			return nil, fmt.Errorf("span sampling rules: sample rate %v out of [0, 1]", r.SampleRate)
+		}
+	}
+	return rules, nil
+}
+
+// loadSpanSamplingRules reads the rules of DD_SPAN_SAMPLING_RULES, or of the
+// file at DD_SPAN_SAMPLING_RULES_FILE, the former taking precedence.
+func loadSpanSamplingRules(rules, file string) ([]SpanSamplingRule, error) {
+	if rules != "" {
+		return ParseSpanSamplingRules([]byte(rules))
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("span sampling rules: %v", err)
+	}
+	return ParseSpanSamplingRules(b)
+}
+
+// spanSampler keeps the spans of the dropped traces matching its rules.
+type spanSampler struct {
+	rules []*spanRule
+}
+
+type spanRule struct {
+	SpanSamplingRule
+	service, name *regexp.Regexp
+	limiter       *rateLimitedSampler
+}
+
+func (t *Tracer) newSpanSampler(rules []SpanSamplingRule) *spanSampler {
+	s := &spanSampler{}
+	for _, r := range rules {
+		rule := &spanRule{SpanSamplingRule: r, service: globRegexp(r.Service), name: globRegexp(r.Name)}
+		if r.MaxPerSecond > 0 {
+			rule.limiter = RateLimitedSampler(r.MaxPerSecond).(*rateLimitedSampler)
+			rule.limiter.now = t.clock.Now
+		}
+		s.rules = append(s.rules, rule)
+	}
+	return s
+}
+
+// globRegexp compiles the glob pattern, matching case insensitively.
+func globRegexp(pattern string) *regexp.Regexp {
+	if pattern == "" || pattern == "*" {
+		return nil
+	}
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.Replace(expr, `\*`, ".*", -1)
+	expr = strings.Replace(expr, `\?`, ".", -1)
+	return regexp.MustCompile("(?i)^" + expr + "$")
+}
+
+func (r *spanRule) match(span *Span) bool {
+	return (r.service == nil || r.service.MatchString(span.Service)) &&
+		(r.name == nil || r.name.MatchString(span.Name))
+}
+
+// keep reports whether the span, locked, is kept by the first rule matching
+// it, in which case it's marked as such.
+func (s *spanSampler) keep(span *Span) bool {
+	for _, r := range s.rules {
+		if !r.match(span) {
+			continue
+		}
+		if !sampleByRate(span.SpanID, r.SampleRate) || (r.limiter != nil && !r.limiter.allow()) {
+			return false
+		}
+		span.setMetric(spanSamplingMechanismKey, spanSamplingMechanism)
+		span.setMetric(spanSamplingRuleRateKey, r.SampleRate)
+		if r.limiter != nil {
+			span.setMetric(spanSamplingMaxPerSecondKey, r.MaxPerSecond)
+		}
+		return true
+	}
+	return false
+}
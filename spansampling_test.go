@@ -0,0 +1,150 @@
+package ddtracer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSpanSamplingRules(t *testing.T) {
+	rules, err := ParseSpanSamplingRules([]byte(`[
+		{"service": "db-*", "name": "postgres.query", "max_per_second": 100},
+		{"name": "cache.?et", "sample_rate": 0.5}
+	]`))
+	require.NoError(t, err)
+	assert.Equal(t, []SpanSamplingRule{
+		{Service: "db-*", Name: "postgres.query", SampleRate: 1, MaxPerSecond: 100},
+		{Name: "cache.?et", SampleRate: 0.5},
+	}, rules)
+
+	_, err = ParseSpanSamplingRules([]byte(`{"name": "op"}`))
+	assert.Error(t, err)
+	_, err = ParseSpanSamplingRules([]byte(`[{"name": "op", "sample_rate": 2}]`))
+	assert.Error(t, err)
+}
+
+func TestSpanSamplingRules(t *testing.T) {
+	rec := &recordingTransport{}
+	tr := NewTracerWithOptions(
+		WithTransport(rec),
+		WithSampler(RateSampler(0)),
+		WithSamplingRules([]SpanSamplingRule{
+			{Service: "DB-*", Name: "*.query", SampleRate: 1},
+			{Name: "cache.?et", SampleRate: 0},
+		}),
+	).(*Tracer)
+
+	root := tr.StartSpan("http.request")
+	tr.StartSpan("postgres.query", opentracing.ChildOf(root.Context()), ServiceName("db-main")).Finish()
+	tr.StartSpan("postgres.query", opentracing.ChildOf(root.Context()), ServiceName("web")).Finish()
+	tr.StartSpan("cache.get", opentracing.ChildOf(root.Context())).Finish()
+	root.Finish()
+	require.NoError(t, tr.FlushTraces())
+
+	var kept []*tracer.Span
+	for _, trace := range rec.traces() {
+		kept = append(kept, trace...)
+	}
+	require.Len(t, kept, 1)
+	assert.Equal(t, "db-main", kept[0].Service)
+	assert.Equal(t, float64(spanSamplingMechanism), kept[0].Metrics[spanSamplingMechanismKey])
+	assert.Equal(t, float64(1), kept[0].Metrics[spanSamplingRuleRateKey])
+	assert.NotContains(t, kept[0].Metrics, spanSamplingMaxPerSecondKey)
+
+	t.Run("Sampled trace", func(t *testing.T) {
+		rec := &recordingTransport{}
+		tr := NewTracerWithOptions(WithTransport(rec), WithSamplingRules([]SpanSamplingRule{{SampleRate: 1}})).(*Tracer)
+		tr.StartSpan("op").Finish()
+		require.NoError(t, tr.FlushTraces())
+
+		require.Len(t, rec.traces(), 1)
+		assert.NotContains(t, rec.traces()[0][0].Metrics, spanSamplingMechanismKey)
+	})
+
+	t.Run("User reject", func(t *testing.T) {
+		rec := &recordingTransport{}
+		tr := NewTracerWithOptions(WithTransport(rec), WithSamplingRules([]SpanSamplingRule{{Name: "child", SampleRate: 1}})).(*Tracer)
+		root := tr.StartSpan("root").(*Span)
+		root.SetSamplingPriority(PriorityUserReject)
+		tr.StartSpan("child", opentracing.ChildOf(root.Context())).Finish()
+		root.Finish()
+		require.NoError(t, tr.FlushTraces())
+
+		assert.Equal(t, []string{"child"}, names(rec.traces()))
+	})
+}
+
+func TestSpanSamplingMaxPerSecond(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	rec := &recordingTransport{}
+	tr := NewTracerWithOptions(
+		WithTransport(rec),
+		WithClock(clock),
+		WithSampler(RateSampler(0)),
+		WithSamplingRules([]SpanSamplingRule{{Name: "op", SampleRate: 1, MaxPerSecond: 2}}),
+	).(*Tracer)
+
+	kept := func() int {
+		for i := 0; i < 5; i++ {
+			tr.StartSpan("op").Finish()
+		}
+		require.NoError(t, tr.FlushTraces())
+		n := 0
+		for _, trace := range rec.traces() {
+			n += len(trace)
+		}
+		rec.batches = nil
+		return n
+	}
+	assert.Equal(t, 2, kept())
+	assert.Equal(t, 0, kept())
+	clock.add(time.Second)
+	assert.Equal(t, 2, kept())
+}
+
+func TestSpanSamplingRulesEnv(t *testing.T) {
+	run := func(opts ...Option) ([]string, *recordingLogger) {
+		rec, logger := &recordingTransport{}, &recordingLogger{}
+		tr := NewTracerWithOptions(append([]Option{WithTransport(rec), WithLogger(logger), WithSampler(RateSampler(0))}, opts...)...).(*Tracer)
+		tr.StartSpan("a").Finish()
+		tr.StartSpan("b").Finish()
+		require.NoError(t, tr.FlushTraces())
+		return names(rec.traces()), logger
+	}
+
+	t.Run("Rules", func(t *testing.T) {
+		defer setenv(map[string]string{spanSamplingRulesEnv: `[{"name": "a"}]`})()
+		kept, _ := run()
+		assert.Equal(t, []string{"a"}, kept)
+
+		kept, _ = run(WithSamplingRules([]SpanSamplingRule{{Name: "b", SampleRate: 1}}))
+		assert.Equal(t, []string{"b"}, kept)
+	})
+
+	t.Run("File", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "rules")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+		file := filepath.Join(dir, "rules.json")
+		require.NoError(t, ioutil.WriteFile(file, []byte(`[{"name": "b"}]`), 0644))
+
+		defer setenv(map[string]string{spanSamplingRulesFileEnv: file})()
+		kept, _ := run()
+		assert.Equal(t, []string{"b"}, kept)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		defer setenv(map[string]string{spanSamplingRulesEnv: `[{"name": `})()
+		kept, logger := run()
+		assert.Empty(t, kept)
+		require.Len(t, logger.lines, 1)
+		assert.Contains(t, logger.lines[0], "span sampling rules")
+	})
+}
@@ -0,0 +1,275 @@
+// Package sqltrace wraps database/sql drivers so every query, statement and
+// transaction produces a DataDog sql span, child of the span in the context.
+//
+//	sql.Register("postgres-traced", sqltrace.Wrap("postgres", &pq.Driver{}, tracer))
+//	db, err := sql.Open("postgres-traced", dsn)
+//	rows, err := db.QueryContext(ctx, "SELECT ...")
+package sqltrace
+
+import (
+	"context"
+	"database/sql/driver"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// Option configures the wrapped driver.
+type Option func(*config)
+
+type config struct {
+	service   string
+	obfuscate func(string) string
+}
+
+// ServiceName sets the prefix of the spans service, the driver name is
+// appended to it, i.e. "myapp-postgres". The service is the driver name when empty.
+func ServiceName(name string) Option {
+	return func(c *config) {
+		c.service = name
+	}
+}
+
+// WithObfuscation replaces the literals of the statements by "?" before
+// using them as resource, see ObfuscateQuery.
+func WithObfuscation() Option {
+	return WithObfuscator(ObfuscateQuery)
+}
+
+// WithObfuscator sets the function used to obfuscate the statements.
+func WithObfuscator(fn func(query string) string) Option {
+	return func(c *config) {
+		c.obfuscate = fn
+	}
+}
+
+// ObfuscateQuery replaces the string and number literals of query by "?",
+// see ddtracer.ObfuscateSQL.
+func ObfuscateQuery(query string) string {
+	return ddtracer.ObfuscateSQL(query)
+}
+
+// Wrap returns a driver tracing d, driverName is the name d is usually
+// registered with, i.e. "postgres".
+func Wrap(driverName string, d driver.Driver, tr opentracing.Tracer, opts ...Option) driver.Driver {
+	cfg := &config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	service := driverName
+	if cfg.service != "" {
+		service = cfg.service + "-" + driverName
+	}
+
+	return &tracedDriver{
+		Driver: d,
+		t: &tracer{
+			tr:        tr,
+			service:   service,
+			obfuscate: cfg.obfuscate,
+		},
+	}
+}
+
+type tracer struct {
+	tr        opentracing.Tracer
+	service   string
+	obfuscate func(string) string
+}
+
+// start starts a span child of the span in ctx, and returns a context with it.
+// The query is used as resource when not empty, the operation otherwise.
+func (t *tracer) start(ctx context.Context, op, query string) (opentracing.Span, context.Context) {
+	span := t.tr.StartSpan(op,
+		ddtracer.ChildOfContext(ctx),
+		ext.SpanKindRPCClient,
+		ddtracer.SpanType(ddtracer.SpanTypeSQL),
+	)
+	ext.PeerService.Set(span, t.service)
+
+	if query == "" {
+		ext.Component.Set(span, op)
+	} else {
+		if t.obfuscate != nil {
+			query = t.obfuscate(query)
+		}
+		ext.DBStatement.Set(span, query)
+	}
+
+	return span, ddtracer.ContextWithSpan(ctx, span)
+}
+
+// finish finishes span, marking it as an error unless err is nil or driver.ErrSkip.
+func finish(span opentracing.Span, err error) {
+	if err != nil && err != driver.ErrSkip {
+		span.LogFields(log.Error(err))
+	}
+	span.Finish()
+}
+
+type tracedDriver struct {
+	driver.Driver
+	t *tracer
+}
+
+func (d *tracedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: c, t: d.t}, nil
+}
+
+type tracedConn struct {
+	driver.Conn
+	t *tracer
+}
+
+func (c *tracedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (stmt driver.Stmt, err error) {
+	span, ctx := c.t.start(ctx, "sql.prepare", query)
+	defer func() { finish(span, err) }()
+
+	if cpc, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = cpc.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tracedStmt{Stmt: stmt, t: c.t, query: query}, nil
+}
+
+func (c *tracedConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (tx driver.Tx, err error) {
+	span, spanCtx := c.t.start(ctx, "sql.begin", "")
+	defer func() { finish(span, err) }()
+
+	if cbt, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = cbt.BeginTx(spanCtx, opts)
+	} else {
+		tx, err = c.Conn.Begin()
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Commit and Rollback are siblings of the begin span.
+	return &tracedTx{Tx: tx, t: c.t, ctx: ctx}, nil
+}
+
+// ExecContext returns driver.ErrSkip when the driver doesn't support
+// executing without a prepared statement, which is then traced instead.
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (res driver.Result, err error) {
+	ec, hasContext := c.Conn.(driver.ExecerContext)
+	e, ok := c.Conn.(driver.Execer)
+	if !hasContext && !ok {
+		return nil, driver.ErrSkip
+	}
+
+	span, ctx := c.t.start(ctx, "sql.exec", query)
+	defer func() { finish(span, err) }()
+
+	if hasContext {
+		return ec.ExecContext(ctx, query, args)
+	}
+	dargs, err := namedValuesToValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return e.Exec(query, dargs)
+}
+
+// QueryContext returns driver.ErrSkip when the driver doesn't support
+// querying without a prepared statement, see ExecContext.
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (rows driver.Rows, err error) {
+	qc, hasContext := c.Conn.(driver.QueryerContext)
+	q, ok := c.Conn.(driver.Queryer)
+	if !hasContext && !ok {
+		return nil, driver.ErrSkip
+	}
+
+	span, ctx := c.t.start(ctx, "sql.query", query)
+	defer func() { finish(span, err) }()
+
+	if hasContext {
+		return qc.QueryContext(ctx, query, args)
+	}
+	dargs, err := namedValuesToValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return q.Query(query, dargs)
+}
+
+type tracedStmt struct {
+	driver.Stmt
+	t     *tracer
+	query string
+}
+
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (res driver.Result, err error) {
+	span, ctx := s.t.start(ctx, "sql.exec", s.query)
+	defer func() { finish(span, err) }()
+
+	if sec, ok := s.Stmt.(driver.StmtExecContext); ok {
+		return sec.ExecContext(ctx, args)
+	}
+	dargs, err := namedValuesToValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Stmt.Exec(dargs)
+}
+
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (rows driver.Rows, err error) {
+	span, ctx := s.t.start(ctx, "sql.query", s.query)
+	defer func() { finish(span, err) }()
+
+	if sqc, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		return sqc.QueryContext(ctx, args)
+	}
+	dargs, err := namedValuesToValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Stmt.Query(dargs)
+}
+
+type tracedTx struct {
+	driver.Tx
+	t   *tracer
+	ctx context.Context
+}
+
+func (tx *tracedTx) Commit() (err error) {
+	span, _ := tx.t.start(tx.ctx, "sql.commit", "")
+	defer func() { finish(span, err) }()
+	return tx.Tx.Commit()
+}
+
+func (tx *tracedTx) Rollback() (err error) {
+	span, _ := tx.t.start(tx.ctx, "sql.rollback", "")
+	defer func() { finish(span, err) }()
+	return tx.Tx.Rollback()
+}
+
+func namedValuesToValues(named []driver.NamedValue) ([]driver.Value, error) {
+	args := make([]driver.Value, len(named))
+	for i, nv := range named {
+		if nv.Name != "" {
+			return nil, driver.ErrSkip
+		}
+		args[i] = nv.Value
+	}
+	return args, nil
+}
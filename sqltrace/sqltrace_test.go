@@ -0,0 +1,163 @@
+package sqltrace
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDriver records the span found in the context of every call.
+type fakeDriver struct {
+	spans []*ddtracer.Span
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{d: d}, nil
+}
+
+func (d *fakeDriver) record(ctx context.Context) {
+	if span, ok := ddtracer.SpanFromContext(ctx).(*ddtracer.Span); ok {
+		d.spans = append(d.spans, span)
+	}
+}
+
+type fakeConn struct {
+	d *fakeDriver
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{c.d}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.d.record(ctx)
+	return fakeTx{}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.d.record(ctx)
+	if query == "fail" {
+		return nil, errors.New("boom")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.d.record(ctx)
+	return fakeRows{}, nil
+}
+
+type fakeStmt struct {
+	d *fakeDriver
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) { return fakeRows{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string              { return nil }
+func (fakeRows) Close() error                   { return nil }
+func (fakeRows) Next(dest []driver.Value) error { return io.EOF }
+
+func open(t *testing.T, name string, opts ...Option) (*sql.DB, *fakeDriver, *ddtracer.Span) {
+	tr := ddtracer.NewTracer()
+	d := &fakeDriver{}
+	sql.Register(name, Wrap("fake", d, tr, opts...))
+
+	db, err := sql.Open(name, "")
+	require.NoError(t, err)
+
+	return db, d, tr.StartSpan("parent").(*ddtracer.Span)
+}
+
+func TestQuery(t *testing.T) {
+	db, d, parent := open(t, "fake-query", ServiceName("app"))
+	defer db.Close()
+
+	ctx := ddtracer.ContextWithSpan(context.Background(), parent)
+	rows, err := db.QueryContext(ctx, "SELECT * FROM users WHERE id = 42")
+	require.NoError(t, err)
+	rows.Close()
+
+	require.Len(t, d.spans, 1)
+	span := d.spans[0]
+	assert.Equal(t, "sql.query", span.Name)
+	assert.Equal(t, "app-fake", span.Service)
+	assert.Equal(t, "sql", span.Type)
+	assert.Equal(t, "SELECT * FROM users WHERE id = 42", span.Resource)
+	assert.Equal(t, parent.TraceID, span.TraceID)
+	assert.Equal(t, parent.SpanID, span.ParentID)
+}
+
+func TestExecError(t *testing.T) {
+	db, d, parent := open(t, "fake-exec")
+	defer db.Close()
+
+	ctx := ddtracer.ContextWithSpan(context.Background(), parent)
+	_, err := db.ExecContext(ctx, "fail")
+	assert.Error(t, err)
+
+	require.Len(t, d.spans, 1)
+	assert.Equal(t, "sql.exec", d.spans[0].Name)
+	assert.Equal(t, "fake", d.spans[0].Service)
+	assert.Equal(t, int32(1), d.spans[0].Error)
+}
+
+func TestTx(t *testing.T) {
+	db, d, parent := open(t, "fake-tx")
+	defer db.Close()
+
+	ctx := ddtracer.ContextWithSpan(context.Background(), parent)
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = tx.ExecContext(ctx, "UPDATE users SET name = 'x'")
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	require.Len(t, d.spans, 2)
+	assert.Equal(t, "sql.begin", d.spans[0].Name)
+	assert.Equal(t, "sql.begin", d.spans[0].Resource)
+	assert.Equal(t, "sql.exec", d.spans[1].Name)
+}
+
+func TestObfuscation(t *testing.T) {
+	db, d, parent := open(t, "fake-obfuscation", WithObfuscation())
+	defer db.Close()
+
+	ctx := ddtracer.ContextWithSpan(context.Background(), parent)
+	_, err := db.ExecContext(ctx, "UPDATE users SET name = 'it''s' WHERE id = 42")
+	require.NoError(t, err)
+
+	require.Len(t, d.spans, 1)
+	assert.Equal(t, "UPDATE users SET name = ? WHERE id = ?", d.spans[0].Resource)
+	assert.Equal(t, "UPDATE users SET name = ? WHERE id = ?", d.spans[0].GetMeta("sql.query"))
+}
+
+func TestObfuscateQuery(t *testing.T) {
+	for query, expected := range map[string]string{
+		"SELECT 1":                        "SELECT ?",
+		"SELECT * FROM t2 WHERE a = 1.5":  "SELECT * FROM t2 WHERE a = ?",
+		"SELECT * FROM t WHERE a = 'b'":   "SELECT * FROM t WHERE a = ?",
+		"SELECT * FROM t WHERE a = $1":    "SELECT * FROM t WHERE a = $1",
+		"INSERT INTO t VALUES ('a', 'b')": "INSERT INTO t VALUES (?, ?)",
+	} {
+		assert.Equal(t, expected, ObfuscateQuery(query), query)
+	}
+}
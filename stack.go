@@ -0,0 +1,60 @@
+package ddtracer
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+const (
+	// stackTraceKey requests the creation stack trace of a span, see
+	// WithStackTrace.
+	stackTraceKey = "_dd.stack_trace"
+
+	// creationStackKey is the meta holding the creation stack trace of the
+	// spans finished with an error.
+	creationStackKey = "span.creation_stack"
+
+	// maxStackDepth bounds the frames captured.
+	maxStackDepth = 32
+)
+
+// WithStackTrace returns a StartSpanOption capturing the stack trace of the
+// creation of the span, set as the span.creation_stack meta if it finishes
+// with an error. It helps finding who created orphan or erroneous spans.
+// See WithCreationStackTraces to capture it for every span.
+func WithStackTrace() opentracing.StartSpanOption {
+	return opentracing.Tag{Key: stackTraceKey, Value: true}
+}
+
+// callers returns the program counters of the stack of the caller of
+// StartSpan, skip being the frames between it and callers.
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	return pcs[:runtime.Callers(skip+2, pcs)]
+}
+
+// setCreationStack sets the creation stack trace of the span if it has an
+// error. The span must be locked.
+func (s *Span) setCreationStack() {
+	if s.Error == 0 || len(s.callers) == 0 {
+		return
+	}
+	s.setMeta(creationStackKey, formatStack(s.callers))
+}
+
+// formatStack formats the frames of pcs as runtime/debug.Stack does.
+func formatStack(pcs []uintptr) string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
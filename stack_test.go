@@ -0,0 +1,43 @@
+package ddtracer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithStackTrace(t *testing.T) {
+	tr := NewTracer()
+
+	span := tr.StartSpan("op", WithStackTrace()).(*Span)
+	span.SetTag("error.object", errors.New("boom"))
+	span.Finish()
+
+	stack := span.GetMeta(creationStackKey)
+	assert.True(t, strings.HasPrefix(stack, "github.com/gchaincl/dd-go-opentracing.TestWithStackTrace\n"), stack)
+	assert.Empty(t, span.GetMeta(stackTraceKey))
+
+	t.Run("Without error", func(t *testing.T) {
+		span := tr.StartSpan("op", WithStackTrace()).(*Span)
+		span.Finish()
+		assert.Empty(t, span.GetMeta(creationStackKey))
+	})
+
+	t.Run("Not requested", func(t *testing.T) {
+		span := tr.StartSpan("op").(*Span)
+		span.SetTag("error", true)
+		span.Finish()
+		assert.Empty(t, span.GetMeta(creationStackKey))
+	})
+}
+
+func TestCreationStackTraces(t *testing.T) {
+	tr := NewTracerWithOptions(WithCreationStackTraces(true))
+
+	span := tr.StartSpan("op").(*Span)
+	span.SetTag("error", true)
+	span.Finish()
+	assert.Contains(t, span.GetMeta(creationStackKey), "TestCreationStackTraces")
+}
@@ -0,0 +1,222 @@
+package ddtracer
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// healthInterval is the interval at which the Tracer reports its health
+// metrics to DogStatsD.
+var healthInterval = 10 * time.Second
+
+// StatsdClient is the subset of the DogStatsD client, i.e.
+// github.com/DataDog/datadog-go/statsd.Client, used to send the span
+// metrics, see WithSpanMetrics.
+type StatsdClient interface {
+	Incr(name string, tags []string, rate float64) error
+	Distribution(name string, value float64, tags []string, rate float64) error
+}
+
+// statsdClient is the DogStatsD client of the Tracer, reporting its health
+// metrics and the span metrics. It's a no-op unless WithDogstatsdAddr is set.
+type statsdClient interface {
+	StatsdClient
+	Count(name string, value int64, tags []string, rate float64) error
+	Gauge(name string, value float64, tags []string, rate float64) error
+	Close() error
+}
+
+type noopStatsd struct{}
+
+func (noopStatsd) Incr(name string, tags []string, rate float64) error { return nil }
+func (noopStatsd) Count(name string, value int64, tags []string, rate float64) error {
+	return nil
+}
+func (noopStatsd) Gauge(name string, value float64, tags []string, rate float64) error {
+	return nil
+}
+func (noopStatsd) Distribution(name string, value float64, tags []string, rate float64) error {
+	return nil
+}
+func (noopStatsd) Close() error { return nil }
+
+// udpStatsd sends the metrics to DogStatsD over UDP, one datagram per metric.
+type udpStatsd struct {
+	conn net.Conn
+}
+
+func newUDPStatsd(addr string) (*udpStatsd, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &udpStatsd{conn: conn}, nil
+}
+
+func (c *udpStatsd) Incr(name string, tags []string, rate float64) error {
+	return c.send(name, "1", "c", tags, rate)
+}
+
+func (c *udpStatsd) Count(name string, value int64, tags []string, rate float64) error {
+	return c.send(name, strconv.FormatInt(value, 10), "c", tags, rate)
+}
+
+func (c *udpStatsd) Gauge(name string, value float64, tags []string, rate float64) error {
+	return c.send(name, strconv.FormatFloat(value, 'f', -1, 64), "g", tags, rate)
+}
+
+func (c *udpStatsd) Distribution(name string, value float64, tags []string, rate float64) error {
+	return c.send(name, strconv.FormatFloat(value, 'f', -1, 64), "d", tags, rate)
+}
+
+func (c *udpStatsd) Close() error {
+	return c.conn.Close()
+}
+
+// send writes the metric in the DogStatsD format, i.e.
+// "name:value|type|@rate|#tag1,tag2".
+func (c *udpStatsd) send(name, value, typ string, tags []string, rate float64) error {
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte(':')
+	b.WriteString(value)
+	b.WriteByte('|')
+	b.WriteString(typ)
+	if rate < 1 {
+		b.WriteString("|@")
+		b.WriteString(strconv.FormatFloat(rate, 'f', -1, 64))
+	}
+	if len(tags) > 0 {
+		b.WriteString("|#")
+		b.WriteString(strings.Join(tags, ","))
+	}
+	_, err := c.conn.Write([]byte(b.String()))
+	return err
+}
+
+// healthReporter sends the Tracer Stats to DogStatsD every healthInterval,
+// the counters as the increments since the previous report.
+type healthReporter struct {
+	t    *Tracer
+	last Stats
+	exit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func (t *Tracer) startHealthReporter() {
+	h := &healthReporter{t: t, exit: make(chan struct{})}
+	t.health = h
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+
+		ticker := time.NewTicker(healthInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				h.report()
+			case <-h.exit:
+				return
+			}
+		}
+	}()
+}
+
+// stop stops the reporting, once the last Stats have been reported.
+func (h *healthReporter) stop() {
+	close(h.exit)
+	h.wg.Wait()
+	h.report()
+}
+
+func (h *healthReporter) report() {
+	s := h.t.Stats()
+	tags := []string{"service:" + h.t.serviceName()}
+	for _, c := range []struct {
+		name      string
+		cur, prev uint64
+	}{
+		{"datadog.tracer.spans_started", s.SpansStarted, h.last.SpansStarted},
+		{"datadog.tracer.spans_finished", s.SpansFinished, h.last.SpansFinished},
+		{"datadog.tracer.spans_dropped", s.SpansDropped, h.last.SpansDropped},
+		{"datadog.tracer.spans_limited", s.SpansLimited, h.last.SpansLimited},
+		{"datadog.tracer.truncations", s.Truncations, h.last.Truncations},
+		{"datadog.tracer.flushes", s.Flushes, h.last.Flushes},
+		{"datadog.tracer.flush_errors", s.FlushErrors, h.last.FlushErrors},
+	} {
+		if c.cur > c.prev {
+			h.t.statsd.Count(c.name, int64(c.cur-c.prev), tags, 1)
+		}
+	}
+	h.t.statsd.Gauge("datadog.tracer.flush_latency", s.FlushLatency.Seconds(), tags, 1)
+	h.last = s
+}
+
+// spanMetrics is a SpanObserver sending the request count, error count and
+// latency of the top-level spans, i.e. the entry spans of every service,
+// before they are sampled.
+//
+// The metrics are named after the operation, as trace.<operation>.hits,
+// trace.<operation>.errors and trace.<operation>.duration in seconds, and
+// tagged by service, resource and env.
+type spanMetrics struct {
+	// client, when nil, is the one of the Tracer, see WithDogstatsdAddr.
+	client StatsdClient
+}
+
+func (m *spanMetrics) OnStart(span *Span) {}
+
+func (m *spanMetrics) OnFinish(span *Span) {
+	if span.ParentID != 0 && span.parentService == span.Service {
+		return
+	}
+
+	tags := []string{statsdTag("service", span.Service), statsdTag("resource", span.Resource)}
+	if env := span.GetMeta(string(EnvTag)); env != "" {
+		tags = append(tags, statsdTag("env", env))
+	}
+	prefix := "trace." + statsdMetricName(span.Name)
+
+	client := m.client
+	if client == nil {
+		client = span.tr.statsd
+	}
+
+	// DogStatsD is best effort, the errors are those of the UDP writes.
+	client.Incr(prefix+".hits", tags, 1)
+	if span.Error != 0 {
+		client.Incr(prefix+".errors", tags, 1)
+	}
+	client.Distribution(prefix+".duration", float64(span.Duration)/1e9, tags, 1)
+}
+
+// statsdTag returns the tag key:value, the characters of value breaking the
+// DogStatsD datagrams, i.e. the commas of the SQL resources, replaced by
+// underscores. The colons are kept, only the first one separating the key.
+func statsdTag(key, value string) string {
+	return key + ":" + strings.Map(func(r rune) rune {
+		switch r {
+		case ',', '|', '#', '\n', '\r':
+			return '_'
+		}
+		return r
+	}, value)
+}
+
+// statsdMetricName returns name with the characters not allowed in the metric
+// names, i.e. but the ASCII alphanumerics, underscores and periods, replaced
+// by underscores.
+func statsdMetricName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,118 @@
+package ddtracer
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingStatsd struct {
+	counts    map[string]int
+	durations map[string]float64
+	tags      []string
+}
+
+func (c *recordingStatsd) Incr(name string, tags []string, rate float64) error {
+	c.counts[name]++
+	c.tags = tags
+	return nil
+}
+
+func (c *recordingStatsd) Distribution(name string, value float64, tags []string, rate float64) error {
+	c.durations[name] = value
+	return nil
+}
+
+func TestSpanMetrics(t *testing.T) {
+	client := &recordingStatsd{counts: map[string]int{}, durations: map[string]float64{}}
+	tr := NewTracerWithOptions(
+		WithServiceName("api"),
+		WithSampleRate(0),
+		WithSpanMetrics(client),
+	)
+
+	start := time.Now()
+	root := tr.StartSpan("http.request", opentracing.StartTime(start), opentracing.Tag{Key: "env", Value: "prod"})
+	root.SetTag("resource.name", "GET /users")
+	child := tr.StartSpan("db.query", opentracing.ChildOf(root.Context()))
+	other := tr.StartSpan("redis.command", opentracing.ChildOf(root.Context()), opentracing.Tag{Key: "service.name", Value: "redis"})
+	ext.Error.Set(other, true)
+
+	other.Finish()
+	child.Finish()
+	root.FinishWithOptions(opentracing.FinishOptions{FinishTime: start.Add(2 * time.Second)})
+
+	// The child of the same service is not a top-level span.
+	assert.Equal(t, map[string]int{
+		"trace.http.request.hits":    1,
+		"trace.redis.command.hits":   1,
+		"trace.redis.command.errors": 1,
+	}, client.counts)
+	assert.Len(t, client.durations, 2)
+	assert.InDelta(t, 2, client.durations["trace.http.request.duration"], 0.001)
+	assert.Equal(t, []string{"service:api", "resource:GET /users", "env:prod"}, client.tags)
+}
+
+func TestSpanMetricsSanitized(t *testing.T) {
+	client := &recordingStatsd{counts: map[string]int{}, durations: map[string]float64{}}
+	tr := NewTracerWithOptions(WithServiceName("db|api"), WithSpanMetrics(client))
+
+	span := tr.StartSpan("sql:query #1",
+		opentracing.Tag{Key: "resource.name", Value: "SELECT id, name FROM users WHERE id = :id"})
+	span.Finish()
+
+	assert.Equal(t, map[string]int{"trace.sql_query__1.hits": 1}, client.counts)
+	assert.Equal(t, []string{"service:db_api", "resource:SELECT id_ name FROM users WHERE id = :id"}, client.tags)
+}
+
+func TestDogstatsdAddr(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	tr := NewTracerWithOptions(
+		WithServiceName("api"),
+		WithTransport(discardTransport{}),
+		WithDogstatsdAddr(conn.LocalAddr().String()),
+		WithSpanMetrics(nil),
+	)
+	tr.StartSpan("op").Finish()
+	require.NoError(t, tr.Close())
+
+	var metrics []string
+	buf := make([]byte, 1024)
+	for {
+		conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+		metrics = append(metrics, string(buf[:n]))
+	}
+
+	assert.Contains(t, metrics, "trace.op.hits:1|c|#service:api,resource:op")
+	assert.Contains(t, metrics, "datadog.tracer.spans_started:1|c|#service:api")
+	assert.Contains(t, metrics, "datadog.tracer.spans_finished:1|c|#service:api")
+}
+
+func TestUDPStatsdFormat(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client, err := newUDPStatsd(conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer client.Close()
+
+	require.NoError(t, client.Distribution("latency", 0.5, []string{"a:b", "c:d"}, 0.25))
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "latency:0.5|d|@0.25|#a:b,c:d", string(buf[:n]))
+}
@@ -0,0 +1,112 @@
+package ddtracer
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// The environment variables propagating the span contexts to subprocesses.
+const (
+	envTraceID          = "DD_TRACE_ID"
+	envParentID         = "DD_PARENT_ID"
+	envSamplingPriority = "DD_SAMPLING_PRIORITY"
+	envTraceTags        = "DD_TRACE_TAGS"
+)
+
+// envFields maps the Datadog headers onto their environment variable.
+var envFields = map[string]string{
+	fieldDatadogTraceID:          envTraceID,
+	fieldDatadogParentID:         envParentID,
+	fieldDatadogSamplingPriority: envSamplingPriority,
+	fieldDatadogTags:             envTraceTags,
+}
+
+// envCarrier holds the Datadog headers as environment variables, the other
+// ones (i.e. baggage) are dropped as the variable names are upper case.
+type envCarrier map[string]string
+
+func (c envCarrier) Set(key, val string) {
+	if name, ok := envFields[key]; ok {
+		c[name] = val
+	}
+}
+
+func (c envCarrier) ForeachKey(handler func(key, val string) error) error {
+	for field, name := range envFields {
+		if v, ok := c[name]; ok {
+			if err := handler(field, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// InjectEnv returns a copy of env, formatted as os.Environ, propagating sc to
+// a subprocess through the DD_TRACE_ID, DD_PARENT_ID, DD_SAMPLING_PRIORITY
+// and DD_TRACE_TAGS variables, replacing the ones of env. See ExtractEnv.
+func (t *Tracer) InjectEnv(sc opentracing.SpanContext, env []string) ([]string, error) {
+	ctx, ok := sc.(*SpanContext)
+	if !ok || ctx == nil {
+		return nil, opentracing.ErrInvalidSpanContext
+	}
+	ctx, err := ctx.refresh()
+	if err != nil {
+		return nil, err
+	}
+
+	c := envCarrier{}
+	if err := (&textMapPropagator{t: t}).Inject(ctx, c); err != nil {
+		return nil, err
+	}
+
+	injected := make([]string, 0, len(env)+len(c))
+	for _, kv := range env {
+		if name := kv[:strings.IndexByte(kv+"=", '=')]; !isEnvField(name) {
+			injected = append(injected, kv)
+		}
+	}
+	for name, v := range c {
+		injected = append(injected, name+"="+v)
+	}
+	return injected, nil
+}
+
+// InjectCmd propagates sc to the process run by cmd, adding the variables
+// of InjectEnv to its Env, the environment of the current process when nil.
+func (t *Tracer) InjectCmd(sc opentracing.SpanContext, cmd *exec.Cmd) error {
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	env, err := t.InjectEnv(sc, env)
+	if err != nil {
+		return err
+	}
+	cmd.Env = env
+	return nil
+}
+
+// ExtractEnv extracts the SpanContext propagated by the parent process
+// through env, i.e. os.Environ(), see InjectEnv. It lets the CLI tools
+// spawned by a traced process continue its trace.
+func (t *Tracer) ExtractEnv(env []string) (opentracing.SpanContext, error) {
+	c := envCarrier{}
+	for _, kv := range env {
+		if i := strings.IndexByte(kv, '='); i > 0 && isEnvField(kv[:i]) {
+			c[kv[:i]] = kv[i+1:]
+		}
+	}
+	return (&textMapPropagator{t: t}).Extract(c)
+}
+
+func isEnvField(name string) bool {
+	switch name {
+	case envTraceID, envParentID, envSamplingPriority, envTraceTags:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package ddtracer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInjectExtractEnv(t *testing.T) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}), WithTraceID128Bit(true)).(*Tracer)
+	span := tr.StartSpan("build", opentracing.Tag{Key: "sampling.priority", Value: PriorityUserKeep}).(*Span)
+	span.SetBaggageItem("user", "42")
+
+	env, err := tr.InjectEnv(span.Context(), []string{"PATH=/bin", "DD_TRACE_ID=1", "DD_PARENT_ID"})
+	require.NoError(t, err)
+	assert.Contains(t, env, "PATH=/bin")
+	assert.Contains(t, env, "DD_TRACE_ID="+strconv.FormatUint(span.TraceID, 10))
+	assert.Contains(t, env, "DD_PARENT_ID="+strconv.FormatUint(span.SpanID, 10))
+	assert.Contains(t, env, "DD_SAMPLING_PRIORITY=2")
+	assert.Len(t, env, 5)
+
+	sc, err := tr.ExtractEnv(env)
+	require.NoError(t, err)
+	ctx := sc.(*SpanContext)
+	assert.Equal(t, span.TraceID, ctx.TraceID())
+	assert.Equal(t, span.traceIDHigh, ctx.TraceIDHigh())
+	assert.Equal(t, span.SpanID, ctx.SpanID())
+	assert.Equal(t, PriorityUserKeep, ctx.samplingPriority())
+	assert.Empty(t, ctx.baggage)
+
+	_, err = tr.ExtractEnv([]string{"PATH=/bin"})
+	assert.Equal(t, opentracing.ErrSpanContextNotFound, err)
+
+	_, err = tr.InjectEnv(nil, nil)
+	assert.Equal(t, opentracing.ErrInvalidSpanContext, err)
+}
+
+func TestInjectCmd(t *testing.T) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{})).(*Tracer)
+	span := tr.StartSpan("build").(*Span)
+
+	cmd := exec.Command(os.Args[0], "-test.run=TestSubprocessHelper")
+	require.NoError(t, tr.InjectCmd(span.Context(), cmd))
+	cmd.Env = append(cmd.Env, "DD_TEST_SUBPROCESS=1")
+	out, err := cmd.Output()
+	require.NoError(t, err)
+
+	assert.Contains(t, string(out), fmt.Sprintf("trace_id=%d parent_id=%d", span.TraceID, span.SpanID))
+}
+
+// TestSubprocessHelper is the subprocess of TestInjectCmd, printing the
+// context it extracts from its environment.
+func TestSubprocessHelper(t *testing.T) {
+	if os.Getenv("DD_TEST_SUBPROCESS") == "" {
+		return
+	}
+
+	tr := NewTracerWithOptions(WithTransport(discardTransport{})).(*Tracer)
+	sc, err := tr.ExtractEnv(os.Environ())
+	require.NoError(t, err)
+	child := tr.StartSpan("child", opentracing.ChildOf(sc)).(*Span)
+	fmt.Printf("trace_id=%d parent_id=%d\n", child.TraceID, child.ParentID)
+}
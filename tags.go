@@ -0,0 +1,274 @@
+package ddtracer
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+const (
+	spanTypeKey     = "span.type"
+	serviceNameKey  = "service.name"
+	resourceNameKey = "resource.name"
+	traceIDKey      = "trace.id"
+	spanIDKey       = "span.id"
+
+	// measuredKey makes DataDog compute the stats of the spans which are
+	// not top-level, i.e. the client ones.
+	measuredKey = "_dd.measured"
+)
+
+// Well-known span types, see SpanType.
+const (
+	SpanTypeWeb   = "web"
+	SpanTypeHTTP  = "http"
+	SpanTypeDB    = "db"
+	SpanTypeSQL   = "sql"
+	SpanTypeCache = "cache"
+	SpanTypeQueue = "queue"
+)
+
+// SpanTypeTag sets the DataDog's type of a given span
+// i.e SpanTypeTag.Set(span, SpanTypeWeb)
+var SpanTypeTag = stringTagName(spanTypeKey)
+
+// SpanType returns a StartSpanOption setting the DataDog's type of the span,
+// i.e. tracer.StartSpan("op", SpanType(SpanTypeWeb)).
+func SpanType(t string) opentracing.StartSpanOption {
+	return opentracing.Tag{Key: spanTypeKey, Value: t}
+}
+
+// AnalyticsRate returns a StartSpanOption setting the rate at which the span
+// is indexed by trace search & analytics, i.e. tracer.StartSpan("op", AnalyticsRate(1)).
+func AnalyticsRate(rate float64) opentracing.StartSpanOption {
+	return opentracing.Tag{Key: analyticsRateKey, Value: rate}
+}
+
+// ServiceName returns a StartSpanOption overriding the service of the span,
+// i.e. tracer.StartSpan("op", ServiceName("user-db")).
+// Client spans can use ext.PeerService to the same effect.
+func ServiceName(name string) opentracing.StartSpanOption {
+	return opentracing.Tag{Key: serviceNameKey, Value: name}
+}
+
+// ResourceName returns a StartSpanOption setting the resource of the span,
+// i.e. tracer.StartSpan("op", ResourceName("GET /users")).
+func ResourceName(name string) opentracing.StartSpanOption {
+	return opentracing.Tag{Key: resourceNameKey, Value: name}
+}
+
+// WithTraceID returns a StartSpanOption overriding the generated trace ID of
+// the span, i.e. to bridge the traces from another tracing system with
+// deterministic IDs. Overriding the trace ID of a child span detaches it
+// from the trace of its parent.
+func WithTraceID(id uint64) opentracing.StartSpanOption {
+	return opentracing.Tag{Key: traceIDKey, Value: id}
+}
+
+// WithSpanID returns a StartSpanOption overriding the generated ID of the
+// span, see WithTraceID.
+func WithSpanID(id uint64) opentracing.StartSpanOption {
+	return opentracing.Tag{Key: spanIDKey, Value: id}
+}
+
+// TagMapper translates the value of a tag onto the DataDog span fields.
+// It's called with the span locked, so it must set the fields or use the
+// methods of the embedded tracer.Span rather than the Span ones, which
+// bypasses the limits set by WithMaxTags and WithMaxTagValueLength.
+type TagMapper func(span *Span, value interface{})
+
+var (
+	tagMappersMu sync.RWMutex
+	tagMappers   = map[string]TagMapper{
+		serviceNameKey:               mapService,
+		resourceNameKey:              mapResource,
+		string(ext.PeerService):      mapService,
+		string(ext.Component):        mapResource,
+		string(ext.SpanKind):         mapSpanKind,
+		string(ext.HTTPMethod):       mapMeta("http.method"),
+		string(ext.HTTPUrl):          mapHTTPURL,
+		string(ext.HTTPStatusCode):   mapHTTPStatusCode,
+		string(ext.DBType):           mapDBType,
+		string(ext.DBStatement):      mapDBStatement,
+		string(ext.SamplingPriority): mapSamplingPriority,
+		spanTypeKey:                  mapType,
+		analyticsRateKey:             mapAnalyticsRate,
+		traceIDKey:                   mapTraceID,
+		spanIDKey:                    mapSpanID,
+		linksKey:                     mapSpanLinks,
+		stackTraceKey:                func(*Span, interface{}) {},
+	}
+)
+
+// RegisterTagMapper sets m as the TagMapper of key, replacing the existing one.
+// Tags without a TagMapper are set as meta, or as metrics when the value is numeric.
+func RegisterTagMapper(key string, m TagMapper) {
+	tagMappersMu.Lock()
+	tagMappers[key] = m
+	tagMappersMu.Unlock()
+}
+
+func tagMapper(key string) (TagMapper, bool) {
+	tagMappersMu.RLock()
+	m, ok := tagMappers[key]
+	tagMappersMu.RUnlock()
+	return m, ok
+}
+
+// tagMetric returns value as a float64 if it is of a numeric type, so it can
+// be stored as a metric rather than as meta.
+func tagMetric(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// tagString formats value as fmt.Sprint does, without its overhead for the
+// most common types. Times are formatted as RFC 3339.
+func tagString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+func mapAnalyticsRate(span *Span, value interface{}) {
+	if rate, ok := tagMetric(value); ok {
+		span.Span.SetMetric(analyticsRateKey, clampRate(rate))
+	}
+}
+
+func mapService(span *Span, value interface{}) {
+	span.Service = tagString(value)
+}
+
+func mapResource(span *Span, value interface{}) {
+	span.Resource = tagString(value)
+}
+
+func mapTraceID(span *Span, value interface{}) {
+	if id, ok := value.(uint64); ok && id != 0 {
+		span.TraceID = id
+	}
+}
+
+func mapSpanID(span *Span, value interface{}) {
+	if id, ok := value.(uint64); ok && id != 0 {
+		span.SpanID = id
+	}
+}
+
+func mapType(span *Span, value interface{}) {
+	span.Type = tagString(value)
+}
+
+func mapMeta(key string) TagMapper {
+	return func(span *Span, value interface{}) {
+		span.setMeta(key, tagString(value))
+	}
+}
+
+// mapHTTPURL sets the http.url meta to the sanitized URL, see SanitizeURL.
+func mapHTTPURL(span *Span, value interface{}) {
+	span.setMeta("http.url", SanitizeURL(tagString(value)))
+}
+
+// mapSpanKind sets the span.kind meta. The client and producer spans are
+// measured, and the spans without type get the one of their kind.
+func mapSpanKind(span *Span, value interface{}) {
+	kind := tagString(value)
+	span.setMeta("span.kind", kind)
+
+	var typ string
+	switch ext.SpanKindEnum(kind) {
+	case ext.SpanKindRPCServerEnum:
+		typ = SpanTypeWeb
+	case ext.SpanKindRPCClientEnum:
+		typ = SpanTypeHTTP
+		span.Span.SetMetric(measuredKey, 1)
+	case ext.SpanKindProducerEnum:
+		typ = SpanTypeQueue
+		span.Span.SetMetric(measuredKey, 1)
+	case ext.SpanKindConsumerEnum:
+		typ = SpanTypeQueue
+	}
+	if span.Type == "" {
+		span.Type = typ
+	}
+}
+
+func mapHTTPStatusCode(span *Span, value interface{}) {
+	code := tagString(value)
+	span.setMeta("http.status_code", code)
+	if n, err := strconv.Atoi(code); err == nil && n >= 500 {
+		span.Error = 1
+	}
+}
+
+func mapDBType(span *Span, value interface{}) {
+	switch t := tagString(value); t {
+	case SpanTypeSQL, "cassandra":
+		span.Type = t
+	case "redis", "memcached":
+		span.Type = SpanTypeCache
+	default:
+		span.Type = SpanTypeDB
+	}
+	span.setMeta("db.type", tagString(value))
+}
+
+func mapDBStatement(span *Span, value interface{}) {
+	query := tagString(value)
+	if span.tr != nil && span.tr.obfuscateSQL {
+		query = ObfuscateSQL(query)
+	}
+	span.setMeta("sql.query", query)
+	span.Resource = query
+}
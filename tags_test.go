@@ -0,0 +1,140 @@
+package ddtracer
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagMappers(t *testing.T) {
+	tr := NewTracer()
+
+	t.Run("HTTP", func(t *testing.T) {
+		span := tr.StartSpan("test").(*Span)
+		ext.SpanKindRPCServer.Set(span)
+		ext.HTTPMethod.Set(span, "GET")
+		ext.HTTPUrl.Set(span, "/users/1")
+		ext.HTTPStatusCode.Set(span, 503)
+
+		assert.Equal(t, "server", span.GetMeta("span.kind"))
+		assert.Equal(t, "GET", span.GetMeta("http.method"))
+		assert.Equal(t, "/users/1", span.GetMeta("http.url"))
+		assert.Equal(t, "503", span.GetMeta("http.status_code"))
+		assert.Equal(t, int32(1), span.Error)
+	})
+
+	t.Run("DB", func(t *testing.T) {
+		span := tr.StartSpan("test").(*Span)
+		ext.DBType.Set(span, "sql")
+		ext.DBStatement.Set(span, "SELECT * FROM users")
+		ext.PeerService.Set(span, "postgres")
+
+		assert.Equal(t, "sql", span.Type)
+		assert.Equal(t, "SELECT * FROM users", span.GetMeta("sql.query"))
+		assert.Equal(t, "SELECT * FROM users", span.Resource)
+		assert.Equal(t, "postgres", span.Service)
+
+		ext.DBType.Set(span, "redis")
+		assert.Equal(t, "cache", span.Type)
+	})
+
+	t.Run("Type", func(t *testing.T) {
+		span := tr.StartSpan("test", SpanType(SpanTypeWeb)).(*Span)
+		assert.Equal(t, "web", span.Type)
+
+		SpanTypeTag.Set(span, "custom")
+		assert.Equal(t, "custom", span.Type)
+		assert.Empty(t, span.GetMeta("span.type"))
+	})
+
+	t.Run("Span kind", func(t *testing.T) {
+		server := tr.StartSpan("test", ext.SpanKindRPCServer).(*Span)
+		assert.Equal(t, "server", server.GetMeta("span.kind"))
+		assert.Equal(t, SpanTypeWeb, server.Type)
+		assert.NotContains(t, server.Metrics, measuredKey)
+
+		client := tr.StartSpan("test", ext.SpanKindRPCClient).(*Span)
+		assert.Equal(t, "client", client.GetMeta("span.kind"))
+		assert.Equal(t, SpanTypeHTTP, client.Type)
+		assert.Equal(t, float64(1), client.Metrics[measuredKey])
+
+		producer := tr.StartSpan("test", ext.SpanKindProducer).(*Span)
+		assert.Equal(t, SpanTypeQueue, producer.Type)
+		assert.Equal(t, float64(1), producer.Metrics[measuredKey])
+
+		// The explicit type takes precedence, whatever the order.
+		db := tr.StartSpan("test", SpanType(SpanTypeSQL)).(*Span)
+		ext.SpanKindRPCClient.Set(db)
+		assert.Equal(t, SpanTypeSQL, db.Type)
+		db = tr.StartSpan("test", ext.SpanKindRPCClient).(*Span)
+		ext.DBType.Set(db, "sql")
+		assert.Equal(t, SpanTypeSQL, db.Type)
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		RegisterTagMapper("custom.resource", func(span *Span, value interface{}) {
+			span.Resource = fmt.Sprintf("custom:%v", value)
+		})
+		defer func() {
+			tagMappersMu.Lock()
+			delete(tagMappers, "custom.resource")
+			tagMappersMu.Unlock()
+		}()
+
+		span := tr.StartSpan("test").(*Span)
+		span.SetTag("custom.resource", 1.5)
+		assert.Equal(t, "custom:1.5", span.Resource)
+		assert.Empty(t, span.Metrics["custom.resource"])
+	})
+}
+
+func TestTagString(t *testing.T) {
+	for _, v := range []interface{}{
+		"s", true, 42, int64(-42), int32(42), uint64(42), uint32(42), uint16(500),
+		1.5, 1e21, float32(1.5), errors.New("boom"), nil, []int{1},
+	} {
+		assert.Equal(t, fmt.Sprint(v), tagString(v), "%#v", v)
+	}
+}
+
+func TestSpanStartOptions(t *testing.T) {
+	tr := NewTracer()
+
+	span := tr.StartSpan("query",
+		ServiceName("user-db"),
+		ResourceName("SELECT users"),
+		SpanType(SpanTypeSQL),
+	).(*Span)
+	assert.Equal(t, "user-db", span.Service)
+	assert.Equal(t, "SELECT users", span.Resource)
+	assert.Equal(t, SpanTypeSQL, span.Type)
+
+	child := tr.StartSpan("call",
+		opentracing.ChildOf(span.Context()),
+		opentracing.Tag{Key: string(ext.PeerService), Value: "billing"},
+	).(*Span)
+	assert.Equal(t, "billing", child.Service)
+	assert.Equal(t, span.TraceID, child.TraceID)
+}
+
+func TestSpanIDOptions(t *testing.T) {
+	tr := NewTracer()
+
+	span := tr.StartSpan("replay", WithTraceID(42), WithSpanID(7)).(*Span)
+	assert.Equal(t, uint64(42), span.TraceID)
+	assert.Equal(t, uint64(7), span.SpanID)
+
+	sc := span.Context().(*SpanContext)
+	assert.Equal(t, uint64(42), sc.TraceID())
+	assert.Equal(t, uint64(7), sc.SpanID())
+
+	child := tr.StartSpan("call", opentracing.ChildOf(sc), WithSpanID(8)).(*Span)
+	assert.Equal(t, uint64(42), child.TraceID)
+	assert.Equal(t, uint64(7), child.ParentID)
+	assert.Equal(t, uint64(8), child.SpanID)
+	assert.Empty(t, child.GetMeta(spanIDKey))
+}
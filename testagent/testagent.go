@@ -0,0 +1,243 @@
+// Package testagent provides an in-process fake of the DataDog agent,
+// receiving the traces sent to its /v0.3 and /v0.4 endpoints, JSON or
+// msgpack encoded and optionally gzipped, to test end to end the code sending
+// them.
+//
+//	agent := testagent.New()
+//	defer agent.Close()
+//	tr := ddtracer.NewTracerWithOptions(ddtracer.WithAgentAddr(agent.Addr()))
+//	// ... code under test using tr ...
+//	tr.Close()
+//	agent.AssertSpan(t, "http.request", map[string]string{"http.method": "GET"})
+package testagent
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/ugorji/go/codec"
+)
+
+// Agent is a fake DataDog agent recording the traces it receives.
+type Agent struct {
+	srv *httptest.Server
+
+	mu         sync.Mutex
+	traces     [][]*tracer.Span
+	payloads   int
+	errors     []error
+	maxPayload int
+	rejected   int
+}
+
+// New starts an Agent listening on a local port, it has to be closed.
+func New() *Agent {
+	a := &Agent{}
+	mux := http.NewServeMux()
+	for _, version := range []string{"v0.3", "v0.4"} {
+		mux.HandleFunc("/"+version+"/traces", a.handleTraces)
+		mux.HandleFunc("/"+version+"/services", a.handleServices)
+	}
+	a.srv = httptest.NewServer(mux)
+	return a
+}
+
+// Addr returns the host:port address of the Agent, for ddtracer.WithAgentAddr.
+func (a *Agent) Addr() string {
+	return a.srv.Listener.Addr().String()
+}
+
+// Close stops the Agent.
+func (a *Agent) Close() {
+	a.srv.Close()
+}
+
+// SetMaxPayloadSize makes the Agent reject the payloads larger than n bytes
+// once uncompressed, as the DataDog agent does, unlimited when zero.
+func (a *Agent) SetMaxPayloadSize(n int) {
+	a.mu.Lock()
+	a.maxPayload = n
+	a.mu.Unlock()
+}
+
+func (a *Agent) handleTraces(w http.ResponseWriter, r *http.Request) {
+	body, err := readBody(r)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err == nil && a.maxPayload > 0 && len(body) > a.maxPayload {
+		a.rejected++
+		http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var traces [][]*tracer.Span
+	if err == nil {
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/msgpack") {
+			var mh codec.MsgpackHandle
+			err = codec.NewDecoderBytes(body, &mh).Decode(&traces)
+		} else {
+			err = json.Unmarshal(body, &traces)
+		}
+	}
+	if err != nil {
+		a.errors = append(a.errors, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.payloads++
+	a.traces = append(a.traces, traces...)
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, `{"rate_by_service":{}}`)
+}
+
+// readBody reads the body of r, decompressing it when gzipped.
+func readBody(r *http.Request) ([]byte, error) {
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		body = zr
+	}
+	return ioutil.ReadAll(body)
+}
+
+func (a *Agent) handleServices(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// Traces returns the traces received so far, the spans of each of them
+// sorted by start time.
+func (a *Agent) Traces() [][]*tracer.Span {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	traces := make([][]*tracer.Span, len(a.traces))
+	for i, trace := range a.traces {
+		traces[i] = append([]*tracer.Span(nil), trace...)
+		sort.SliceStable(traces[i], func(j, k int) bool {
+			return traces[i][j].Start < traces[i][k].Start
+		})
+	}
+	return traces
+}
+
+// Spans returns the spans of all the traces received so far.
+func (a *Agent) Spans() []*tracer.Span {
+	var spans []*tracer.Span
+	for _, trace := range a.Traces() {
+		spans = append(spans, trace...)
+	}
+	return spans
+}
+
+// FindSpans returns the spans received so far named name.
+func (a *Agent) FindSpans(name string) []*tracer.Span {
+	var spans []*tracer.Span
+	for _, span := range a.Spans() {
+		if span.Name == name {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// Payloads returns the number of payloads received, i.e. of flushes.
+func (a *Agent) Payloads() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.payloads
+}
+
+// Rejected returns the number of payloads rejected as too large, see
+// SetMaxPayloadSize.
+func (a *Agent) Rejected() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.rejected
+}
+
+// Reset discards the traces received so far.
+func (a *Agent) Reset() {
+	a.mu.Lock()
+	a.traces, a.payloads, a.errors, a.rejected = nil, 0, nil, 0
+	a.mu.Unlock()
+}
+
+// WaitForTraces waits up to timeout for at least n traces to be received,
+// returning them. It's meant for the tracers flushing in the background.
+func (a *Agent) WaitForTraces(n int, timeout time.Duration) ([][]*tracer.Span, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		traces := a.Traces()
+		if len(traces) >= n {
+			return traces, nil
+		}
+		if time.Now().After(deadline) {
+			return traces, fmt.Errorf("received %d traces, want %d", len(traces), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// AssertNoErrors fails t if any of the payloads received could not be decoded.
+func (a *Agent) AssertNoErrors(t testing.TB) bool {
+	t.Helper()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, err := range a.errors {
+		t.Errorf("testagent: decoding payload: %v", err)
+	}
+	return len(a.errors) == 0
+}
+
+// AssertTraceCount fails t unless exactly n traces have been received.
+func (a *Agent) AssertTraceCount(t testing.TB, n int) bool {
+	t.Helper()
+
+	if got := len(a.Traces()); got != n {
+		t.Errorf("testagent: received %d traces, want %d", got, n)
+		return false
+	}
+	return true
+}
+
+// AssertSpan fails t unless a span named name with all the meta of meta has
+// been received, returning the first one matching.
+func (a *Agent) AssertSpan(t testing.TB, name string, meta map[string]string) *tracer.Span {
+	t.Helper()
+
+	spans := a.FindSpans(name)
+	for _, span := range spans {
+		if hasMeta(span, meta) {
+			return span
+		}
+	}
+	t.Errorf("testagent: no span %q with meta %v among the %d received with this name", name, meta, len(spans))
+	return nil
+}
+
+func hasMeta(span *tracer.Span, meta map[string]string) bool {
+	for k, v := range meta {
+		if got, ok := span.Meta[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+package testagent
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	ddtracer "github.com/gchaincl/dd-go-opentracing"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgent(t *testing.T) {
+	for name, opts := range map[string][]ddtracer.Option{
+		"msgpack":      nil,
+		"json":         {ddtracer.WithHTTPClient(&http.Client{Timeout: time.Second})},
+		"msgpack gzip": {ddtracer.WithEncoding(ddtracer.EncodingMsgpack), ddtracer.WithCompression(true)},
+		"json gzip":    {ddtracer.WithEncoding(ddtracer.EncodingJSON), ddtracer.WithCompression(true)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			agent := New()
+			defer agent.Close()
+
+			tr := ddtracer.NewTracerWithOptions(append(opts,
+				ddtracer.WithAgentAddr(agent.Addr()),
+				ddtracer.WithServiceName("svc"),
+			)...)
+			parent := tr.StartSpan("parent")
+			child := tr.StartSpan("child", opentracing.ChildOf(parent.Context()), opentracing.Tag{Key: "key", Value: "value"})
+			child.Finish()
+			parent.Finish()
+			tr.StartSpan("other").Finish()
+			require.NoError(t, tr.Close())
+
+			traces, err := agent.WaitForTraces(2, time.Second)
+			require.NoError(t, err)
+			assert.True(t, agent.AssertNoErrors(t))
+			assert.True(t, agent.AssertTraceCount(t, 2))
+			assert.Equal(t, 1, agent.Payloads())
+			assert.Len(t, traces, 2)
+			assert.Len(t, agent.Spans(), 3)
+
+			p := agent.AssertSpan(t, "parent", nil)
+			c := agent.AssertSpan(t, "child", map[string]string{"key": "value"})
+			require.NotNil(t, p)
+			require.NotNil(t, c)
+			assert.Equal(t, "svc", c.Service)
+			assert.Equal(t, p.SpanID, c.ParentID)
+			assert.Equal(t, p.TraceID, c.TraceID)
+
+			agent.Reset()
+			assert.Empty(t, agent.Spans())
+		})
+	}
+}
+
+func TestPayloadChunking(t *testing.T) {
+	run := func(opts ...ddtracer.Option) *Agent {
+		agent := New()
+		agent.SetMaxPayloadSize(4096)
+
+		tr := ddtracer.NewTracerWithOptions(append(opts, ddtracer.WithAgentAddr(agent.Addr()))...)
+		root := tr.StartSpan("root")
+		for i := 0; i < 50; i++ {
+			tr.StartSpan("child", opentracing.ChildOf(root.Context()), opentracing.Tag{Key: "value", Value: strings.Repeat("x", 200)}).Finish()
+		}
+		root.Finish()
+		tr.Close()
+		return agent
+	}
+
+	t.Run("Rejected", func(t *testing.T) {
+		agent := run()
+		defer agent.Close()
+
+		assert.Equal(t, 1, agent.Rejected())
+		assert.Empty(t, agent.Spans())
+	})
+
+	for _, enc := range []ddtracer.Encoding{ddtracer.EncodingMsgpack, ddtracer.EncodingJSON} {
+		t.Run(string(enc), func(t *testing.T) {
+			agent := run(ddtracer.WithEncoding(enc), ddtracer.WithMaxPayloadBytes(4096), ddtracer.WithCompression(true))
+			defer agent.Close()
+
+			assert.True(t, agent.AssertNoErrors(t))
+			assert.Zero(t, agent.Rejected())
+			assert.True(t, agent.Payloads() > 1)
+
+			spans := agent.Spans()
+			require.Len(t, spans, 51)
+			for _, span := range spans {
+				assert.Equal(t, spans[0].TraceID, span.TraceID)
+			}
+		})
+	}
+}
+
+// recordingT records the errors rather than failing the test.
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertionsFail(t *testing.T) {
+	agent := New()
+	defer agent.Close()
+
+	rt := &recordingT{TB: t}
+	assert.Nil(t, agent.AssertSpan(rt, "missing", nil))
+	assert.False(t, agent.AssertTraceCount(rt, 1))
+	assert.Equal(t, []string{
+		`testagent: no span "missing" with meta map[] among the 0 received with this name`,
+		"testagent: received 0 traces, want 1",
+	}, rt.errors)
+
+	_, err := agent.WaitForTraces(1, 20*time.Millisecond)
+	assert.Error(t, err)
+}
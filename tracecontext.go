@@ -0,0 +1,103 @@
+package ddtracer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+const (
+	traceParentKey = "traceparent"
+	traceStateKey  = "tracestate"
+)
+
+// traceContextPropagator implements W3C Trace Context propagation
+// (https://www.w3.org/TR/trace-context/).
+// The upper 64 bits of the trace IDs are kept apart, and the tracestate
+// is kept as is for re-injection.
+type traceContextPropagator struct{}
+
+func (p *traceContextPropagator) Inject(sc *SpanContext, carrier interface{}) error {
+	tm, ok := carrier.(opentracing.TextMapWriter)
+	if !ok {
+		return opentracing.ErrInvalidCarrier
+	}
+
+	flags := "00"
+	if sc.sampled {
+		flags = "01"
+	}
+	tm.Set(traceParentKey, fmt.Sprintf("00-%016x%016x-%016x-%s", sc.traceIDHigh, sc.traceID, sc.spanID, flags))
+	if sc.traceState != "" {
+		tm.Set(traceStateKey, sc.traceState)
+	}
+
+	return nil
+}
+
+func (p *traceContextPropagator) Extract(carrier interface{}) (opentracing.SpanContext, error) {
+	tm, ok := carrier.(opentracing.TextMapReader)
+	if !ok {
+		return nil, opentracing.ErrInvalidCarrier
+	}
+
+	var traceParent, traceState string
+	tm.ForeachKey(func(k, v string) error {
+		switch lowerKey(k) {
+		case traceParentKey:
+			traceParent = strings.TrimSpace(v)
+		case traceStateKey:
+			traceState = v
+		}
+		return nil
+	})
+
+	if traceParent == "" {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+
+	parts := strings.Split(traceParent, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+
+	traceIDHigh, err := strconv.ParseUint(parts[1][:16], 16, 64)
+	if err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	traceID, err := strconv.ParseUint(parts[1][16:], 16, 64)
+	if err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	spanID, err := strconv.ParseUint(parts[2], 16, 64)
+	if err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+
+	if (traceIDHigh == 0 && traceID == 0) || spanID == 0 {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+
+	span := &Span{
+		Span: &tracer.Span{
+			SpanID:  spanID,
+			TraceID: traceID,
+			Sampled: flags&0x1 == 1,
+		},
+		traceState:  traceState,
+		traceIDHigh: traceIDHigh,
+	}
+
+	return span.Context(), nil
+}
@@ -0,0 +1,52 @@
+package ddtracer
+
+import (
+	"net/http"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceContextPropagation(t *testing.T) {
+	tr := NewTracer().(*Tracer)
+	tr.EnableTraceContext(true, true)
+
+	sc, err := tr.ExtractHTTPHeader(http.Header{
+		"Traceparent": []string{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		"Tracestate":  []string{"congo=t61rcWkgMzE,rojo=00f067aa0ba902b7"},
+	})
+	require.NoError(t, err)
+
+	ctx := sc.(*SpanContext)
+	assert.Equal(t, uint64(0xa3ce929d0e0e4736), ctx.traceID)
+	assert.Equal(t, uint64(0x4bf92f3577b34da6), ctx.traceIDHigh)
+	assert.Equal(t, uint64(0x00f067aa0ba902b7), ctx.spanID)
+	assert.True(t, ctx.sampled)
+
+	child := tr.StartSpan("child", opentracing.ChildOf(sc)).(*Span)
+	child.SpanID = 0xaa
+	child.TraceID = 0xbb
+
+	h := http.Header{}
+	require.NoError(t, tr.InjectHTTPHeader(child.Context(), h))
+	assert.Equal(t, "00-4bf92f3577b34da600000000000000bb-00000000000000aa-01", h.Get("Traceparent"))
+	assert.Equal(t, "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7", h.Get("Tracestate"))
+	assert.Equal(t, "187", h.Get("X-Datadog-Trace-Id"))
+	assert.Equal(t, "_dd.p.tid=4bf92f3577b34da6", h.Get("X-Datadog-Tags"))
+
+	t.Run("Corrupted", func(t *testing.T) {
+		for _, tp := range []string{
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+			"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-00",
+		} {
+			_, err := tr.ExtractHTTPHeader(http.Header{"Traceparent": []string{tp}})
+			assert.Equal(t, opentracing.ErrSpanContextCorrupted, err, tp)
+		}
+	})
+}
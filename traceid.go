@@ -0,0 +1,40 @@
+package ddtracer
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// traceIDHighKey is the meta holding the upper 64 bits of 128-bit trace IDs,
+// hex encoded. It's also propagated in the x-datadog-tags header.
+const traceIDHighKey = "_dd.p.tid"
+
+// newTraceIDHigh returns the upper 64 bits of a 128-bit trace ID: the
+// current unix time in seconds followed by 32 zero bits, as the other
+// DataDog tracers do.
+func newTraceIDHigh() uint64 {
+	return uint64(time.Now().Unix()) << 32
+}
+
+// parseTraceIDHigh returns the upper 64 bits of the trace ID found in the
+// x-datadog-tags header, or 0 when there's none or it's malformed.
+func parseTraceIDHigh(tags string) uint64 {
+	for _, tag := range strings.Split(tags, ",") {
+		kv := strings.SplitN(tag, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) != traceIDHighKey {
+			continue
+		}
+
+		v := strings.TrimSpace(kv[1])
+		if len(v) != 16 {
+			return 0
+		}
+		high, err := strconv.ParseUint(v, 16, 64)
+		if err != nil {
+			return 0
+		}
+		return high
+	}
+	return 0
+}
@@ -0,0 +1,85 @@
+package ddtracer
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceID128Bit(t *testing.T) {
+	tr := NewTracerWithOptions(WithTraceID128Bit(true)).(*Tracer)
+	tr.EnableB3(true, false)
+	tr.EnableTraceContext(true, false)
+
+	root := tr.StartSpan("root").(*Span)
+	high := root.traceIDHigh
+	require.NotZero(t, high)
+	assert.Equal(t, uint64(0), high&0xffffffff)
+	assert.InDelta(t, time.Now().Unix(), int64(high>>32), 5)
+	assert.Equal(t, fmt.Sprintf("%016x", high), root.GetMeta(traceIDHighKey))
+
+	child := tr.StartSpan("child", opentracing.ChildOf(root.Context())).(*Span)
+	assert.Equal(t, high, child.traceIDHigh)
+	assert.Equal(t, root.GetMeta(traceIDHighKey), child.GetMeta(traceIDHighKey))
+
+	carrier := opentracing.TextMapCarrier{}
+	require.NoError(t, tr.Inject(child.Context(), opentracing.TextMap, carrier))
+	assert.Equal(t, traceIDHighKey+"="+fmt.Sprintf("%016x", high), carrier[fieldDatadogTags])
+	assert.Equal(t, fmt.Sprintf("%016x%016x", high, child.TraceID), carrier[b3TraceID])
+	assert.Contains(t, carrier[traceParentKey], fmt.Sprintf("00-%016x%016x-", high, child.TraceID))
+
+	for _, format := range []interface{}{opentracing.TextMap, opentracing.Binary} {
+		var sc opentracing.SpanContext
+		var err error
+		if format == opentracing.Binary {
+			buf := &bytes.Buffer{}
+			require.NoError(t, tr.Inject(child.Context(), format, buf))
+			sc, err = tr.Extract(format, buf)
+		} else {
+			sc, err = tr.Extract(format, carrier)
+		}
+		require.NoError(t, err)
+
+		remote := tr.StartSpan("remote", opentracing.ChildOf(sc)).(*Span)
+		assert.Equal(t, child.TraceID, remote.TraceID, formatName(format))
+		assert.Equal(t, high, remote.traceIDHigh, formatName(format))
+		assert.Equal(t, fmt.Sprintf("%016x", high), remote.GetMeta(traceIDHighKey))
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		span := NewTracer().StartSpan("root").(*Span)
+		assert.Zero(t, span.traceIDHigh)
+		assert.Empty(t, span.GetMeta(traceIDHighKey))
+	})
+}
+
+func TestExtractB3TraceID128Bit(t *testing.T) {
+	tr := NewTracer().(*Tracer)
+	tr.EnableB3(false, true)
+
+	sc, err := tr.Extract(opentracing.TextMap, opentracing.TextMapCarrier{
+		b3TraceID: "463ac35c9f6413ad48485a3953bb6124",
+		b3SpanID:  "a2fb4a1d1a96d312",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0x463ac35c9f6413ad), sc.(*SpanContext).traceIDHigh)
+	assert.Equal(t, uint64(0x48485a3953bb6124), sc.(*SpanContext).traceID)
+}
+
+func TestParseTraceIDHigh(t *testing.T) {
+	for tags, high := range map[string]uint64{
+		"_dd.p.tid=640cfd8d00000000":             0x640cfd8d00000000,
+		"_dd.p.dm=-1,_dd.p.tid=640cfd8d00000000": 0x640cfd8d00000000,
+		"_dd.p.tid=640cfd8d":                     0,
+		"_dd.p.tid=zzzzzzzzzzzzzzzz":             0,
+		"_dd.p.dm=-1":                            0,
+		"":                                       0,
+	} {
+		assert.Equal(t, high, parseTraceIDHigh(tags), tags)
+	}
+}
@@ -0,0 +1,34 @@
+package ddtracer
+
+import (
+	"strings"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// traceTagPrefix prefixes the baggage items holding the trace tags, which
+// are then propagated as ot-baggage-trace-tag-* headers.
+const traceTagPrefix = "trace-tag-"
+
+// SetTraceTag sets the tag key on the span and on its local root, and on
+// every span of the trace started after it, including the downstream ones
+// as it's propagated as a baggage item. It's meant for the attributes known
+// at the edge, i.e. a customer ID. Keep the keys lower case, as the HTTP
+// headers might be.
+func (s *Span) SetTraceTag(key, value string) opentracing.Span {
+	s.SetBaggageItem(traceTagPrefix+key, value)
+	s.SetTag(key, value)
+	if s.root != nil && s.root != s {
+		s.root.SetTag(key, value)
+	}
+	return s
+}
+
+// setTraceTags sets the trace tags found in baggage.
+func (s *Span) setTraceTags(baggage map[string]string) {
+	for k, v := range baggage {
+		if strings.HasPrefix(k, traceTagPrefix) {
+			s.SetTag(strings.TrimPrefix(k, traceTagPrefix), v)
+		}
+	}
+}
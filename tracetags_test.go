@@ -0,0 +1,39 @@
+package ddtracer
+
+import (
+	"net/http"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetTraceTag(t *testing.T) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}))
+
+	root := tr.StartSpan("root").(*Span)
+	edge := tr.StartSpan("edge", opentracing.ChildOf(root.Context())).(*Span)
+	edge.SetTraceTag("customer_id", "42")
+	assert.Equal(t, "42", edge.GetMeta("customer_id"))
+	assert.Equal(t, "42", root.GetMeta("customer_id"))
+
+	child := tr.StartSpan("child", opentracing.ChildOf(edge.Context())).(*Span)
+	assert.Equal(t, "42", child.GetMeta("customer_id"))
+
+	// The explicit tags take precedence.
+	other := tr.StartSpan("other", opentracing.ChildOf(edge.Context()), opentracing.Tag{Key: "customer_id", Value: "7"}).(*Span)
+	assert.Equal(t, "7", other.GetMeta("customer_id"))
+
+	h := http.Header{}
+	require.NoError(t, tr.Inject(child.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(h)))
+	assert.Equal(t, "42", h.Get("ot-baggage-trace-tag-customer_id"))
+
+	sc, err := tr.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(h))
+	require.NoError(t, err)
+	downstream := tr.StartSpan("downstream", opentracing.ChildOf(sc)).(*Span)
+	assert.Equal(t, "42", downstream.GetMeta("customer_id"))
+	downstream.SetTraceTag("region", "eu")
+	assert.Equal(t, "eu", downstream.GetMeta("region"))
+	assert.Empty(t, root.GetMeta("region"))
+}
@@ -2,122 +2,562 @@ package ddtracer
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	stdlog "log"
-	"math/rand"
+	"math"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/DataDog/dd-trace-go/tracer"
 	opentracing "github.com/opentracing/opentracing-go"
-	"github.com/opentracing/opentracing-go/ext"
 	"github.com/opentracing/opentracing-go/log"
 )
 
-func init() {
-	rand.Seed(time.Now().UnixNano())
-}
-
 func defaultHostname() string {
 	host, _ := os.Hostname()
 	return host
 }
 
 var (
-	DefaultService  = defaultHostname()
+	// DefaultService is the service of root spans when the Tracer has none configured.
+	//
+	// Deprecated: use WithServiceName instead, DefaultService is shared by all
+	// the tracers and it's not safe to change it concurrently.
+	DefaultService = defaultHostname()
+
+	// DefaultResource is the resource of root spans when the Tracer has none configured
+	// and it doesn't infer it from the operation name, see WithResourceFromOperation.
+	//
+	// Deprecated: use WithResourceName instead, DefaultResource is shared by all
+	// the tracers and it's not safe to change it concurrently.
 	DefaultResource = "/"
 
 	// EnvTag set's the environment for a given span
 	// i.e EnvTag.Set(span, "development")
 	EnvTag = stringTagName("env")
+
+	// VersionTag set's the version of the service for a given span
+	// i.e VersionTag.Set(span, "1.2.3")
+	VersionTag = stringTagName("version")
 )
 
+// refTypeTag marks the spans whose parent is a FollowsFrom reference.
+const (
+	refTypeTag         = "opentracing.ref_type"
+	followsFromRefType = "follows_from"
+)
+
+// analyticsRateKey is the metric read by DataDog to index spans in trace search & analytics.
+const analyticsRateKey = "_dd1.sr.eausr"
+
 type Tracer struct {
 	*tracer.Tracer
-	textPropagator *textMapPropagator
+	propagators map[interface{}]Propagator
+
+	// service and resource override DefaultService and DefaultResource when not empty.
+	service  string
+	resource string
+	// staticResource disables setting the resource to the operation name.
+	staticResource bool
+	// serviceMapper, when not nil, names the spans after their operation.
+	serviceMapper ServiceMapper
+
+	// sampler, when not nil, decides whether the traces are kept, otherwise
+	// it's sampleRate, the bits of a float64. The DataDog's tracer keeps them
+	// all, as it can't be reconfigured at runtime.
+	samplerMu  sync.RWMutex
+	sampler    Sampler
+	sampleRate uint64
+
+	// observers are notified of every span started and finished.
+	observers []SpanObserver
+	// debug, when not nil, is the observer logging the spans, see WithDebug.
+	debug *debugObserver
+	// abandoned, when not nil, is the observer tracking the open spans, see
+	// WithAbandonedSpanDetection.
+	abandoned *abandonedSpans
+	// durations, when not nil, is the observer recording the durations of
+	// the spans, see WithDurationHistograms.
+	durations *durationHistograms
+
+	// idGenerator generates the span and trace IDs, see WithIDGenerator.
+	idGenerator IDGenerator
+	// clock times the spans, see WithClock.
+	clock Clock
+
+	// stackTraces captures the creation stack trace of every span, see
+	// WithCreationStackTraces.
+	stackTraces bool
+
+	// scopes hold the active spans, see Activate.
+	scopes scopes
+	// implicitParenting makes the active span the parent of the spans
+	// started without references, see WithImplicitParenting.
+	implicitParenting bool
+
+	// limiter, when not nil, caps the spans started per operation, see
+	// WithSpanRateLimit.
+	limiter *spanLimiter
+	// spanSampler, when not nil, keeps the spans of the dropped traces
+	// matching its rules, see WithSamplingRules.
+	spanSampler *spanSampler
+
+	// spanFilters drop the spans when finished, see WithSpanFilter.
+	spanFilters []FilterFunc
+	// markLocalRoots marks the local root spans for the trace filters, see
+	// WithTraceFilter.
+	markLocalRoots bool
+
+	// hooks intercept every span started and finished, see RegisterSpanHook.
+	hooksMu sync.RWMutex
+	hooks   []SpanHook
+
+	stats  *stats
+	logger Logger
+
+	// statsd reports the health and span metrics, see WithDogstatsdAddr,
+	// health being nil unless it's set.
+	statsd statsdClient
+	health *healthReporter
+
+	// limits bound the tags and logs of the spans.
+	limits limits
+
+	// sanitizer, when not nil, is applied to every meta of the spans.
+	sanitizer TagSanitizer
+
+	// obfuscateSQL obfuscates the db.statement tags, see WithSQLObfuscation.
+	obfuscateSQL bool
+
+	// queue, when configured, holds the traces between flushes.
+	queue *queueTransport
+	// retry, when configured, holds the traces failed to be sent.
+	retry *retryTransport
+	// router, when configured, sends the traces to their destination, see
+	// WithRouter.
+	router *routerTransport
+
+	// partialFlushSpans is the number of finished spans triggering a flush,
+	// counted by finishedSpans, see WithPartialFlush.
+	partialFlushSpans uint64
+	finishedSpans     uint64
+	partialFlushing   int32
+
+	closeOnce sync.Once
+	closeErr  error
+	// shared is set on the Tracer created by Default.
+	shared bool
+
+	// AnalyticsRate, when greater than zero, is set on every span started by the Tracer.
+	// See Span.SetAnalyticsRate.
+	AnalyticsRate float64
+
+	// TraceID128Bit makes the root spans to have 128-bit trace IDs, see WithTraceID128Bit.
+	TraceID128Bit bool
+
+	// LegacyHeaders makes Inject to also set the legacy dd-trace-* headers,
+	// to keep compatibility with services not upgraded yet.
+	LegacyHeaders bool
+
+	// idEncoding is the format of the IDs of the Datadog headers, see
+	// WithPropagationIDEncoding.
+	idEncoding IDEncoding
+}
+
+// ClosableTracer is an opentracing.Tracer which buffers the traces, it has to be
+// closed to flush the remaining ones before exiting.
+type ClosableTracer interface {
+	opentracing.Tracer
+
+	// Flush sends the buffered traces to the agent, it gives up when ctx is done.
+	Flush(ctx context.Context) error
+
+	// Close flushes the buffered traces and stops the tracer.
+	Close() error
 }
 
-// NewTracer creates a new Tracer.
-func NewTracer() opentracing.Tracer {
-	return NewTracerTransport(nil)
+// NewTracer creates a new Tracer configured by the DD_* environment variables,
+// or a NoopTracer when disabled by DD_TRACE_ENABLED.
+func NewTracer() ClosableTracer {
+	return NewTracerWithOptions()
 }
 
-// NewTracerTransport create a new Tracer with the given transport.
-func NewTracerTransport(tr tracer.Transport) opentracing.Tracer {
-	var driver *tracer.Tracer
+// NewTracerTransport create a new Tracer with the given transport, see NewTracer.
+func NewTracerTransport(tr tracer.Transport) ClosableTracer {
 	if tr == nil {
-		driver = tracer.NewTracer()
-	} else {
-		driver = tracer.NewTracerTransport(tr)
+		return NewTracerWithOptions()
 	}
+	return NewTracerWithOptions(WithTransport(tr))
+}
 
-	t := &Tracer{Tracer: driver}
-	t.textPropagator = &textMapPropagator{t}
+func newTracer(c *config) *Tracer {
+	t := &Tracer{
+		stats:       &stats{},
+		logger:      c.logger,
+		idGenerator: c.idGenerator,
+		clock:       c.clock,
+		sampleRate:  math.Float64bits(1),
+	}
+	if t.idGenerator == nil {
+		t.idGenerator = newRandIDGenerator()
+	}
+	if t.clock == nil {
+		t.clock = systemClock{}
+	}
+	t.statsd = noopStatsd{}
+	if c.dogstatsdAddr != "" {
+		if client, err := newUDPStatsd(c.dogstatsdAddr); err != nil {
+			c.logger.Printf("dogstatsd %s: %v", c.dogstatsdAddr, err)
+		} else {
+			t.statsd = client
+			t.startHealthReporter()
+		}
+	}
+
+	tr, retry := t.newPipeline(c, c.newTransport())
+	t.retry = retry
+	if len(c.routes) > 0 {
+		router := &routerTransport{tag: c.routerTag, routes: make(map[string]tracer.Transport), fallback: tr}
+		for value, d := range c.routes {
+			dest, retry := t.newPipeline(c, d.transport(c))
+			router.routes[value] = dest
+			if retry != nil {
+				router.retries = append(router.retries, retry)
+			}
+		}
+		t.router = router
+		tr = router
+	}
+	if c.queueing {
+		t.queue = newQueueTransport(tr, t.stats, c.maxQueueSize, c.maxPayloadBytes, c.dropPolicy)
+		tr = t.queue
+	}
+	if len(c.traceFilters) > 0 {
+		tr = &filterTransport{Transport: tr, filters: c.traceFilters, stats: t.stats}
+		t.markLocalRoots = true
+	}
+	t.Tracer = tracer.NewTracerTransport(tr)
+	if t.queue != nil {
+		t.queue.start(c.flushInterval, t.Tracer.FlushTraces)
+	}
+	t.propagators = map[interface{}]Propagator{
+		opentracing.TextMap:     &textMapPropagator{t: t},
+		opentracing.HTTPHeaders: &textMapPropagator{t: t, httpHeaders: true},
+		opentracing.Binary:      &binaryPropagator{t},
+	}
 
 	return t
 }
 
+// newPipeline wraps dest to count the flushes in the stats, and to retry
+// the traces failed to be sent when configured with WithRetry, in which case
+// the retry transport is returned as well.
+func (t *Tracer) newPipeline(c *config, dest tracer.Transport) (tracer.Transport, *retryTransport) {
+	var tr tracer.Transport = &statsTransport{Transport: dest, stats: t.stats, logger: c.logger}
+	if c.retrySpans <= 0 {
+		return tr, nil
+	}
+	retry := newRetryTransport(tr, t.stats, c.logger, c)
+	retry.start()
+	return retry, retry
+}
+
+func (t *Tracer) serviceName() string {
+	if t.service != "" {
+		return t.service
+	}
+	return DefaultService
+}
+
+func (t *Tracer) resourceName(op string) string {
+	if t.resource != "" {
+		return t.resource
+	}
+	if !t.staticResource && op != "" {
+		return op
+	}
+	return DefaultResource
+}
+
+// newChildSpan creates a child of p, which might be a live span or a
+// remote one (i.e. extracted) not attached to any tracer.
+func (t *Tracer) newChildSpan(op string, p *SpanContext) *tracer.Span {
+	var span *tracer.Span
+	if parent, ok := p.ddSpan(); ok && parent.Tracer() != nil {
+		span = t.NewChildSpan(op, parent)
+		span.SpanID = t.idGenerator.NewID()
+	} else if p.traceID != 0 {
+		span = tracer.NewSpan(op, t.serviceName(), t.resourceName(op), t.idGenerator.NewID(), p.traceID, p.spanID, t.Tracer)
+		span.Sampled = p.sampled
+	} else {
+		return nil
+	}
+
+	inheritSamplingPriority(span, p)
+	return span
+}
+
+// startSpanOptionsPool reuses the StartSpanOptions, which escape to the heap
+// as they're applied through an interface. The spans themselves are not
+// pooled, as they might be referenced after being finished.
+var startSpanOptionsPool = sync.Pool{
+	New: func() interface{} {
+		return &opentracing.StartSpanOptions{}
+	},
+}
+
 func (t *Tracer) StartSpan(op string, opts ...opentracing.StartSpanOption) opentracing.Span {
-	sso := &opentracing.StartSpanOptions{}
+	sso := startSpanOptionsPool.Get().(*opentracing.StartSpanOptions)
 	for _, o := range opts {
 		o.Apply(sso)
 	}
 
-	return t.startSpanWithOptions(op, sso)
+	span := t.startSpanWithOptions(op, sso)
+
+	for i := range sso.References {
+		sso.References[i] = opentracing.SpanReference{}
+	}
+	sso.References = sso.References[:0]
+	for k := range sso.Tags {
+		delete(sso.Tags, k)
+	}
+	sso.StartTime = time.Time{}
+	startSpanOptionsPool.Put(sso)
+
+	return span
 }
 
 func (t *Tracer) startSpanWithOptions(op string, opts *opentracing.StartSpanOptions) opentracing.Span {
+	if t.implicitParenting && len(opts.References) == 0 {
+		if active := t.ActiveSpan(); active != nil {
+			opts.References = append(opts.References, opentracing.ChildOf(active.Context()))
+		}
+	}
+	if t.limiter != nil && !t.limiter.allow(op) {
+		return t.limitedSpan(op, opts)
+	}
+
 	var span *tracer.Span
+	var baggage map[string]string
+	var traceState string
+	var traceIDHigh uint64
+	var refType opentracing.SpanReferenceType
+	var parent *SpanContext
 	for _, ref := range opts.References {
-		if ref.Type == opentracing.ChildOfRef {
-			if p, ok := ref.ReferencedContext.(*SpanContext); ok {
-				span = tracer.NewChildSpanFromContext(op, p.ctx)
-			}
+		p, ok := ref.ReferencedContext.(*SpanContext)
+		if !ok {
+			continue
+		}
+
+		// ChildOf references take precedence over FollowsFrom ones.
+		if span != nil && (refType == opentracing.ChildOfRef || ref.Type != opentracing.ChildOfRef) {
+			continue
+		}
+
+		span = t.newChildSpan(op, p)
+		if span == nil {
+			continue
 		}
+		parent = p
+		baggage = p.baggage
+		traceState = p.traceState
+		traceIDHigh = p.traceIDHigh
+		refType = ref.Type
 	}
 
-	if span == nil {
-		span = t.NewRootSpan(op, DefaultService, DefaultResource)
+	root := span == nil
+	if root {
+		span = t.NewRootSpan(op, t.serviceName(), t.resourceName(op))
+		span.SpanID = t.idGenerator.NewID()
+		span.TraceID = span.SpanID
+		if t.TraceID128Bit {
+			traceIDHigh = newTraceIDHigh()
+		}
+	} else if refType == opentracing.FollowsFromRef {
+		span.SetMeta(refTypeTag, followsFromRefType)
 	}
+	if len(opts.References) > 1 {
+		setSpanLinks(span, opts.References, parent)
+	}
+	if t.markLocalRoots && (root || parent.span == nil || parent.span.tr != t) {
+		span.SetMetric(localRootKey, 1)
+	}
+
+	t.applyServiceMapper(span, op)
 
-	s := &Span{span}
+	start := opts.StartTime
+	if start.IsZero() {
+		start = t.clock.Now()
+	}
+	span.Start = start.UnixNano()
+
+	s := &Span{Span: span, tr: t, start: start, traceState: traceState, traceIDHigh: traceIDHigh}
+	s.root = s
+	if parent != nil && parent.span != nil {
+		parent.span.mu.Lock()
+		s.parentService = parent.span.Service
+		parent.span.mu.Unlock()
+		if parent.span.tr != nil && parent.span.root != nil {
+			s.root = parent.span.root
+		}
+	}
+	if _, ok := opts.Tags[stackTraceKey]; ok || t.stackTraces {
+		// Skips startSpanWithOptions and StartSpan.
+		s.callers = callers(2)
+	}
+	if traceIDHigh != 0 {
+		span.SetMeta(traceIDHighKey, fmt.Sprintf("%016x", traceIDHigh))
+	}
+	for k, v := range baggage {
+		s.SetBaggageItem(k, v)
+	}
+	s.setTraceTags(baggage)
+	if t.AnalyticsRate > 0 {
+		s.SetAnalyticsRate(t.AnalyticsRate)
+	}
 	for key, value := range opts.Tags {
 		s.SetTag(key, value)
 	}
 
+	if _, ok := s.SamplingPriority(); root && !ok {
+		s.Sampled = t.sample(s)
+	}
+
+	s.hooks = t.spanHooks()
+	for _, h := range s.hooks {
+		h.OnStart(s, *opts)
+	}
+
+	atomic.AddUint64(&t.stats.spansStarted, 1)
+	for _, o := range t.observers {
+		o.OnStart(s)
+	}
+
 	return s
 
 }
 
 func (t *Tracer) Inject(sm opentracing.SpanContext, format interface{}, carrier interface{}) error {
 	sc, ok := sm.(*SpanContext)
-	if !ok {
+	if !ok || sc == nil {
 		return opentracing.ErrInvalidSpanContext
 	}
+	sc, err := sc.refresh()
+	if err != nil {
+		return err
+	}
 
-	span, ok := tracer.SpanFromContext(sc.ctx)
+	p, ok := t.propagators[format]
 	if !ok {
-		return opentracing.ErrInvalidSpanContext
+		return opentracing.ErrUnsupportedFormat
 	}
 
-	switch format {
-	case opentracing.HTTPHeaders:
-		return t.textPropagator.Inject(span, carrier)
+	err = p.Inject(sc, carrier)
+	if err != nil {
+		t.logger.Printf("inject %s: %v", formatName(format), err)
 	}
-
-	return opentracing.ErrUnsupportedFormat
+	return err
 }
 
 func (t *Tracer) Extract(format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
-	switch format {
-	case opentracing.HTTPHeaders:
-		return t.textPropagator.Extract(carrier)
+	p, ok := t.propagators[format]
+	if !ok {
+		return nil, opentracing.ErrUnsupportedFormat
+	}
+
+	sc, err := p.Extract(carrier)
+	if err != nil && err != opentracing.ErrSpanContextNotFound {
+		t.logger.Printf("extract %s: %v", formatName(format), err)
+	}
+	return sc, err
+}
+
+// ChildOfContext returns a StartSpanOption pointing to the span found in ctx as parent,
+// i.e. tracer.StartSpan("op", ChildOfContext(ctx)).
+// When ctx holds no span the option is a no-op, and a root span will be created.
+func ChildOfContext(ctx context.Context) opentracing.StartSpanOption {
+	return childOfContext{ctx}
+}
+
+type childOfContext struct {
+	ctx context.Context
+}
+
+func (c childOfContext) Apply(o *opentracing.StartSpanOptions) {
+	if span := SpanFromContext(c.ctx); span != nil {
+		opentracing.ChildOf(span.Context()).Apply(o)
 	}
-	return nil, opentracing.ErrUnsupportedFormat
 }
 
 type Span struct {
 	*tracer.Span
+
+	// tr is the Tracer which started the span, nil for context-only spans.
+	tr *Tracer
+	// start is the start time of the span, keeping the monotonic clock
+	// reading the Start nanoseconds lack.
+	start time.Time
+
+	// mu guards the mutations of the span, which are no-ops once finished
+	// as it's then owned by the flushing goroutine.
+	mu       sync.Mutex
+	finished bool
+
+	// hooks are the Tracer's SpanHooks when the span was started, finishing
+	// is set once their OnFinish have been called.
+	hooks     []SpanHook
+	finishing bool
+
+	baggageMu sync.RWMutex
+	baggage   map[string]string
+
+	// traceState is the W3C tracestate received from upstream.
+	traceState string
+
+	// traceIDHigh are the upper 64 bits of 128-bit trace IDs, the lower
+	// ones being the TraceID.
+	traceIDHigh uint64
+
+	logsMu sync.Mutex
+	logs   int
+
+	// callers is the creation stack trace, see WithStackTrace.
+	callers []uintptr
+
+	// deadline, when not zero, is the deadline of the context the span
+	// was started from, see StartSpanFromContextWithDeadline.
+	deadline time.Time
+
+	// parentService is the service of the local parent, if any, to tell
+	// the top-level spans apart, see WithSpanMetrics.
+	parentService string
+
+	// root is the local root of the trace, see SetTraceTag.
+	root *Span
+}
+
+// contextOnly reports whether the span has been synthesized purely for
+// propagation (i.e. by Extract) and is not attached to any tracer.
+// Mutating a context-only span is a no-op.
+func (s *Span) contextOnly() bool {
+	return s.Span == nil || s.Span.Tracer() == nil
+}
+
+// lock locks the span for mutation, unless it's context-only or finished.
+// It reports whether it did, in which case the caller must unlock it.
+func (s *Span) lock() bool {
+	if s.contextOnly() {
+		return false
+	}
+
+	s.mu.Lock()
+	if s.finished {
+		s.mu.Unlock()
+		return false
+	}
+	return true
 }
 
 func (s *Span) Finish() {
@@ -125,99 +565,376 @@ func (s *Span) Finish() {
 }
 
 func (s *Span) FinishWithOptions(opts opentracing.FinishOptions) {
+	if !s.lock() {
+		return
+	}
+
+	for _, record := range opts.LogRecords {
+		s.logFields(record.Timestamp, record.Fields)
+	}
+
 	if !opts.FinishTime.IsZero() {
 		s.Duration = opts.FinishTime.UTC().UnixNano() - s.Start
+	} else if s.Duration == 0 {
+		s.Duration = int64(s.since())
+	}
+	s.checkDeadline()
+	s.setCreationStack()
+
+	// The hooks are called unlocked so that they can tag the span.
+	if len(s.hooks) > 0 && !s.finishing {
+		s.finishing = true
+		s.mu.Unlock()
+		for _, h := range s.hooks {
+			h.OnFinish(s)
+		}
+		if !s.lock() {
+			return
+		}
+	}
+	s.finished = true
+	if s.tr != nil {
+		s.Resource = s.truncate(s.Resource, s.tr.limits.maxResourceLength)
+	}
+	if s.tr != nil && !s.Sampled && s.tr.spanSampler != nil {
+		s.Sampled = s.tr.spanSampler.keep(s)
+	}
+	if s.tr != nil && s.Sampled && s.tr.filterSpan(s.Span) {
+		s.Sampled = false
+	}
+	s.mu.Unlock()
+
+	if s.tr != nil {
+		s.notifyFinish()
 	}
 	s.Span.Finish()
+	if s.tr != nil {
+		s.tr.partialFlush()
+	}
+}
+
+// notifyFinish updates the stats and notifies the observers of the Tracer.
+func (s *Span) notifyFinish() {
+	atomic.AddUint64(&s.tr.stats.spansFinished, 1)
+	if !s.tr.Enabled() {
+		atomic.AddUint64(&s.tr.stats.spansDropped, 1)
+		s.tr.logger.Printf("span %q: dropped, the tracer is disabled", s.Name)
+	} else if !s.Sampled {
+		atomic.AddUint64(&s.tr.stats.spansDropped, 1)
+	}
+
+	for _, o := range s.tr.observers {
+		o.OnFinish(s)
+	}
 }
 
 func (s *Span) Context() opentracing.SpanContext {
-	ctx := s.Span.Context(context.Background())
-	return &SpanContext{ctx}
+	if s.Span == nil {
+		return &SpanContext{}
+	}
+
+	var baggage map[string]string
+	s.baggageMu.RLock()
+	if len(s.baggage) > 0 {
+		baggage = make(map[string]string, len(s.baggage))
+		for k, v := range s.baggage {
+			baggage[k] = v
+		}
+	}
+	s.baggageMu.RUnlock()
+
+	s.mu.Lock()
+	sampled := s.Sampled
+	priority, hasPriority := samplingPriority(s.Span)
+	s.mu.Unlock()
+
+	return &SpanContext{
+		span:     s,
+		traceID:  s.TraceID,
+		spanID:   s.SpanID,
+		parentID: s.ParentID,
+		sampled:  sampled,
+		baggage:  baggage,
+
+		priority:    priority,
+		hasPriority: hasPriority,
+
+		traceState:  s.traceState,
+		traceIDHigh: s.traceIDHigh,
+	}
 }
 
 func (s *Span) SetOperationName(operationName string) opentracing.Span {
+	if !s.lock() {
+		return s
+	}
+	defer s.mu.Unlock()
+
 	s.Name = operationName
 	return s
 }
 
-func (s *Span) setTag(key string, value interface{}) opentracing.Span {
-	val := fmt.Sprint(value)
-	switch key {
-	case string(ext.PeerService):
-		s.Service = val
-	case string(ext.Component):
-		s.Resource = val
-	default:
-		s.SetMeta(key, val)
+func (s *Span) SetTag(key string, value interface{}) opentracing.Span {
+	if !s.lock() {
+		return s
 	}
+	defer s.mu.Unlock()
 
+	s.setTag(key, value)
 	return s
 }
 
-func (s *Span) SetTag(key string, value interface{}) opentracing.Span {
-	switch t := value.(type) {
-	case float64:
-		s.SetMetric(key, t)
-	default:
-		s.setTag(key, value)
+// setTag implements SetTag, the span must be locked.
+func (s *Span) setTag(key string, value interface{}) {
+	if s.setErrorTag(key, value) {
+		return
 	}
 
-	return s
+	if m, ok := tagMapper(key); ok {
+		m(s, value)
+		return
+	}
+
+	if v, ok := tagMetric(value); ok {
+		s.setMetric(key, v)
+		return
+	}
+	s.setMeta(key, tagString(value))
+}
+
+func (s *Span) SetMeta(key, value string) {
+	if !s.lock() {
+		return
+	}
+	defer s.mu.Unlock()
+
+	s.setMeta(key, value)
+}
+
+func (s *Span) SetMetric(key string, value float64) {
+	if !s.lock() {
+		return
+	}
+	defer s.mu.Unlock()
+
+	s.setMetric(key, value)
 }
 
+// SetAnalyticsRate sets the rate at which the span is indexed by trace search & analytics.
+// The rate is clamped to [0, 1], being 1 always index.
+func (s *Span) SetAnalyticsRate(rate float64) {
+	s.SetMetric(analyticsRateKey, clampRate(rate))
+}
+
+func clampRate(rate float64) float64 {
+	if rate < 0 {
+		return 0
+	} else if rate > 1 {
+		return 1
+	}
+	return rate
+}
+
+// LogFields sets every field as a tag of the span, and keeps the whole
+// record with its timestamp in a log.<n> meta.
 func (s *Span) LogFields(fields ...log.Field) {
+	if !s.lock() {
+		return
+	}
+	defer s.mu.Unlock()
+
+	s.logFields(s.now(), fields)
+}
+
+// logFields implements LogFields, the span must be locked.
+func (s *Span) logFields(ts time.Time, fields []log.Field) {
+	s.recordLog(ts, fields)
+	if s.setErrorLog(fields) {
+		return
+	}
+
 	for _, field := range fields {
-		switch field.Key() {
-		case "error":
-			s.SetError(field.Value().(error))
-		default:
-			s.SetTag(field.Key(), field.Value())
-		}
+		s.setTag(field.Key(), field.Value())
 	}
 }
 
 func (s *Span) LogKV(alternatingKeyValues ...interface{}) {
 	fields, err := log.InterleavedKVToFields(alternatingKeyValues...)
 	if err != nil {
+		if s.lock() {
+			name := s.Name
+			s.mu.Unlock()
+			s.logger().Printf("span %q: dropping LogKV: %v", name, err)
+		}
 		return
 	}
 	s.LogFields(fields...)
 }
 
+// logger returns the Logger of the Tracer of the span, the standard one for
+// the spans without a Tracer.
+func (s *Span) logger() Logger {
+	if s.tr == nil {
+		return StdLogger(nil)
+	}
+	return s.tr.logger
+}
+
+// LogEvent has been deprecated, use LogFields or LogKV.
 func (s *Span) LogEvent(event string) {
-	stdlog.Println("Span.LogEvent() has been deprecated, use LogFields or LogKV")
+	s.LogFields(log.String("event", event))
 }
 
+// LogEventWithPayload has been deprecated, use LogFields or LogKV.
+// The payload is stored JSON encoded.
 func (s *Span) LogEventWithPayload(event string, payload interface{}) {
-	stdlog.Println("Span.LogWithPayload() has been deprecated, use LogFields or LogKV")
+	s.LogFields(
+		log.String("event", event),
+		log.String("payload", encodePayload(payload)),
+	)
 }
 
+// Log has been deprecated, use LogFields or LogKV.
 func (s *Span) Log(data opentracing.LogData) {
-	stdlog.Println("Span.Log() has been deprecated, use LogFields or LogKV")
+	if data.Payload == nil {
+		s.LogEvent(data.Event)
+		return
+	}
+	s.LogEventWithPayload(data.Event, data.Payload)
+}
+
+func encodePayload(payload interface{}) string {
+	if buf, err := json.Marshal(payload); err == nil {
+		return string(buf)
+	}
+	return fmt.Sprint(payload)
 }
 
-// SetBaggageItem  hasn't been implemented
 func (s *Span) SetBaggageItem(restrictedKey string, value string) opentracing.Span {
-	panic("not implemented")
+	s.baggageMu.Lock()
+	if s.baggage == nil {
+		s.baggage = make(map[string]string)
+	}
+	s.baggage[restrictedKey] = value
+	s.baggageMu.Unlock()
+
+	return s
 }
 
-// BaggageItem hasn't been implemented
 func (s *Span) BaggageItem(restrictedKey string) string {
-	panic("not implemented")
+	s.baggageMu.RLock()
+	defer s.baggageMu.RUnlock()
+
+	return s.baggage[restrictedKey]
 }
 
+// Tracer returns the Tracer which started the span. The spans not started by
+// a Tracer, i.e. extracted ones, return opentracing.GlobalTracer().
 func (s *Span) Tracer() opentracing.Tracer {
-	return s.Tracer()
+	if s.tr == nil {
+		return opentracing.GlobalTracer()
+	}
+	return s.tr
 }
 
 type SpanContext struct {
-	ctx context.Context
+	// span is the span the context belongs to, counting its limited children.
+	span *Span
+
+	traceID  uint64
+	spanID   uint64
+	parentID uint64
+	sampled  bool
+
+	// traceIDHigh are the upper 64 bits of 128-bit trace IDs.
+	traceIDHigh uint64
+
+	baggage map[string]string
+
+	priority    int
+	hasPriority bool
+
+	traceState string
+}
+
+// refresh returns ctx with the IDs of its live span, they might have changed
+// since the context was taken.
+func (ctx *SpanContext) refresh() (*SpanContext, error) {
+	if span, ok := ctx.ddSpan(); ok {
+		live := *ctx
+		live.traceID, live.spanID, live.parentID = span.TraceID, span.SpanID, span.ParentID
+		live.sampled = span.Sampled
+		live.priority, live.hasPriority = samplingPriority(span)
+		return &live, nil
+	}
+	if ctx.traceID == 0 || ctx.spanID == 0 {
+		// The context holds no live span (i.e. it has been relayed from an
+		// Extract) nor the IDs to fallback to.
+		return nil, opentracing.ErrInvalidSpanContext
+	}
+	return ctx, nil
+}
+
+// ddSpan returns the DataDog span of the context, which is not attached to
+// any tracer for the extracted contexts.
+func (ctx *SpanContext) ddSpan() (*tracer.Span, bool) {
+	if ctx.span == nil || ctx.span.Span == nil {
+		return nil, false
+	}
+	return ctx.span.Span, true
+}
+
+// TraceID returns the ID of the trace, its lower 64 bits for 128-bit trace IDs.
+func (ctx *SpanContext) TraceID() uint64 {
+	return ctx.traceID
+}
+
+// TraceIDHigh returns the upper 64 bits of 128-bit trace IDs, zero otherwise.
+func (ctx *SpanContext) TraceIDHigh() uint64 {
+	return ctx.traceIDHigh
+}
+
+// SpanID returns the ID of the span.
+func (ctx *SpanContext) SpanID() uint64 {
+	return ctx.spanID
+}
+
+// ParentID returns the ID of the parent span, zero for root spans.
+func (ctx *SpanContext) ParentID() uint64 {
+	return ctx.parentID
+}
+
+// Sampled reports whether the trace is sampled.
+func (ctx *SpanContext) Sampled() bool {
+	return ctx.sampled
+}
+
+// NewRemoteSpanContext returns the context of a span started by another
+// tracing system, i.e. to bridge its traces, to be used as parent.
+func NewRemoteSpanContext(traceIDHigh, traceID, spanID uint64, sampled bool) *SpanContext {
+	span := &Span{
+		Span: &tracer.Span{
+			SpanID:  spanID,
+			TraceID: traceID,
+			Sampled: sampled,
+		},
+		traceIDHigh: traceIDHigh,
+	}
+	return span.Context().(*SpanContext)
+}
+
+// String formats the IDs as DataDog's log correlation does,
+// i.e. "dd.trace_id=123 dd.span_id=456".
+func (ctx *SpanContext) String() string {
+	return fmt.Sprintf("%s=%d %s=%d", logTraceIDKey, ctx.traceID, logSpanIDKey, ctx.spanID)
 }
 
-// ForeachBaggageItem hasn't been implemented
 func (ctx *SpanContext) ForeachBaggageItem(handler func(k, v string) bool) {
-	panic("not implemented")
+	for k, v := range ctx.baggage {
+		if !handler(k, v) {
+			return
+		}
+	}
 }
 
 type stringTagName string
@@ -3,13 +3,16 @@ package ddtracer
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/http/httputil"
 	"net/url"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -93,6 +96,26 @@ func TestSpansParenthood(t *testing.T) {
 	assert.Equal(t, child.TraceID, parent.TraceID)
 }
 
+func TestSpanFollowsFrom(t *testing.T) {
+	tr := NewTracer()
+	producer := tr.StartSpan("producer").(*Span)
+
+	consumer := tr.StartSpan("consumer", opentracing.FollowsFrom(producer.Context())).(*Span)
+	assert.Equal(t, producer.TraceID, consumer.TraceID)
+	assert.Equal(t, producer.SpanID, consumer.ParentID)
+	assert.Equal(t, "follows_from", consumer.GetMeta("opentracing.ref_type"))
+
+	t.Run("ChildOf takes precedence", func(t *testing.T) {
+		parent := tr.StartSpan("parent").(*Span)
+		span := tr.StartSpan("span",
+			opentracing.FollowsFrom(producer.Context()),
+			opentracing.ChildOf(parent.Context()),
+		).(*Span)
+		assert.Equal(t, parent.SpanID, span.ParentID)
+		assert.Empty(t, span.GetMeta("opentracing.ref_type"))
+	})
+}
+
 func TestSpanTags(t *testing.T) {
 	span := NewTracer().StartSpan("test")
 	span.LogKV(
@@ -104,10 +127,50 @@ func TestSpanTags(t *testing.T) {
 
 	assert.Equal(t, "bar", span.(*Span).GetMeta("foo"))
 	assert.Equal(t, "val", span.(*Span).GetMeta("key"))
-	assert.Equal(t, "123", span.(*Span).GetMeta("int"))
+	assert.Equal(t, float64(123), span.(*Span).Metrics["int"])
 	assert.Equal(t, 0.1, span.(*Span).Metrics["metric"])
 }
 
+func TestSpanTagTypes(t *testing.T) {
+	now := time.Date(2017, 1, 2, 3, 4, 5, 6, time.UTC)
+	span := NewTracer().StartSpan("test").(*Span)
+	span.SetTag("int", 1)
+	span.SetTag("int64", int64(-2))
+	span.SetTag("uint64", uint64(3))
+	span.SetTag("uint16", uint16(4))
+	span.SetTag("float32", float32(0.5))
+	span.SetTag("bool", true)
+	span.SetTag("time", now)
+	span.SetTag("duration", time.Second)
+
+	assert.Equal(t, float64(1), span.Metrics["int"])
+	assert.Equal(t, float64(-2), span.Metrics["int64"])
+	assert.Equal(t, float64(3), span.Metrics["uint64"])
+	assert.Equal(t, float64(4), span.Metrics["uint16"])
+	assert.Equal(t, 0.5, span.Metrics["float32"])
+	assert.Equal(t, "true", span.GetMeta("bool"))
+	assert.Equal(t, "2017-01-02T03:04:05.000000006Z", span.GetMeta("time"))
+	assert.Equal(t, "1s", span.GetMeta("duration"))
+}
+
+func TestSpanDeprecatedLogs(t *testing.T) {
+	span := NewTracer().StartSpan("test").(*Span)
+
+	span.LogEvent("started")
+	assert.Equal(t, "started", span.GetMeta("event"))
+
+	span.LogEventWithPayload("query", map[string]int{"rows": 1})
+	assert.Equal(t, "query", span.GetMeta("event"))
+	assert.Equal(t, `{"rows":1}`, span.GetMeta("payload"))
+
+	span.Log(opentracing.LogData{Event: "done", Payload: []string{"a"}})
+	assert.Equal(t, "done", span.GetMeta("event"))
+	assert.Equal(t, `["a"]`, span.GetMeta("payload"))
+
+	span.LogEventWithPayload("func", func() {})
+	assert.Contains(t, span.GetMeta("payload"), "0x")
+}
+
 func TestDDParams(t *testing.T) {
 	span := NewTracer().StartSpan("test").(*Span)
 
@@ -134,6 +197,15 @@ func TestSpanSetOperationName(t *testing.T) {
 	assert.Equal(t, "op", span.(*Span).Name)
 }
 
+func TestSpanStartTime(t *testing.T) {
+	start := time.Now().Add(-1 * time.Minute)
+	span := NewTracer().StartSpan("test", opentracing.StartTime(start)).(*Span)
+	assert.Equal(t, start.UnixNano(), span.Start)
+
+	span.Finish()
+	assert.True(t, time.Duration(span.Duration) >= 1*time.Minute)
+}
+
 func TestSpanFinishWithOptions(t *testing.T) {
 	span := NewTracer().StartSpan("test")
 	span.FinishWithOptions(opentracing.FinishOptions{
@@ -156,4 +228,305 @@ func TestSpanFinishWithOptions(t *testing.T) {
 		assert.NotZero(t, dur)
 		assert.True(t, dur < diff)
 	})
+
+	t.Run("With LogRecords", func(t *testing.T) {
+		span := NewTracer().StartSpan("test")
+		span.FinishWithOptions(opentracing.FinishOptions{
+			LogRecords: []opentracing.LogRecord{
+				{Timestamp: time.Now(), Fields: []log.Field{log.String("foo", "bar")}},
+				{Timestamp: time.Now(), Fields: []log.Field{log.Float64("metric", 0.1)}},
+			},
+		})
+
+		assert.Equal(t, "bar", span.(*Span).GetMeta("foo"))
+		assert.Equal(t, 0.1, span.(*Span).Metrics["metric"])
+	})
+}
+
+func TestSpanAnalyticsRate(t *testing.T) {
+	span := NewTracer().StartSpan("test").(*Span)
+	_, ok := span.Metrics[analyticsRateKey]
+	assert.False(t, ok)
+
+	for rate, expected := range map[float64]float64{
+		0.5: 0.5,
+		1:   1,
+		-1:  0,
+		2:   1,
+	} {
+		span.SetAnalyticsRate(rate)
+		assert.Equal(t, expected, span.Metrics["_dd1.sr.eausr"])
+	}
+
+	t.Run("From Tracer", func(t *testing.T) {
+		tr := NewTracer()
+		tr.(*Tracer).AnalyticsRate = 0.25
+
+		span := tr.StartSpan("test").(*Span)
+		assert.Equal(t, 0.25, span.Metrics["_dd1.sr.eausr"])
+	})
+
+	t.Run("With options", func(t *testing.T) {
+		span := NewTracerWithOptions(WithAnalytics(true)).StartSpan("test").(*Span)
+		assert.Equal(t, float64(1), span.Metrics["_dd1.sr.eausr"])
+
+		span = NewTracerWithOptions(WithAnalyticsRate(0.5)).StartSpan("test", AnalyticsRate(2)).(*Span)
+		assert.Equal(t, float64(1), span.Metrics["_dd1.sr.eausr"])
+
+		span = NewTracerWithOptions(WithAnalytics(false)).StartSpan("test").(*Span)
+		_, ok := span.Metrics[analyticsRateKey]
+		assert.False(t, ok)
+	})
+}
+
+func TestChildOfContext(t *testing.T) {
+	tr := NewTracer()
+
+	t.Run("With parent", func(t *testing.T) {
+		parent := tr.StartSpan("parent").(*Span)
+		ctx := opentracing.ContextWithSpan(context.Background(), parent)
+
+		child := tr.StartSpan("child", ChildOfContext(ctx)).(*Span)
+		assert.Equal(t, parent.TraceID, child.TraceID)
+		assert.Equal(t, parent.SpanID, child.ParentID)
+	})
+
+	t.Run("Without parent", func(t *testing.T) {
+		span := tr.StartSpan("root", ChildOfContext(context.Background())).(*Span)
+		assert.Equal(t, span.SpanID, span.TraceID)
+		assert.Zero(t, span.ParentID)
+	})
+}
+
+func TestSpanContextOnly(t *testing.T) {
+	tr := NewTracer()
+	sc, err := tr.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(http.Header{
+		"Dd-Trace-Spanid":  []string{"aa"},
+		"Dd-Trace-Traceid": []string{"bb"},
+	}))
+	require.NoError(t, err)
+
+	ddspan, ok := sc.(*SpanContext).ddSpan()
+	require.True(t, ok)
+
+	for name, span := range map[string]*Span{
+		"Extracted": {Span: ddspan},
+		"Nil":       {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.NotPanics(t, func() {
+				span.SetOperationName("op")
+				span.SetTag("key", "val")
+				span.SetTag(string(ext.PeerService), "service")
+				span.SetMeta("key", "val")
+				span.SetMetric("metric", 0.1)
+				span.SetAnalyticsRate(1)
+				span.LogFields(log.String("key", "val"), log.Error(errors.New("boom")))
+				span.LogKV("key", "val")
+				span.Context()
+				span.Finish()
+			})
+		})
+	}
+
+	assert.Empty(t, ddspan.Name)
+	assert.Empty(t, ddspan.Meta)
+	assert.Empty(t, ddspan.Metrics)
+
+	t.Run("Live without Tracer", func(t *testing.T) {
+		live := &Span{Span: tr.(*Tracer).NewRootSpan("op", "service", "resource")}
+		assert.NotPanics(t, func() {
+			live.LogKV("odd")
+			live.LogKV("key", "val")
+		})
+		assert.Equal(t, "val", live.GetMeta("key"))
+	})
+}
+
+func TestSpanBaggage(t *testing.T) {
+	tr := NewTracer()
+	parent := tr.StartSpan("parent")
+	parent.SetBaggageItem("user", "42")
+	assert.Equal(t, "42", parent.BaggageItem("user"))
+	assert.Empty(t, parent.BaggageItem("missing"))
+
+	child := tr.StartSpan("child", opentracing.ChildOf(parent.Context()))
+	assert.Equal(t, "42", child.BaggageItem("user"))
+
+	t.Run("Child doesn't leak into parent", func(t *testing.T) {
+		child.SetBaggageItem("foo", "bar")
+		assert.Empty(t, parent.BaggageItem("foo"))
+	})
+
+	t.Run("ForeachBaggageItem", func(t *testing.T) {
+		items := map[string]string{}
+		child.Context().ForeachBaggageItem(func(k, v string) bool {
+			items[k] = v
+			return true
+		})
+		assert.Equal(t, map[string]string{"user": "42", "foo": "bar"}, items)
+	})
+}
+
+func TestSpanConcurrentMutation(t *testing.T) {
+	env := newEnv(t)
+	defer env.close()
+	tr := env.tr.(*Tracer)
+
+	for i := 0; i < 10; i++ {
+		span := tr.StartSpan("op").(*Span)
+
+		var wg sync.WaitGroup
+		for j := 0; j < 4; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for k := 0; k < 50; k++ {
+					span.SetTag("key", k)
+					span.SetTag("peer.service", "svc")
+					span.SetTag("http.status_code", 500)
+					span.SetOperationName("renamed")
+					span.LogFields(log.Int("k", k))
+					span.SetSamplingPriority(PriorityUserKeep)
+					span.Context()
+				}
+			}()
+		}
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			span.Finish()
+		}()
+		go func() {
+			defer wg.Done()
+			tr.FlushTraces()
+		}()
+		wg.Wait()
+	}
+
+	require.NoError(t, tr.FlushTraces())
+}
+
+func TestSpanFinishedIsImmutable(t *testing.T) {
+	tr := NewTracer()
+
+	span := tr.StartSpan("op").(*Span)
+	span.Finish()
+	span.SetTag("key", "value")
+	span.SetOperationName("renamed")
+	span.LogFields(log.String("event", "late"))
+
+	assert.Equal(t, "op", span.Name)
+	assert.Empty(t, span.GetMeta("key"))
+	assert.Empty(t, span.GetMeta("event"))
+}
+
+// discardTransport drops the traces, to benchmark the tracer alone.
+type discardTransport struct{}
+
+func (discardTransport) SendTraces(spans [][]*tracer.Span) (*http.Response, error) {
+	return nil, nil
+}
+
+func (discardTransport) SendServices(services map[string]tracer.Service) (*http.Response, error) {
+	return nil, nil
+}
+
+func (discardTransport) SetHeader(key, value string) {}
+
+// TestStartSpanAllocs guards the fast path of the tagless spans: the DataDog
+// span and its wrapper are the only allocations.
+func TestStartSpanAllocs(t *testing.T) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		tr.StartSpan("op").Finish()
+	})
+	assert.True(t, allocs <= 2, "%v allocations", allocs)
+}
+
+func BenchmarkStartSpan(b *testing.B) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tr.StartSpan("op").Finish()
+	}
+}
+
+func BenchmarkStartChildSpan(b *testing.B) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}))
+	parent := tr.StartSpan("parent")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tr.StartSpan("op", opentracing.ChildOf(parent.Context())).Finish()
+	}
+}
+
+func BenchmarkSpanContext(b *testing.B) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}))
+	span := tr.StartSpan("op")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		span.Context()
+	}
+}
+
+func BenchmarkStartSpanWithTags(b *testing.B) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}))
+	parent := tr.StartSpan("parent")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tr.StartSpan("op",
+			opentracing.ChildOf(parent.Context()),
+			opentracing.Tag{Key: "http.method", Value: "GET"},
+			opentracing.Tag{Key: "user.id", Value: 42},
+		).Finish()
+	}
+}
+
+func BenchmarkSetTag(b *testing.B) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}))
+	span := tr.StartSpan("op")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		span.SetTag("key", "value")
+		span.SetTag("count", i)
+		span.SetTag("ok", true)
+	}
+}
+
+func TestSpanContextIDs(t *testing.T) {
+	tr := NewTracerWithOptions(WithTraceID128Bit(true))
+	parent := tr.StartSpan("parent").(*Span)
+	child := tr.StartSpan("child", opentracing.ChildOf(parent.Context())).(*Span)
+
+	sc := child.Context().(*SpanContext)
+	assert.Equal(t, child.TraceID, sc.TraceID())
+	assert.NotZero(t, sc.TraceIDHigh())
+	assert.Equal(t, child.SpanID, sc.SpanID())
+	assert.Equal(t, parent.SpanID, sc.ParentID())
+	assert.Zero(t, parent.Context().(*SpanContext).ParentID())
+	assert.Equal(t, fmt.Sprintf("dd.trace_id=%d dd.span_id=%d", child.TraceID, child.SpanID), sc.String())
+}
+
+func TestSpanTracer(t *testing.T) {
+	tr := NewTracer()
+	span := tr.StartSpan("parent")
+	assert.Equal(t, tr, span.Tracer())
+
+	child := span.Tracer().StartSpan("child", opentracing.ChildOf(span.Context())).(*Span)
+	assert.Equal(t, span.(*Span).SpanID, child.ParentID)
+
+	assert.Equal(t, opentracing.GlobalTracer(), (&Span{}).Tracer())
 }
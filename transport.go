@@ -0,0 +1,172 @@
+package ddtracer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+)
+
+const (
+	defaultAgentHost = "localhost"
+	defaultAgentPort = "8126"
+)
+
+// newTransport returns the transport configured by c: the intake's one in
+// agentless mode, otherwise the DataDog's one unless a custom HTTP client, a
+// Unix socket or encoding options are required.
+func (c *config) newTransport() tracer.Transport {
+	tr := c.transport
+	if tr == nil {
+		if c.agentless {
+			url := c.intakeURL
+			if url == "" {
+				url = intakeURL(c.site)
+			}
+			tr = newIntakeTransport(url, c.apiKey, c.httpClient)
+		} else if c.httpClient != nil || c.agentSocket != "" || c.encoding != "" || c.compress || c.maxPayloadBytes > 0 {
+			agent := newAgentTransport(c.agentHost, c.agentPort, c.agentSocket, c.httpClient)
+			if c.encoding != "" {
+				agent.encoding = c.encoding
+			}
+			agent.compress = c.compress
+			agent.maxPayload = c.maxPayloadBytes
+			tr = agent
+		} else {
+			// NewTransport defaults the empty ones.
+			tr = tracer.NewTransport(c.agentHost, c.agentPort)
+		}
+	}
+
+	for k, v := range c.headers {
+		tr.SetHeader(k, v)
+	}
+	return tr
+}
+
+// agentTransport sends the traces to the agent, as tracer.NewTransport does,
+// but through the given http.Client, JSON encoded by default. Its payloads
+// are split at maxPayload bytes when positive, see encodePayloads.
+type agentTransport struct {
+	traceURL   string
+	serviceURL string
+	client     *http.Client
+	encoding   Encoding
+	compress   bool
+	maxPayload int
+
+	headersMu sync.RWMutex
+	headers   map[string]string
+}
+
+// newAgentTransport returns a transport to the agent at host:port, or at the
+// socket Unix domain socket when not empty. A nil client is replaced by one
+// with a 1 second timeout, as the DataDog's transport does.
+func newAgentTransport(host, port, socket string, client *http.Client) *agentTransport {
+	if host == "" {
+		host = defaultAgentHost
+	}
+	if port == "" {
+		port = defaultAgentPort
+	}
+	if client == nil {
+		client = &http.Client{Timeout: time.Second}
+	}
+	if socket != "" {
+		c := *client
+		c.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		}
+		client = &c
+	}
+
+	base := "http://" + net.JoinHostPort(host, port)
+	return &agentTransport{
+		traceURL:   base + "/v0.3/traces",
+		serviceURL: base + "/v0.3/services",
+		client:     client,
+		encoding:   EncodingJSON,
+		headers:    map[string]string{},
+	}
+}
+
+// SendTraces sends the traces in as many requests as required by maxPayload,
+// returning the last error.
+func (t *agentTransport) SendTraces(traces [][]*tracer.Span) (*http.Response, error) {
+	if len(traces) == 0 {
+		return t.send(t.traceURL, t.encoding.array(nil), 0)
+	}
+
+	payloads, err := encodePayloads(t.encoding, traces, t.maxPayload)
+	resp := &http.Response{}
+	for _, p := range payloads {
+		r, e := t.send(t.traceURL, p.body, p.traces)
+		if e != nil {
+			err = e
+		}
+		resp = r
+	}
+	return resp, err
+}
+
+func (t *agentTransport) SendServices(services map[string]tracer.Service) (*http.Response, error) {
+	body, err := t.encoding.marshal(services)
+	if err != nil {
+		return &http.Response{}, err
+	}
+	return t.send(t.serviceURL, body, -1)
+}
+
+func (t *agentTransport) SetHeader(key, value string) {
+	t.headersMu.Lock()
+	t.headers[key] = value
+	t.headersMu.Unlock()
+}
+
+// send posts body to url, gzipped when compress is set, with the
+// X-Datadog-Trace-Count header set to count unless negative. As the
+// DataDog's transport, it never returns a nil response.
+func (t *agentTransport) send(url string, body []byte, count int) (*http.Response, error) {
+	if t.compress {
+		var err error
+		if body, err = gzipped(body); err != nil {
+			return &http.Response{}, err
+		}
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return &http.Response{}, err
+	}
+	req.Header.Set("Content-Type", string(t.encoding))
+	t.headersMu.RLock()
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	t.headersMu.RUnlock()
+	if t.compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if count >= 0 {
+		req.Header.Set("X-Datadog-Trace-Count", strconv.Itoa(count))
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return &http.Response{}, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return resp, fmt.Errorf("agent responded %s", resp.Status)
+	}
+	return resp, nil
+}
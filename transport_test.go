@@ -0,0 +1,140 @@
+package ddtracer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// agent is a fake DataDog agent keeping the trace requests it receives.
+type agent struct {
+	headers []http.Header
+	traces  [][][]*tracer.Span
+}
+
+func (a *agent) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/v0.3/traces" {
+		return
+	}
+	var traces [][]*tracer.Span
+	if err := json.NewDecoder(req.Body).Decode(&traces); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	a.headers = append(a.headers, req.Header)
+	a.traces = append(a.traces, traces)
+}
+
+func TestTransportOptions(t *testing.T) {
+	t.Run("HTTP client", func(t *testing.T) {
+		a := &agent{}
+		ts := httptest.NewServer(a)
+		defer ts.Close()
+
+		var proxied int
+		client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			proxied++
+			return http.DefaultTransport.RoundTrip(req)
+		})}
+
+		tr := NewTracerWithOptions(
+			WithAgentAddr(ts.Listener.Addr().String()),
+			WithHTTPClient(client),
+			WithHeaders(map[string]string{"X-Auth": "secret"}),
+		).(*Tracer)
+		tr.StartSpan("test").Finish()
+		require.NoError(t, tr.FlushTraces())
+
+		assert.Equal(t, 1, proxied)
+		require.Len(t, a.traces, 1)
+		assert.Equal(t, "test", a.traces[0][0][0].Name)
+		assert.Equal(t, "secret", a.headers[0].Get("X-Auth"))
+		assert.Equal(t, "1", a.headers[0].Get("X-Datadog-Trace-Count"))
+	})
+
+	t.Run("UDS", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "ddtracer")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "apm.socket")
+		l, err := net.Listen("unix", path)
+		require.NoError(t, err)
+		a := &agent{}
+		ts := &httptest.Server{Listener: l, Config: &http.Server{Handler: a}}
+		ts.Start()
+		defer ts.Close()
+
+		tr := NewTracerWithOptions(WithUDS(path)).(*Tracer)
+		tr.StartSpan("test").Finish()
+		require.NoError(t, tr.FlushTraces())
+		require.Len(t, a.traces, 1)
+	})
+
+	t.Run("Headers on a custom transport", func(t *testing.T) {
+		rec := &headerTransport{headers: map[string]string{}}
+		NewTracerWithOptions(WithTransport(rec), WithHeaders(map[string]string{"X-Auth": "secret"}))
+		assert.Equal(t, "secret", rec.headers["X-Auth"])
+	})
+
+	t.Run("Encoding", func(t *testing.T) {
+		var headers http.Header
+		ts := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+			headers = req.Header
+		}))
+		defer ts.Close()
+
+		tr := NewTracerWithOptions(
+			WithAgentAddr(ts.Listener.Addr().String()),
+			WithEncoding(EncodingMsgpack),
+			WithCompression(true),
+		).(*Tracer)
+		tr.StartSpan("test").Finish()
+		require.NoError(t, tr.FlushTraces())
+
+		require.NotNil(t, headers)
+		assert.Equal(t, "application/msgpack", headers.Get("Content-Type"))
+		assert.Equal(t, "gzip", headers.Get("Content-Encoding"))
+	})
+
+	t.Run("Agent errors", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer ts.Close()
+
+		host, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+		resp, err := newAgentTransport(host, port, "", nil).SendTraces(nil)
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		ts.Close()
+		resp, err = newAgentTransport(host, port, "", nil).SendTraces(nil)
+		assert.Error(t, err)
+		assert.NotNil(t, resp)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type headerTransport struct {
+	discardTransport
+	headers map[string]string
+}
+
+func (t *headerTransport) SetHeader(k, v string) {
+	t.headers[k] = v
+}
@@ -0,0 +1,85 @@
+package ddtracer
+
+import (
+	"encoding/base64"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// The user monitoring tags, in the DataDog's canonical format.
+const (
+	userIDKey        = "usr.id"
+	userEmailKey     = "usr.email"
+	userNameKey      = "usr.name"
+	userRoleKey      = "usr.role"
+	userSessionIDKey = "usr.session_id"
+
+	// propagatedUserIDKey holds the base64 encoded user ID propagated to
+	// the downstream services, see WithUserPropagation.
+	propagatedUserIDKey = "_dd.p.usr.id"
+)
+
+// UserOption sets the optional attributes of the user, see SetUser.
+type UserOption func(*userConfig)
+
+type userConfig struct {
+	tags      map[string]string
+	propagate bool
+}
+
+func userTag(key, value string) UserOption {
+	return func(c *userConfig) {
+		c.tags[key] = value
+	}
+}
+
+// WithUserEmail sets the email of the user.
+func WithUserEmail(email string) UserOption {
+	return userTag(userEmailKey, email)
+}
+
+// WithUserName sets the name of the user.
+func WithUserName(name string) UserOption {
+	return userTag(userNameKey, name)
+}
+
+// WithUserRole sets the role of the user, i.e. admin.
+func WithUserRole(role string) UserOption {
+	return userTag(userRoleKey, role)
+}
+
+// WithUserSessionID sets the ID of the session of the user.
+func WithUserSessionID(id string) UserOption {
+	return userTag(userSessionIDKey, id)
+}
+
+// WithUserPropagation propagates the ID of the user to the downstream
+// services, which tag their spans with it. It's off by default, as the ID
+// then leaves the service.
+func WithUserPropagation() UserOption {
+	return func(c *userConfig) {
+		c.propagate = true
+	}
+}
+
+// SetUser tags span with the user identified by id, i.e.
+// SetUser(span, "42", WithUserEmail("jane@example.com")), so the traces can be
+// searched by user and App & API Protection can monitor them.
+// The tags are set on the local root span as well, the service entry span.
+func SetUser(span opentracing.Span, id string, opts ...UserOption) {
+	c := &userConfig{tags: map[string]string{userIDKey: id}}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	s, ok := span.(*Span)
+	for k, v := range c.tags {
+		span.SetTag(k, v)
+		if ok && s.root != nil && s.root != s {
+			s.root.SetTag(k, v)
+		}
+	}
+	if c.propagate && ok {
+		s.SetTraceTag(propagatedUserIDKey, base64.StdEncoding.EncodeToString([]byte(id)))
+	}
+}
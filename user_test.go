@@ -0,0 +1,60 @@
+package ddtracer
+
+import (
+	"net/http"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetUser(t *testing.T) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}))
+
+	root := tr.StartSpan("root").(*Span)
+	span := tr.StartSpan("login", opentracing.ChildOf(root.Context())).(*Span)
+	SetUser(span, "42",
+		WithUserEmail("jane@example.com"),
+		WithUserName("Jane"),
+		WithUserRole("admin"),
+		WithUserSessionID("s1"),
+	)
+
+	for _, s := range []*Span{span, root} {
+		assert.Equal(t, "42", s.GetMeta("usr.id"))
+		assert.Equal(t, "jane@example.com", s.GetMeta("usr.email"))
+		assert.Equal(t, "Jane", s.GetMeta("usr.name"))
+		assert.Equal(t, "admin", s.GetMeta("usr.role"))
+		assert.Equal(t, "s1", s.GetMeta("usr.session_id"))
+	}
+
+	h := http.Header{}
+	require.NoError(t, tr.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(h)))
+	assert.Empty(t, h.Get("ot-baggage-trace-tag-_dd.p.usr.id"))
+}
+
+func TestSetUserPropagation(t *testing.T) {
+	tr := NewTracerWithOptions(WithTransport(discardTransport{}))
+
+	span := tr.StartSpan("login").(*Span)
+	SetUser(span, "42", WithUserPropagation())
+	assert.Equal(t, "NDI=", span.GetMeta("_dd.p.usr.id"))
+
+	h := http.Header{}
+	require.NoError(t, tr.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(h)))
+	sc, err := tr.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(h))
+	require.NoError(t, err)
+
+	downstream := tr.StartSpan("downstream", opentracing.ChildOf(sc)).(*Span)
+	assert.Equal(t, "NDI=", downstream.GetMeta("_dd.p.usr.id"))
+}
+
+func TestSetUserOtherTracer(t *testing.T) {
+	span := mocktracer.New().StartSpan("login").(*mocktracer.MockSpan)
+	SetUser(span, "42", WithUserEmail("jane@example.com"), WithUserPropagation())
+
+	assert.Equal(t, "42", span.Tag("usr.id"))
+	assert.Equal(t, "jane@example.com", span.Tag("usr.email"))
+}
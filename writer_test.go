@@ -0,0 +1,53 @@
+package ddtracer
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterTransport(t *testing.T) {
+	var buf bytes.Buffer
+	tr := NewTracerWithOptions(WithServiceName("svc"), WithTransport(NewWriterTransport(&buf)))
+
+	root := tr.StartSpan("root").(*Span)
+	child := tr.StartSpan("child", opentracing.ChildOf(root.Context()), opentracing.Tag{Key: "k", Value: "v"})
+	child.Finish()
+	root.Finish()
+	tr.StartSpan("other").Finish()
+	require.NoError(t, tr.Flush(context.Background()))
+
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+
+	traces, err := ReadTraces(&buf)
+	require.NoError(t, err)
+	require.Len(t, traces, 2)
+
+	for _, trace := range traces {
+		if trace[0].TraceID != root.TraceID {
+			require.Len(t, trace, 1)
+			assert.Equal(t, "other", trace[0].Name)
+			continue
+		}
+		require.Len(t, trace, 2)
+		for _, span := range trace {
+			assert.Equal(t, "svc", span.Service)
+			if span.Name == "child" {
+				assert.Equal(t, root.SpanID, span.ParentID)
+				assert.Equal(t, "v", span.Meta["k"])
+			}
+		}
+	}
+}
+
+func TestReadTracesCorrupted(t *testing.T) {
+	traces, err := ReadTraces(strings.NewReader(`[{"name":"op"}]` + "\n{"))
+	assert.Error(t, err)
+	require.Len(t, traces, 1)
+	assert.Equal(t, "op", traces[0][0].Name)
+}
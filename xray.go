@@ -0,0 +1,105 @@
+package ddtracer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/dd-trace-go/tracer"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+const xrayTraceIDKey = "x-amzn-trace-id"
+
+// xrayPropagator implements the AWS X-Ray X-Amzn-Trace-Id header,
+// i.e. "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1".
+// The 96-bit X-Ray trace IDs, prefixed by their 32-bit epoch, make up 128-bit
+// trace IDs: the lower 64 bits are the TraceID and the upper ones are kept
+// apart, being zero for the IDs not coming from X-Ray.
+type xrayPropagator struct{}
+
+func (p *xrayPropagator) Inject(sc *SpanContext, carrier interface{}) error {
+	tm, ok := carrier.(opentracing.TextMapWriter)
+	if !ok {
+		return opentracing.ErrInvalidCarrier
+	}
+
+	high := fmt.Sprintf("%016x", sc.traceIDHigh)
+	sampled := "0"
+	if sc.sampled {
+		sampled = "1"
+	}
+	tm.Set(xrayTraceIDKey, fmt.Sprintf("Root=1-%s-%s%016x;Parent=%016x;Sampled=%s",
+		high[:8], high[8:], sc.traceID, sc.spanID, sampled))
+
+	return nil
+}
+
+func (p *xrayPropagator) Extract(carrier interface{}) (opentracing.SpanContext, error) {
+	tm, ok := carrier.(opentracing.TextMapReader)
+	if !ok {
+		return nil, opentracing.ErrInvalidCarrier
+	}
+
+	var header string
+	tm.ForeachKey(func(k, v string) error {
+		if lowerKey(k) == xrayTraceIDKey {
+			header = v
+		}
+		return nil
+	})
+	if header == "" {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+
+	var root, parent string
+	sampled := true
+	for _, field := range strings.Split(header, ";") {
+		kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "Root":
+			root = kv[1]
+		case "Parent":
+			parent = kv[1]
+		case "Sampled":
+			sampled = kv[1] != "0"
+		}
+	}
+
+	// The root is the version, the epoch and the 96-bit ID: 1-8hex-24hex.
+	parts := strings.Split(root, "-")
+	if len(parts) != 3 || parts[0] != "1" || len(parts[1]) != 8 || len(parts[2]) != 24 || len(parent) != 16 {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+
+	traceIDHigh, err := strconv.ParseUint(parts[1]+parts[2][:8], 16, 64)
+	if err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	traceID, err := strconv.ParseUint(parts[2][8:], 16, 64)
+	if err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+	spanID, err := strconv.ParseUint(parent, 16, 64)
+	if err != nil {
+		return nil, opentracing.ErrSpanContextCorrupted
+	}
+
+	if traceID == 0 || spanID == 0 {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+
+	span := &Span{
+		Span: &tracer.Span{
+			SpanID:  spanID,
+			TraceID: traceID,
+			Sampled: sampled,
+		},
+		traceIDHigh: traceIDHigh,
+	}
+
+	return span.Context(), nil
+}
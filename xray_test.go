@@ -0,0 +1,62 @@
+package ddtracer
+
+import (
+	"net/http"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXRayPropagation(t *testing.T) {
+	tr := NewTracer().(*Tracer)
+	tr.EnableXRay(true, true)
+
+	sc, err := tr.ExtractHTTPHeader(http.Header{
+		"X-Amzn-Trace-Id": []string{"Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"},
+	})
+	require.NoError(t, err)
+
+	ctx := sc.(*SpanContext)
+	assert.Equal(t, uint64(0xe1be46a994272793), ctx.traceID)
+	assert.Equal(t, uint64(0x5759e988bd862e3f), ctx.traceIDHigh)
+	assert.Equal(t, uint64(0x53995c3f42cd8ad8), ctx.spanID)
+	assert.True(t, ctx.sampled)
+
+	child := tr.StartSpan("child", opentracing.ChildOf(sc)).(*Span)
+	child.SpanID = 0xaa
+
+	h := http.Header{}
+	require.NoError(t, tr.InjectHTTPHeader(child.Context(), h))
+	assert.Equal(t, "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=00000000000000aa;Sampled=1", h.Get("X-Amzn-Trace-Id"))
+	assert.Equal(t, "170", h.Get("X-Datadog-Parent-Id"))
+
+	t.Run("Datadog IDs", func(t *testing.T) {
+		span := tr.StartSpan("root").(*Span)
+		span.TraceID, span.SpanID = 0xbb, 0xcc
+		span.Sampled = false
+
+		h := http.Header{}
+		require.NoError(t, tr.InjectHTTPHeader(span.Context(), h))
+		assert.Equal(t, "Root=1-00000000-0000000000000000000000bb;Parent=00000000000000cc;Sampled=0", h.Get("X-Amzn-Trace-Id"))
+
+		sc, err := tr.ExtractHTTPHeader(http.Header{"X-Amzn-Trace-Id": h["X-Amzn-Trace-Id"]})
+		require.NoError(t, err)
+		assert.Equal(t, uint64(0xbb), sc.(*SpanContext).traceID)
+		assert.Zero(t, sc.(*SpanContext).traceIDHigh)
+		assert.False(t, sc.(*SpanContext).sampled)
+	})
+
+	t.Run("Corrupted", func(t *testing.T) {
+		for _, v := range []string{
+			"Root=1-5759e988-bd862e3fe1be46a994272793",
+			"Root=2-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8",
+			"Root=1-5759e988-bd862e3fe1be46a99427279z;Parent=53995c3f42cd8ad8",
+			"Root=1-5759e988-bd862e3fe1be;Parent=53995c3f42cd8ad8",
+		} {
+			_, err := tr.ExtractHTTPHeader(http.Header{"X-Amzn-Trace-Id": []string{v}})
+			assert.Equal(t, opentracing.ErrSpanContextCorrupted, err, v)
+		}
+	})
+}